@@ -0,0 +1,100 @@
+package cron
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JobSpec is a job to add with AddJobs, with its spec and entry options.
+type JobSpec struct {
+	Spec    string
+	Job     Job
+	Options []EntryOption
+}
+
+// JobSpecError is the error of a JobSpec that AddJobs could not add.
+type JobSpecError struct {
+	// The position of the JobSpec in the batch, from 0.
+	Index int
+	// The name of the entry, or its key if it has no name.
+	Name string
+	Err  error
+}
+
+func (e *JobSpecError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("Job %d (%q): %s", e.Index, e.Name, e.Err)
+	}
+	return fmt.Sprintf("Job %d: %s", e.Index, e.Err)
+}
+
+func (e *JobSpecError) Unwrap() error { return e.Err }
+
+// JobSpecErrors are the errors of the JobSpecs that AddJobs could not add.
+type JobSpecErrors []*JobSpecError
+
+func (e JobSpecErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AddJobs adds the jobs of a batch, as with AddJob, either all of them or none.
+// Every spec is parsed, and every entry checked against the existing entries
+// and the other entries of the batch, before any of them is added; if any of
+// them fails, none is added, and the errors are returned as JobSpecErrors.
+// Otherwise the IDs of the entries are returned, in the order of specs. If the
+// scheduler is running, the next run times of all the entries are computed from
+// the same time, so that their intervals line up.
+func (c *Cron) AddJobs(specs []JobSpec) ([]EntryID, error) {
+	var errs JobSpecErrors
+	entries := make([]*Entry, len(specs))
+	for i, s := range specs {
+		schedule, err := c.parse(s.Spec, hashKeyOf(s.Options))
+		if err == nil {
+			entries[i], err = c.newEntry(schedule, s.Job, append([]EntryOption{withSpec(s.Spec)}, s.Options...))
+		}
+		if err != nil {
+			errs = append(errs, &JobSpecError{Index: i, Name: hashKeyOf(s.Options), Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	c.apply(func() {
+		names := make(map[string]bool)
+		ids := make(map[string]bool)
+		for i, entry := range entries {
+			err := c.checkEntry(entry)
+			switch {
+			case err != nil:
+			case entry.Name != "" && names[entry.Name]:
+				err = ErrDuplicateName
+			case entry.customID && ids[entry.StringID]:
+				err = ErrDuplicateID
+			}
+			if err != nil {
+				errs = append(errs, &JobSpecError{Index: i, Name: hashKeyOf(specs[i].Options), Err: err})
+			}
+			names[entry.Name] = true
+			ids[entry.StringID] = true
+		}
+		if len(errs) > 0 {
+			return
+		}
+		now := c.now()
+		for _, entry := range entries {
+			c.addEntry(entry, now)
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	ids := make([]EntryID, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	return ids, nil
+}
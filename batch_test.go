@@ -0,0 +1,78 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddJobsErrors(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	_, _ = cron.AddFunc("@every 1m", func() {}, WithName("existing"))
+	job := FuncJob(func() {})
+	specs := []JobSpec{
+		{Spec: "@every 1m", Job: job, Options: []EntryOption{WithName("a")}},
+		{Spec: "61 * * * *", Job: job, Options: []EntryOption{WithName("b")}},
+		{Spec: "@every 2m", Job: job},
+	}
+	ids, err := cron.AddJobs(specs)
+	assert.Empty(t, ids)
+	var errs JobSpecErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 1, errs[0].Index)
+	assert.Equal(t, "b", errs[0].Name)
+	assert.Contains(t, err.Error(), `Job 1 ("b"): `)
+	assert.Len(t, cron.Entries(), 1)
+
+	// Collisions with the existing entries and within the batch.
+	specs = []JobSpec{
+		{Spec: "@every 1m", Job: job, Options: []EntryOption{WithName("existing")}},
+		{Spec: "@every 1m", Job: job, Options: []EntryOption{WithName("a")}},
+		{Spec: "@every 1m", Job: job, Options: []EntryOption{WithName("a")}},
+		{Spec: "@every 1m", Job: job, Options: []EntryOption{WithID("x")}},
+		{Spec: "@every 1m", Job: job, Options: []EntryOption{WithID("x")}},
+	}
+	ids, err = cron.AddJobs(specs)
+	assert.Empty(t, ids)
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+	assert.Equal(t, 0, errs[0].Index)
+	assert.True(t, errors.Is(errs[0], ErrDuplicateName))
+	assert.Equal(t, 2, errs[1].Index)
+	assert.True(t, errors.Is(errs[1], ErrDuplicateName))
+	assert.Equal(t, 4, errs[2].Index)
+	assert.True(t, errors.Is(errs[2], ErrDuplicateID))
+	assert.Len(t, cron.Entries(), 1)
+}
+
+func TestAddJobsWhileRunning(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(1500 * time.Millisecond)
+
+	specs := make([]JobSpec, 100)
+	for i := range specs {
+		specs[i] = JobSpec{Spec: "@every 1m", Job: FuncJob(func() {}), Options: []EntryOption{WithName(fmt.Sprint("job", i))}}
+	}
+	ids, err := cron.AddJobs(specs)
+	assert.NoError(t, err)
+	assert.Len(t, ids, 100)
+	entries := cron.Entries()
+	assert.Len(t, entries, 100)
+	for i, id := range ids {
+		entry := cron.Entry(id)
+		assert.Equal(t, fmt.Sprint("job", i), entry.Name)
+		assert.Equal(t, entries[0].Next, entry.Next)
+		if i > 0 {
+			assert.True(t, id > ids[i-1])
+		}
+	}
+}
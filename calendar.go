@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"sync"
+	"time"
+)
+
+// Calendar tells which times are excluded from a schedule wrapped with Exclude.
+type Calendar interface {
+	IsExcluded(t time.Time) bool
+}
+
+// DateCalendar is a Calendar excluding whole dates, such as holidays, in a
+// time zone. Dates can be added and removed while in use.
+type DateCalendar struct {
+	loc    *time.Location
+	mu     sync.RWMutex
+	dates  map[int]bool
+	ranges [][2]int
+}
+
+// NewDateCalendar returns a calendar excluding the given dates in the given
+// location, or in the location of the times it is asked about if nil. Only
+// the year, month and day of each date are used, in its own location.
+func NewDateCalendar(loc *time.Location, dates ...time.Time) *DateCalendar {
+	c := &DateCalendar{loc: loc, dates: make(map[int]bool)}
+	c.Add(dates...)
+	return c
+}
+
+// Add excludes the given dates.
+func (c *DateCalendar) Add(dates ...time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, date := range dates {
+		c.dates[dateKey(date)] = true
+	}
+}
+
+// AddRange excludes the dates from from to to, included.
+func (c *DateCalendar) AddRange(from, to time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ranges = append(c.ranges, [2]int{dateKey(from), dateKey(to)})
+}
+
+// Remove stops excluding the given dates, and the ranges starting on them.
+func (c *DateCalendar) Remove(dates ...time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, date := range dates {
+		key := dateKey(date)
+		delete(c.dates, key)
+		ranges := c.ranges[:0]
+		for _, r := range c.ranges {
+			if r[0] != key {
+				ranges = append(ranges, r)
+			}
+		}
+		c.ranges = ranges
+	}
+}
+
+// IsExcluded returns true if the date of t is excluded.
+func (c *DateCalendar) IsExcluded(t time.Time) bool {
+	key := dateKey(c.in(t))
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.dates[key] {
+		return true
+	}
+	for _, r := range c.ranges {
+		if r[0] <= key && key <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// nextDay returns the start of the day after t, in the location of the
+// calendar.
+func (c *DateCalendar) nextDay(t time.Time) time.Time {
+	local := c.in(t)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, local.Location())
+}
+
+func (c *DateCalendar) in(t time.Time) time.Time {
+	if c.loc != nil {
+		return t.In(c.loc)
+	}
+	return t
+}
+
+// dateKey returns the date of t as an int that sorts like dates, e.g. 20251225.
+func dateKey(t time.Time) int {
+	year, month, day := t.Date()
+	return year*10000 + int(month)*100 + day
+}
+
+// ExcludeSchedule is a schedule without the activations excluded by a
+// calendar.
+type ExcludeSchedule struct {
+	Schedule Schedule
+	Calendar Calendar
+}
+
+// Exclude returns a Schedule activated at the activations of inner that are not
+// excluded by the calendar.
+func Exclude(inner Schedule, cal Calendar) ExcludeSchedule {
+	return ExcludeSchedule{Schedule: inner, Calendar: cal}
+}
+
+// Next returns the next activation of the inner schedule after t that is not
+// excluded. With a DateCalendar, an excluded activation skips the inner
+// schedule to the next day, as for a Window. If no time is found within five
+// years, Next returns the zero time.
+func (s ExcludeSchedule) Next(t time.Time) time.Time {
+	limit := t.AddDate(5, 0, 0)
+	days, _ := s.Calendar.(*DateCalendar)
+	for {
+		next := s.Schedule.Next(t)
+		if next.IsZero() || next.After(limit) {
+			return time.Time{}
+		}
+		if !s.Calendar.IsExcluded(next) {
+			return next
+		}
+		t = next
+		if days != nil {
+			t = skipTo(s.Schedule, days.nextDay(next).In(next.Location()))
+		}
+	}
+}
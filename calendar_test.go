@@ -0,0 +1,45 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExcludeNext(t *testing.T) {
+	daily, _ := Parse("@daily")
+	christmas := time.Date(2025, time.December, 25, 0, 0, 0, 0, time.UTC)
+	holidays := NewDateCalendar(time.UTC, christmas)
+	week := NewDateCalendar(time.UTC)
+	week.AddRange(time.Date(2025, time.December, 22, 0, 0, 0, 0, time.UTC), time.Date(2025, time.December, 28, 0, 0, 0, 0, time.UTC))
+	everything := NewDateCalendar(time.UTC)
+	everything.AddRange(time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2099, time.December, 31, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		sched    Schedule
+		cal      Calendar
+		time     string
+		expected string
+	}{
+		{daily, holidays, "Tue Dec 23 12:00 2025", "Wed Dec 24 00:00 2025"},
+		{daily, holidays, "Wed Dec 24 12:00 2025", "Fri Dec 26 00:00 2025"},
+		{Every(time.Hour), holidays, "Wed Dec 24 23:30 2025", "Fri Dec 26 01:00 2025"},
+		{daily, week, "Sun Dec 21 12:00 2025", "Mon Dec 29 00:00 2025"},
+		{Every(time.Minute), week, "Sun Dec 21 23:59:30 2025", "Mon Dec 29 00:01 2025"},
+		{daily, everything, "Sun Dec 21 12:00 2025", ""},
+	}
+
+	for _, c := range tests {
+		actual := Exclude(c.sched, c.cal).Next(getTime(c.time))
+		expected := getTime(c.expected)
+		if !actual.Equal(expected) {
+			t.Errorf("%s: (expected) %v != %v (actual)", c.time, expected, actual)
+		}
+	}
+
+	// Dates can be changed while in use.
+	sched := Exclude(daily, holidays)
+	holidays.Remove(christmas)
+	if actual, expected := sched.Next(getTime("Wed Dec 24 12:00 2025")), getTime("Thu Dec 25 00:00 2025"); !actual.Equal(expected) {
+		t.Errorf("(expected) %v != %v (actual)", expected, actual)
+	}
+}
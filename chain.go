@@ -0,0 +1,627 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+)
+
+// JobWrapper decorates the given Job with some behavior. The wrappers of this
+// package return jobs implementing JobWithContext, which pass the context of
+// each run on to the wrapped job if it is a JobWithContext too, and an
+// Unwrap() Job method returning the wrapped job, as UnwrapJob and JobAs
+// expect. Other wrappers can implement Unwrap too.
+type JobWrapper func(Job) Job
+
+// Chain returns a JobWrapper applying the given wrappers, the first one being
+// the outermost: Chain(a, b)(j) is a(b(j)).
+func Chain(wrappers ...JobWrapper) JobWrapper {
+	return func(j Job) Job {
+		for i := len(wrappers) - 1; i >= 0; i-- {
+			j = wrappers[i](j)
+		}
+		return j
+	}
+}
+
+// UnwrapJob returns the innermost job of j, by calling the Unwrap() Job method
+// of each job that has one, such as those returned by the wrappers of this
+// package. It returns j if it has no Unwrap method.
+func UnwrapJob(j Job) Job {
+	for {
+		u, ok := j.(interface{ Unwrap() Job })
+		if !ok {
+			return j
+		}
+		j = u.Unwrap()
+	}
+}
+
+// JobAs returns the first job of the chain of j, starting from j itself and
+// unwrapping as with UnwrapJob, that is a T, as errors.As does for errors. For
+// example, JobAs[*MyJob](entry.WrappedJob) returns the *MyJob of an entry,
+// through the wrappers of its chain.
+func JobAs[T Job](j Job) (T, bool) {
+	for j != nil {
+		if t, ok := j.(T); ok {
+			return t, true
+		}
+		u, ok := j.(interface{ Unwrap() Job })
+		if !ok {
+			break
+		}
+		j = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// WrapperFunc returns a JobWrapper running each run of the wrapped job through
+// fn, with the context of the run and a func running the wrapped job with the
+// given context, which returns its error. The error returned by fn is the one
+// of the run. It is the way for wrappers outside of this package to pass on
+// the errors of the jobs they wrap.
+func WrapperFunc(fn func(ctx context.Context, run func(context.Context) error) error) JobWrapper {
+	return func(j Job) Job {
+		return &funcWrapperJob{job: j, fn: fn}
+	}
+}
+
+type funcWrapperJob struct {
+	job Job
+	fn  func(context.Context, func(context.Context) error) error
+}
+
+func (j *funcWrapperJob) Run() { j.runErr(context.Background()) }
+
+func (j *funcWrapperJob) RunCtx(ctx context.Context) { j.runErr(ctx) }
+
+func (j *funcWrapperJob) Unwrap() Job { return j.job }
+
+func (j *funcWrapperJob) runErr(ctx context.Context) error {
+	return j.fn(ctx, func(ctx context.Context) error { return runJob(ctx, j.job) })
+}
+
+// runJob runs the job, with the given context if it is a JobWithContext, and
+// returns its error if it is an errorJob.
+func runJob(ctx context.Context, j Job) error {
+	switch job := j.(type) {
+	case errorJob:
+		return job.runErr(ctx)
+	case JobWithContext:
+		job.RunCtx(ctx)
+	default:
+		job.Run()
+	}
+	return nil
+}
+
+// clockOf returns clock, or if nil the clock of the Cron running the job, from
+// the context of the run, or the real clock if there is none.
+func clockOf(ctx context.Context, clock clockwork.Clock) clockwork.Clock {
+	if clock != nil {
+		return clock
+	}
+	if clock, ok := ctx.Value(clockKey{}).(clockwork.Clock); ok {
+		return clock
+	}
+	return clockwork.NewRealClock()
+}
+
+// SkipIfStillRunning returns a JobWrapper that skips a run of the wrapped job
+// if its previous run is still in progress, logging it to logger (or the
+// standard logger if nil). The skipped run fails with ErrSkipped. Around a job
+// returned by WithTimeout, a run stops being in progress at the timeout.
+func SkipIfStillRunning(logger *log.Logger) JobWrapper {
+	return func(j Job) Job {
+		return &skipJob{job: j, logger: logger, running: make(chan struct{}, 1)}
+	}
+}
+
+type skipJob struct {
+	job     Job
+	logger  *log.Logger
+	running chan struct{}
+}
+
+func (j *skipJob) Run() { j.run(context.Background()) }
+
+func (j *skipJob) RunCtx(ctx context.Context) { j.run(ctx) }
+
+func (j *skipJob) Unwrap() Job { return j.job }
+
+func (j *skipJob) runErr(ctx context.Context) error { return j.run(ctx) }
+
+func (j *skipJob) run(ctx context.Context) error {
+	select {
+	case j.running <- struct{}{}:
+		defer func() { <-j.running }()
+		return runJob(ctx, j.job)
+	default:
+		logf(j.logger, "cron: skipping a run, as the previous one is still running")
+		return skipError("still running")
+	}
+}
+
+// RecoverWith returns a JobWrapper that recovers from the panics of the wrapped
+// job, and calls handler with the ID of the entry, the value the job panicked
+// with and the stack of the job at that point. The ID is 0 if the job is not
+// run by a Cron. Once handler returns, the panic is raised again if repanic is
+// true, or else swallowed, in which case the run does not fail.
+func RecoverWith(handler func(id EntryID, recovered interface{}, stack []byte), repanic bool) JobWrapper {
+	return func(j Job) Job {
+		return &recoverJob{job: j, handler: handler, repanic: repanic}
+	}
+}
+
+type recoverJob struct {
+	job     Job
+	handler func(EntryID, interface{}, []byte)
+	repanic bool
+}
+
+func (j *recoverJob) Run() { j.run(context.Background()) }
+
+func (j *recoverJob) RunCtx(ctx context.Context) { j.run(ctx) }
+
+func (j *recoverJob) Unwrap() Job { return j.job }
+
+func (j *recoverJob) runErr(ctx context.Context) error { return j.run(ctx) }
+
+func (j *recoverJob) run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, _ := EntryFromContext(ctx)
+			j.handler(e.ID, r, debug.Stack())
+			if j.repanic {
+				panic(r)
+			}
+			err = nil
+		}
+	}()
+	return runJob(ctx, j.job)
+}
+
+// DelayIfStillRunning returns a JobWrapper that delays a run of the wrapped job
+// until its previous runs are complete, so that they run one at a time, in
+// order. Delays of more than a minute are logged to logger (or the standard
+// logger if nil).
+func DelayIfStillRunning(logger *log.Logger) JobWrapper {
+	return DelayIfStillRunningN(logger, -1, 0)
+}
+
+// DelayIfStillRunningN is like DelayIfStillRunning, with at most maxQueued runs
+// waiting for the run in progress, the runs over that being skipped, and with
+// the runs that waited more than maxDelay abandoned rather than run. Skipped
+// and abandoned runs are logged, and fail with ErrSkipped, as do the runs
+// cancelled while waiting. A negative maxQueued means no limit, and a zero
+// maxDelay no maximum. The delays are measured by the clock of the Cron.
+func DelayIfStillRunningN(logger *log.Logger, maxQueued int, maxDelay time.Duration) JobWrapper {
+	return func(j Job) Job {
+		return &delayJob{
+			job:       j,
+			logger:    logger,
+			maxQueued: maxQueued,
+			maxDelay:  maxDelay,
+			running:   make(chan struct{}, 1),
+		}
+	}
+}
+
+type delayJob struct {
+	job       Job
+	logger    *log.Logger
+	maxQueued int
+	maxDelay  time.Duration
+	running   chan struct{}
+
+	mu      sync.Mutex
+	pending int // The number of runs in progress or waiting.
+}
+
+func (j *delayJob) Run() { j.run(context.Background()) }
+
+func (j *delayJob) RunCtx(ctx context.Context) { j.run(ctx) }
+
+func (j *delayJob) Unwrap() Job { return j.job }
+
+func (j *delayJob) runErr(ctx context.Context) error { return j.run(ctx) }
+
+func (j *delayJob) run(ctx context.Context) error {
+	clock := clockOf(ctx, nil)
+	start := clock.Now()
+	j.mu.Lock()
+	if j.maxQueued >= 0 && j.pending > j.maxQueued {
+		j.mu.Unlock()
+		logf(j.logger, "cron: skipping a run, as %d runs are already waiting", j.maxQueued)
+		return skipError("queue full")
+	}
+	j.pending++
+	j.mu.Unlock()
+	defer func() {
+		j.mu.Lock()
+		j.pending--
+		j.mu.Unlock()
+	}()
+
+	select {
+	case j.running <- struct{}{}:
+	case <-ctx.Done():
+		logf(j.logger, "cron: abandoning a run cancelled while delayed")
+		return skipError("cancelled")
+	}
+	defer func() { <-j.running }()
+	delay := clock.Since(start)
+	if j.maxDelay > 0 && delay > j.maxDelay {
+		logf(j.logger, "cron: abandoning a run delayed by %v, more than %v", delay, j.maxDelay)
+		return skipError("delayed")
+	}
+	if delay > time.Minute {
+		logf(j.logger, "cron: run delayed by %v", delay)
+	}
+	return runJob(ctx, j.job)
+}
+
+// RateLimitPolicy is what RateLimit does with the runs over the budget.
+type RateLimitPolicy struct {
+	// Whether the runs over the budget wait for a token, rather than being
+	// skipped.
+	Wait bool
+
+	// The maximum wait for a token, over which the run is skipped. Zero means
+	// no maximum.
+	MaxWait time.Duration
+
+	// The logger the skipped runs are logged to, or the standard logger if
+	// nil.
+	Logger *log.Logger
+
+	// The clock the tokens are refilled by, or the clock of the Cron running
+	// the job if nil.
+	Clock clockwork.Clock
+}
+
+// RateLimit returns a JobWrapper that limits the runs of the wrapped job to n
+// per duration, with a bucket of n tokens refilled at that rate, which each
+// run takes one token from. A run with no token left is skipped, failing with
+// ErrSkipped, or waits for a token according to the policy. Each wrapped job
+// has its own bucket. An n or per of 0 or less means no limit, and the job is
+// returned as is.
+//
+// Put it inside SkipIfStillRunning, as in Chain(SkipIfStillRunning(nil),
+// RateLimit(n, per, policy)), so that the skipped runs do not take tokens, and
+// a run waiting for a token counts as still running.
+func RateLimit(n int, per time.Duration, policy RateLimitPolicy) JobWrapper {
+	return func(j Job) Job {
+		if n <= 0 || per <= 0 {
+			return j
+		}
+		return &rateLimitJob{
+			job:    j,
+			policy: policy,
+			size:   float64(n),
+			every:  per / time.Duration(n),
+			tokens: float64(n),
+		}
+	}
+}
+
+type rateLimitJob struct {
+	job    Job
+	policy RateLimitPolicy
+	size   float64
+	every  time.Duration // The time to refill one token.
+
+	mu     sync.Mutex
+	tokens float64   // Negative when runs are waiting for tokens.
+	last   time.Time // The time of the last refill, zero before the first run.
+}
+
+func (j *rateLimitJob) Run() { j.run(context.Background()) }
+
+func (j *rateLimitJob) RunCtx(ctx context.Context) { j.run(ctx) }
+
+func (j *rateLimitJob) Unwrap() Job { return j.job }
+
+func (j *rateLimitJob) runErr(ctx context.Context) error { return j.run(ctx) }
+
+func (j *rateLimitJob) run(ctx context.Context) error {
+	clock := clockOf(ctx, j.policy.Clock)
+	wait, ok := j.take(clock.Now())
+	if !ok {
+		logf(j.policy.Logger, "cron: skipping a run over the rate limit")
+		return skipError("rate limited")
+	}
+	if wait > 0 {
+		timer := clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			j.giveBack()
+			return skipError("rate limited")
+		}
+	}
+	return runJob(ctx, j.job)
+}
+
+// take takes a token from the bucket, and returns how long to wait for it, or
+// false if the run is to be skipped.
+func (j *rateLimitJob) take(now time.Time) (time.Duration, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.last.IsZero() {
+		j.last = now
+	}
+	j.tokens += float64(now.Sub(j.last)) / float64(j.every)
+	if j.tokens > j.size {
+		j.tokens = j.size
+	}
+	j.last = now
+	if j.tokens >= 1 {
+		j.tokens--
+		return 0, true
+	}
+	wait := time.Duration((1 - j.tokens) * float64(j.every))
+	if !j.policy.Wait || (j.policy.MaxWait > 0 && wait > j.policy.MaxWait) {
+		return 0, false
+	}
+	j.tokens--
+	return wait, true
+}
+
+// giveBack returns the token taken by a run that did not wait for it.
+func (j *rateLimitJob) giveBack() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.tokens++
+}
+
+// WithTimeout returns a JobWrapper that bounds each run of the wrapped job to
+// the given duration. A timeout is logged to logger (or the standard logger if
+// nil) as soon as a run overruns it, and the run returns then, failing with
+// ErrTimeout: it is recorded as such in the history and stats of the entry,
+// and no longer counts as running.
+//
+// Only jobs implementing JobWithContext can actually be cancelled: they are run
+// with a context that is done once the timeout elapses, as measured by the
+// clock of the Cron. A plain Job keeps running in its goroutine until it
+// returns on its own, which cannot be forced, and Shutdown does not wait for
+// it.
+func WithTimeout(timeout time.Duration, logger *log.Logger) JobWrapper {
+	return func(j Job) Job {
+		return &timeoutJob{job: j, timeout: timeout, logger: logger}
+	}
+}
+
+type timeoutJob struct {
+	job     Job
+	timeout time.Duration
+	logger  *log.Logger
+}
+
+func (j *timeoutJob) Run() { j.run(context.Background()) }
+
+func (j *timeoutJob) RunCtx(ctx context.Context) { j.run(ctx) }
+
+func (j *timeoutJob) Unwrap() Job { return j.job }
+
+func (j *timeoutJob) runErr(ctx context.Context) error { return j.run(ctx) }
+
+// run runs the job until it returns or overruns the timeout, in which case it
+// returns ErrTimeout. A panic of the job before the timeout is raised again.
+func (j *timeoutJob) run(ctx context.Context) error {
+	clock := clockOf(ctx, nil)
+	parent := ctx
+	tctx := &timeoutContext{deadline: clock.Now().Add(j.timeout)}
+	var cancel context.CancelFunc
+	tctx.Context, cancel = context.WithCancel(ctx)
+	defer cancel()
+	ctx = tctx
+	timer := clock.NewTimer(j.timeout)
+	defer timer.Stop()
+	type result struct {
+		err      error
+		panicked interface{}
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		defer func() {
+			res.panicked = recover()
+			done <- res
+		}()
+		res.err = runJob(ctx, j.job)
+	}()
+	returned := func(res result) error {
+		if res.panicked != nil {
+			panic(res.panicked)
+		}
+		return res.err
+	}
+
+	parentDone := parent.Done()
+	for expired := false; !expired; {
+		select {
+		case res := <-done:
+			return returned(res)
+		case <-parentDone:
+			// The parent context is done, which is up to the job to handle,
+			// within the timeout still.
+			parentDone = nil
+		case <-timer.C():
+			expired = true
+		}
+	}
+	atomic.StoreInt32(&tctx.timedOut, 1)
+	cancel()
+	logf(j.logger, "cron: job exceeded its timeout of %v", j.timeout)
+	go func() {
+		if res := <-done; res.panicked != nil {
+			logf(j.logger, "cron: job panicked after its timeout: %v", res.panicked)
+		}
+	}()
+	return ErrTimeout
+}
+
+// timeoutContext is the context of a run of a job returned by WithTimeout,
+// done with context.DeadlineExceeded once the timer of the run fires.
+type timeoutContext struct {
+	context.Context
+	deadline time.Time
+	timedOut int32
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	if deadline, ok := c.Context.Deadline(); ok && deadline.Before(c.deadline) {
+		return deadline, true
+	}
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && atomic.LoadInt32(&c.timedOut) == 1 {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// RetryPolicy is how RetryWithBackoff retries the failed runs of a job.
+type RetryPolicy struct {
+	// The maximum number of attempts of each run, including the first one.
+	// Runs are not retried if it is less than 2.
+	MaxAttempts int
+
+	// The delay before the first retry.
+	InitialDelay time.Duration
+
+	// The factor the delay is multiplied by after each retry. The delay stays
+	// the same if it is less than 1.
+	Multiplier float64
+
+	// The maximum delay before a retry. Zero means no maximum.
+	MaxDelay time.Duration
+
+	// The fraction of each delay, from 0 to 1, added to it at random, to
+	// spread the retries of jobs failing at the same time.
+	Jitter float64
+
+	// The logger the failed attempts are logged to, or the standard logger if
+	// nil.
+	Logger *log.Logger
+
+	// The clock the delays are measured by, or the clock of the Cron running
+	// the job if nil.
+	Clock clockwork.Clock
+}
+
+// RetryError is the error of a run that failed at each of its attempts.
+type RetryError struct {
+	// The number of attempts made.
+	Attempts int
+
+	// The error of the last attempt.
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("cron: job failed after %d attempts: %s", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetryWithBackoff returns a JobWrapper that runs the wrapped job again, on the
+// same goroutine, each time it fails, after a delay growing with each attempt
+// as set by the policy. A job fails if it panics, or with the error of an
+// ErrorJob or a job returned by WithTimeout. The attempts are logged with their
+// numbers, and all belong to the same run of the entry.
+//
+// A run failing at its last attempt fails with a *RetryError. The retries are
+// abandoned once the context of the run, if any, is done.
+func RetryWithBackoff(policy RetryPolicy) JobWrapper {
+	return func(j Job) Job {
+		return &retryJob{job: j, policy: policy}
+	}
+}
+
+type retryJob struct {
+	job    Job
+	policy RetryPolicy
+}
+
+func (j *retryJob) Run() { j.run(context.Background()) }
+
+func (j *retryJob) RunCtx(ctx context.Context) { j.run(ctx) }
+
+func (j *retryJob) Unwrap() Job { return j.job }
+
+func (j *retryJob) runErr(ctx context.Context) error { return j.run(ctx) }
+
+// run runs the job until an attempt succeeds, returning the error of the last
+// attempt if none does.
+func (j *retryJob) run(ctx context.Context) error {
+	delay := j.policy.InitialDelay
+	for attempt := 1; ; attempt++ {
+		err := j.attempt(ctx)
+		if errors.Is(err, ErrSkipped) {
+			return err
+		}
+		if err == nil {
+			if attempt > 1 {
+				logf(j.policy.Logger, "cron: job succeeded at attempt %d", attempt)
+			}
+			return nil
+		}
+		if attempt >= j.policy.MaxAttempts {
+			if attempt == 1 {
+				return err
+			}
+			err = &RetryError{Attempts: attempt, Err: err}
+			logf(j.policy.Logger, "%v", err)
+			return err
+		}
+
+		wait := delay
+		if j.policy.Jitter > 0 && wait > 0 {
+			wait += time.Duration(rand.Int63n(int64(float64(wait)*j.policy.Jitter) + 1))
+		}
+		logf(j.policy.Logger, "cron: job failed at attempt %d of %d, retrying in %v: %v", attempt, j.policy.MaxAttempts, wait, err)
+		timer := clockOf(ctx, j.policy.Clock).NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		if j.policy.Multiplier > 1 {
+			delay = time.Duration(float64(delay) * j.policy.Multiplier)
+		}
+		if j.policy.MaxDelay > 0 && delay > j.policy.MaxDelay {
+			delay = j.policy.MaxDelay
+		}
+	}
+}
+
+// attempt runs the job once, returning its error, or an error describing its
+// panic.
+func (j *retryJob) attempt(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return runJob(ctx, j.job)
+}
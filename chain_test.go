@@ -0,0 +1,636 @@
+package cron
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer is a bytes.Buffer that is safe to write from a timer goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type ctxFuncJob func(context.Context)
+
+func (f ctxFuncJob) Run()                       { f(context.Background()) }
+func (f ctxFuncJob) RunCtx(ctx context.Context) { f(ctx) }
+
+func TestWithTimeoutCancelsContextJob(t *testing.T) {
+	var buf syncBuffer
+	clock := clockwork.NewFakeClock()
+	ctx := context.WithValue(context.Background(), clockKey{}, clock)
+	jobErr := make(chan error, 1)
+	start := clock.Now()
+	job := WithTimeout(20*time.Millisecond, log.New(&buf, "", 0))(ctxFuncJob(func(ctx context.Context) {
+		select {
+		case <-ctx.Done():
+			assert.Equal(t, 20*time.Millisecond, clock.Since(start))
+			jobErr <- ctx.Err()
+		case <-time.After(time.Second):
+			jobErr <- nil
+		}
+	}))
+	go job.(JobWithContext).RunCtx(ctx)
+	clock.BlockUntil(1)
+	clock.Advance(19 * time.Millisecond)
+	select {
+	case <-jobErr:
+		t.Fatal("context done too early")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, <-jobErr)
+	assert.Contains(t, buf.String(), "exceeded its timeout")
+}
+
+func TestWithTimeoutFastJob(t *testing.T) {
+	var buf syncBuffer
+	clock := clockwork.NewFakeClock()
+	ctx := context.WithValue(context.Background(), clockKey{}, clock)
+	calls := 0
+	job := WithTimeout(50*time.Millisecond, log.New(&buf, "", 0))(FuncJob(func() { calls++ }))
+	job.(JobWithContext).RunCtx(ctx)
+	clock.Advance(100 * time.Millisecond)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestWithTimeoutPlainJobOverrun(t *testing.T) {
+	var buf syncBuffer
+	clock := clockwork.NewFakeClock()
+	ctx := context.WithValue(context.Background(), clockKey{}, clock)
+	release := make(chan struct{})
+	defer close(release)
+	job := WithTimeout(10*time.Millisecond, log.New(&buf, "", 0))(FuncJob(func() { <-release }))
+	done := make(chan error, 1)
+	go func() { done <- job.(*timeoutJob).runErr(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Millisecond)
+	assert.Equal(t, ErrTimeout, <-done)
+	assert.Contains(t, buf.String(), "exceeded its timeout")
+}
+
+func TestWithTimeoutCancelledPlainJob(t *testing.T) {
+	var buf syncBuffer
+	clock := clockwork.NewFakeClock()
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), clockKey{}, clock))
+	release := make(chan struct{})
+	defer close(release)
+	job := WithTimeout(10*time.Millisecond, log.New(&buf, "", 0))(FuncJob(func() { <-release }))
+	done := make(chan error, 1)
+	go func() { done <- job.(*timeoutJob).runErr(ctx) }()
+	clock.BlockUntil(1)
+	// The run still times out once its context is cancelled.
+	cancel()
+	clock.Advance(10 * time.Millisecond)
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrTimeout, err)
+	case <-time.After(OneSecond):
+		t.Fatal("expected the cancelled run to time out")
+	}
+	assert.Contains(t, buf.String(), "exceeded its timeout")
+}
+
+func TestWithTimeoutEntry(t *testing.T) {
+	var buf syncBuffer
+	var errs []error
+	var mu sync.Mutex
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithErrorHandler(func(id EntryID, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	slow := WithTimeout(20*time.Millisecond, log.New(&buf, "", 0))(FuncJob(func() {
+		<-release
+		close(finished)
+	}))
+	id := cron.Schedule(Every(time.Hour), slow, WithHistory(2))
+	fast := cron.Schedule(Every(time.Hour), WithTimeout(time.Second, nil)(FuncJob(func() {})), WithHistory(2))
+
+	assert.NoError(t, cron.RunNow(id))
+	clock.BlockUntil(1)
+	clock.Advance(20 * time.Millisecond)
+	assert.NoError(t, cron.RunNow(fast))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+
+	// The slow run stopped counting as running at the timeout, before the job
+	// returned.
+	select {
+	case <-finished:
+		t.Error("expected the slow job to be still running")
+	default:
+	}
+	assert.Equal(t, 0, cron.Entry(id).Running)
+	assert.Equal(t, ErrTimeout, cron.History(id)[0].Err)
+	assert.Equal(t, 1, cron.Entry(id).Stats.ConsecutiveFailures)
+	assert.Equal(t, nil, cron.History(fast)[0].Err)
+	mu.Lock()
+	assert.Equal(t, []error{ErrTimeout}, errs)
+	mu.Unlock()
+	close(release)
+	<-finished
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	var buf syncBuffer
+	clock := clockwork.NewFakeClock()
+	var calls []time.Time
+	job := RetryWithBackoff(RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: time.Second,
+		Multiplier:   2,
+		Logger:       log.New(&buf, "", 0),
+		Clock:        clock,
+	})(FuncJob(func() {
+		calls = append(calls, clock.Now())
+		if len(calls) < 3 {
+			panic("blip")
+		}
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		job.Run()
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Second)
+	<-done
+
+	assert.Equal(t, 3, len(calls))
+	assert.Equal(t, time.Second, calls[1].Sub(calls[0]))
+	assert.Equal(t, 2*time.Second, calls[2].Sub(calls[1]))
+	assert.Contains(t, buf.String(), "failed at attempt 1 of 5")
+	assert.Contains(t, buf.String(), "failed at attempt 2 of 5")
+	assert.Contains(t, buf.String(), "succeeded at attempt 3")
+}
+
+func TestRetryWithBackoffExhausted(t *testing.T) {
+	var buf syncBuffer
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithErrorHandler(func(EntryID, error) {}))
+	calls := 0
+	// The delays are measured by the clock of the Cron.
+	job := RetryWithBackoff(RetryPolicy{
+		MaxAttempts:  3,
+		InitialDelay: time.Minute,
+		Multiplier:   10,
+		MaxDelay:     5 * time.Minute,
+		Jitter:       0.5,
+		Logger:       log.New(&buf, "", 0),
+	})(FuncJob(func() {
+		calls++
+		panic("down")
+	}))
+	id := cron.Schedule(Every(time.Hour), job, WithHistory(1))
+	assert.NoError(t, cron.RunNow(id))
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(7*time.Minute + 30*time.Second)
+	}
+	assert.NoError(t, cron.Shutdown(context.Background()))
+
+	assert.Equal(t, 3, calls)
+	err, ok := cron.History(id)[0].Err.(*RetryError)
+	assert.True(t, ok, "expected a RetryError")
+	assert.Equal(t, 3, err.Attempts)
+	assert.Contains(t, err.Err.Error(), "panic running job: down")
+	assert.Equal(t, uint64(1), cron.Entry(id).Stats.Runs)
+	assert.Contains(t, buf.String(), "failed after 3 attempts")
+}
+
+func TestRetryWithBackoffCancelled(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	calls := 0
+	job := RetryWithBackoff(RetryPolicy{
+		MaxAttempts:  3,
+		InitialDelay: time.Minute,
+		Logger:       log.New(&syncBuffer{}, "", 0),
+		Clock:        clock,
+	})(FuncJob(func() {
+		calls++
+		panic("down")
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		job.(JobWithContext).RunCtx(ctx)
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	cancel()
+	<-done
+	assert.Equal(t, 1, calls)
+}
+
+func TestChainOrder(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, name)
+	}
+	named := func(name string) JobWrapper {
+		return func(j Job) Job {
+			return FuncJob(func() {
+				record(name)
+				j.Run()
+			})
+		}
+	}
+
+	cron := New(clockwork.NewFakeClock(), WithJobWrappers(named("cron1"), named("cron2")))
+	job := FuncJob(func() { record("job") })
+	chained := cron.Schedule(Every(time.Hour), job, WithChain(named("entry1"), named("entry2")))
+	plain := cron.Schedule(Every(time.Hour), job)
+	assert.NoError(t, cron.RunNow(chained))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, []string{"cron1", "cron2", "entry1", "entry2", "job"}, calls)
+
+	calls = nil
+	assert.NoError(t, cron.RunNow(plain))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, []string{"cron1", "cron2", "job"}, calls)
+
+	// Without wrappers, the wrapped job is the job.
+	cron = New(clockwork.NewFakeClock())
+	id := cron.Schedule(Every(time.Hour), DummyJob{})
+	assert.Equal(t, Job(DummyJob{}), cron.Entry(id).WrappedJob)
+}
+
+func TestSkipIfStillRunningPerEntry(t *testing.T) {
+	var buf syncBuffer
+	skip := SkipIfStillRunning(log.New(&buf, "", 0))
+	cron := New(clockwork.NewFakeClock())
+	release := make(chan struct{})
+	var runs int32
+	job := FuncJob(func() {
+		atomic.AddInt32(&runs, 1)
+		<-release
+	})
+	a := cron.Schedule(Every(time.Hour), job, WithChain(skip))
+	b := cron.Schedule(Every(time.Hour), job, WithChain(skip))
+
+	assert.NoError(t, cron.RunNow(a))
+	for atomic.LoadInt32(&runs) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// The second run of a is skipped, but not the run of b.
+	assert.NoError(t, cron.RunNow(a))
+	assert.NoError(t, cron.RunNow(b))
+	for atomic.LoadInt32(&runs) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	for !strings.Contains(buf.String(), "still running") {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	assert.Equal(t, 1, strings.Count(buf.String(), "still running"))
+}
+
+func TestDelayIfStillRunningN(t *testing.T) {
+	var buf syncBuffer
+	var runs int32
+	release := make(chan struct{})
+	wrap := DelayIfStillRunningN(log.New(&buf, "", 0), 1, 0)
+	job := wrap(FuncJob(func() {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			<-release
+		}
+	}))
+	pending := func() int {
+		dj := job.(*delayJob)
+		dj.mu.Lock()
+		defer dj.mu.Unlock()
+		return dj.pending
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); job.Run() }()
+	for atomic.LoadInt32(&runs) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() { defer wg.Done(); job.Run() }()
+	for pending() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// The other activations behind the queued one are skipped.
+	const n = 5
+	for i := 0; i < n-1; i++ {
+		job.Run()
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	assert.Equal(t, n-1, strings.Count(buf.String(), "skipping a run"))
+
+	// Entries wrapped with the same wrapper do not wait for each other.
+	other := wrap(FuncJob(func() { atomic.AddInt32(&runs, 1) }))
+	release = make(chan struct{})
+	atomic.StoreInt32(&runs, 0)
+	go job.Run()
+	for atomic.LoadInt32(&runs) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	other.Run()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	close(release)
+}
+
+func TestDelayIfStillRunningMaxDelay(t *testing.T) {
+	var buf syncBuffer
+	var runs int32
+	release := make(chan struct{})
+	clock := clockwork.NewFakeClock()
+	ctx := context.WithValue(context.Background(), clockKey{}, clock)
+	job := DelayIfStillRunningN(log.New(&buf, "", 0), -1, 20*time.Millisecond)(FuncJob(func() {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			<-release
+		}
+	})).(*delayJob)
+	pending := func() int {
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		return job.pending
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); job.RunCtx(ctx) }()
+	for atomic.LoadInt32(&runs) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	delayed := make(chan error, 1)
+	go func() { delayed <- job.runErr(ctx) }()
+	for pending() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(30 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.True(t, errors.Is(<-delayed, ErrSkipped))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	assert.Contains(t, buf.String(), "abandoning a run delayed by 30ms")
+
+	// A run cancelled while delayed is abandoned as well.
+	release = make(chan struct{})
+	atomic.StoreInt32(&runs, 0)
+	wg.Add(1)
+	go func() { defer wg.Done(); job.RunCtx(ctx) }()
+	for atomic.LoadInt32(&runs) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	go func() { delayed <- job.runErr(cancelled) }()
+	for pending() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.True(t, errors.Is(<-delayed, ErrSkipped))
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	assert.Contains(t, buf.String(), "abandoning a run cancelled while delayed")
+
+	// Without limits, the runs are delayed one after the other.
+	atomic.StoreInt32(&runs, 0)
+	job = DelayIfStillRunning(nil)(FuncJob(func() {
+		atomic.AddInt32(&runs, 1)
+		time.Sleep(10 * time.Millisecond)
+	})).(*delayJob)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); job.Run() }()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+}
+
+func panickingJob() { panic("boom") }
+
+func TestRecoverWith(t *testing.T) {
+	type recovered struct {
+		id    EntryID
+		value interface{}
+		stack string
+	}
+	got := make(chan recovered, 2)
+	handler := func(id EntryID, r interface{}, stack []byte) {
+		got <- recovered{id, r, string(stack)}
+	}
+
+	// Swallowed, the run does not fail.
+	cron := New(clockwork.NewFakeClock(), WithRecoverHandler(handler, false), WithErrorHandler(func(EntryID, error) {
+		t.Error("expected the panic to be swallowed")
+	}))
+	id := cron.Schedule(Every(time.Hour), FuncJob(panickingJob), WithHistory(1))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	r := <-got
+	assert.Equal(t, id, r.id)
+	assert.Equal(t, "boom", r.value)
+	assert.Contains(t, r.stack, "panickingJob")
+	assert.Equal(t, nil, cron.History(id)[0].Err)
+
+	// Raised again, the run fails with the panic.
+	errs := make(chan error, 1)
+	cron = New(clockwork.NewFakeClock(), WithErrorHandler(func(_ EntryID, err error) { errs <- err }))
+	id = cron.Schedule(Every(time.Hour), FuncJob(panickingJob), WithChain(RecoverWith(handler, true)))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, id, (<-got).id)
+	assert.Contains(t, (<-errs).Error(), "boom")
+
+	// Jobs that do not panic run as usual.
+	calls := 0
+	RecoverWith(handler, true)(FuncJob(func() { calls++ })).Run()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, len(got))
+
+	// Outside of a Cron, the entry ID is 0.
+	RecoverWith(handler, false)(FuncJob(panickingJob)).Run()
+	assert.Equal(t, EntryID(0), (<-got).id)
+}
+
+func TestRateLimit(t *testing.T) {
+	var buf syncBuffer
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	calls := 0
+	// The tokens are refilled by the clock of the Cron.
+	job := RateLimit(3, time.Minute, RateLimitPolicy{Logger: log.New(&buf, "", 0)})(FuncJob(func() { calls++ }))
+	id := cron.Schedule(Every(time.Hour), job, WithHistory(1))
+
+	// 3 runs in the first minute, the 4th is skipped.
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, cron.RunNow(id))
+		cron.jobWaiter.Wait()
+	}
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, uint64(1), cron.Entry(id).Stats.Skipped)
+	assert.True(t, cron.History(id)[0].Skipped, "expected the last run to be skipped")
+	assert.Contains(t, buf.String(), "over the rate limit")
+
+	// A token is refilled every 20s, up to 3.
+	clock.Advance(20 * time.Second)
+	assert.NoError(t, cron.RunNow(id))
+	cron.jobWaiter.Wait()
+	assert.NoError(t, cron.RunNow(id))
+	cron.jobWaiter.Wait()
+	assert.Equal(t, 4, calls)
+	clock.Advance(time.Hour)
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, cron.RunNow(id))
+		cron.jobWaiter.Wait()
+	}
+	assert.Equal(t, 7, calls)
+	assert.Equal(t, uint64(3), cron.Entry(id).Stats.Skipped)
+}
+
+func TestRateLimitNoLimit(t *testing.T) {
+	calls := 0
+	job := FuncJob(func() { calls++ })
+	for _, limit := range []struct {
+		n   int
+		per time.Duration
+	}{{0, time.Minute}, {-1, time.Minute}, {1, 0}, {1, -time.Minute}} {
+		calls = 0
+		wrapped := RateLimit(limit.n, limit.per, RateLimitPolicy{})(job)
+		_, limited := wrapped.(*rateLimitJob)
+		assert.False(t, limited)
+		for i := 0; i < 3; i++ {
+			wrapped.Run()
+		}
+		assert.Equal(t, 3, calls)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var calls []time.Time
+	var mu sync.Mutex
+	job := RateLimit(2, time.Minute, RateLimitPolicy{Wait: true, MaxWait: 45 * time.Second, Clock: clock})(FuncJob(func() {
+		mu.Lock()
+		calls = append(calls, clock.Now())
+		mu.Unlock()
+	}))
+	start := clock.Now()
+	job.Run()
+	job.Run()
+
+	// The 3rd run waits 30s for a token, and the 4th would wait 60s.
+	done := make(chan struct{})
+	go func() {
+		job.Run()
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	ran := make(chan struct{})
+	go func() {
+		job.Run()
+		close(ran)
+	}()
+	<-ran
+	clock.Advance(30 * time.Second)
+	<-done
+
+	assert.Equal(t, 3, len(calls))
+	assert.Equal(t, 30*time.Second, calls[2].Sub(start))
+
+	// A cancelled wait gives its token back.
+	ctx, cancel := context.WithCancel(context.Background())
+	done = make(chan struct{})
+	go func() {
+		job.(JobWithContext).RunCtx(ctx)
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	cancel()
+	<-done
+	clock.Advance(30 * time.Second)
+	job.Run()
+	assert.Equal(t, 4, len(calls))
+}
+
+func TestWrapperFunc(t *testing.T) {
+	var got []error
+	errs := make(chan error, 1)
+	cron := New(clockwork.NewFakeClock(), WithErrorHandler(func(_ EntryID, err error) { errs <- err }))
+	var activation time.Time
+	id, _ := cron.AddFuncE("@every 1h", func() error { return errors.New("failed") }, WithChain(WrapperFunc(func(ctx context.Context, run func(context.Context) error) error {
+		activation, _ = ActivationFromContext(ctx)
+		err := run(ctx)
+		got = append(got, err)
+		return fmt.Errorf("wrapped: %v", err)
+	})))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, 1, len(got))
+	assert.Equal(t, "failed", got[0].Error())
+	assert.Equal(t, "wrapped: failed", (<-errs).Error())
+	assert.Equal(t, cron.Entry(id).Prev, activation)
+}
+
+type statusJob struct{ status string }
+
+func (j *statusJob) Run() {}
+
+func (j *statusJob) Status() string { return j.status }
+
+func TestUnwrapJob(t *testing.T) {
+	job := &statusJob{status: "idle"}
+	wrapped := Chain(RecoverWith(func(EntryID, interface{}, []byte) {}, false), SkipIfStillRunning(nil), DelayIfStillRunning(nil))(job)
+	assert.True(t, UnwrapJob(wrapped) == Job(job))
+	assert.True(t, UnwrapJob(job) == Job(job))
+
+	found, ok := JobAs[*statusJob](wrapped)
+	assert.True(t, ok)
+	assert.True(t, found == job)
+	status, ok := JobAs[interface {
+		Job
+		Status() string
+	}](wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, "idle", status.Status())
+	_, ok = JobAs[*statusJob](FuncJob(func() {}))
+	assert.False(t, ok)
+
+	cron := New(clockwork.NewFakeClock(), WithJobWrappers(WithTimeout(time.Minute, nil)))
+	id, _ := cron.AddJob("@every 1h", job, WithChain(SkipIfStillRunning(nil)))
+	entry := cron.Entry(id)
+	assert.True(t, entry.Job == Job(job))
+	assert.True(t, UnwrapJob(entry.WrappedJob) == Job(job))
+}
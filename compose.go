@@ -0,0 +1,63 @@
+package cron
+
+import "time"
+
+// UnionSchedule is a schedule activated at the activations of any of its
+// schedules.
+type UnionSchedule struct {
+	Schedules []Schedule
+}
+
+// Union returns a Schedule activated at the activations of any of the given
+// schedules, such as every hour on weekdays and at 08:00 on Saturdays, with a
+// single entry.
+func Union(schedules ...Schedule) UnionSchedule {
+	return UnionSchedule{Schedules: schedules}
+}
+
+// Next returns the earliest next activation of the schedules after t, or the
+// zero time if none of them has one.
+func (s UnionSchedule) Next(t time.Time) time.Time {
+	var earliest time.Time
+	for _, schedule := range s.Schedules {
+		if next := schedule.Next(t); !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
+			earliest = next
+		}
+	}
+	return earliest
+}
+
+// IntersectSchedule is a schedule activated at the activations of A that are
+// also activations of B.
+type IntersectSchedule struct {
+	A, B Schedule
+}
+
+// Intersect returns a Schedule activated at the times both schedules are
+// activated at, such as on the 1st of the month only if it is a Monday. Unlike
+// a spec restricting both day fields, it always takes both into account.
+// Excluding the times a predicate rejects is done with Exclude instead.
+func Intersect(a, b Schedule) IntersectSchedule {
+	return IntersectSchedule{A: a, B: b}
+}
+
+// Next returns the next time after t both schedules are activated at, by
+// moving the earlier of their activations to the later one until they meet.
+// If no time is found within five years, Next returns the zero time.
+func (s IntersectSchedule) Next(t time.Time) time.Time {
+	limit := t.AddDate(5, 0, 0)
+	a, b := s.A.Next(t), s.B.Next(t)
+	for {
+		if a.IsZero() || b.IsZero() || a.After(limit) || b.After(limit) {
+			return time.Time{}
+		}
+		switch {
+		case a.Equal(b):
+			return a
+		case a.Before(b):
+			a = s.A.Next(b.Add(-time.Nanosecond))
+		default:
+			b = s.B.Next(a.Add(-time.Nanosecond))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnionNext(t *testing.T) {
+	weekdays, _ := Parse("0 0 * * * MON-FRI")
+	saturdays, _ := Parse("0 0 8 * * SAT")
+	union := Union(weekdays, saturdays)
+	expected := []string{
+		"Fri Jul 13 23:00 2012",
+		"Sat Jul 14 08:00 2012",
+		"Mon Jul 16 00:00 2012",
+		"Mon Jul 16 01:00 2012",
+	}
+	actual := nextN(union.Next, getTime("Fri Jul 13 22:30 2012"), len(expected))
+	for i, value := range expected {
+		assert.Equal(t, getTime(value), actual[i])
+	}
+
+	// Schedules without activations are left out.
+	assert.Equal(t, getTime("Sat Jul 14 08:00 2012"), Union(At(getTime("Sun Jul 1 00:00 2012")), saturdays).Next(getTime("Fri Jul 13 22:30 2012")))
+	assert.True(t, Union().Next(getTime("Fri Jul 13 22:30 2012")).IsZero())
+}
+
+func TestIntersectNext(t *testing.T) {
+	first, _ := Parse("0 0 9 1 * *")
+	mondays, _ := Parse("0 0 * * * MON")
+	tests := []struct {
+		sched    Schedule
+		time     string
+		expected string
+	}{
+		// The 1st of the month only if it is a Monday, several months out.
+		{Intersect(first, mondays), "Tue Jan 2 00:00 2024", "Mon Apr 1 09:00 2024"},
+		{Intersect(mondays, first), "Tue Jan 2 00:00 2024", "Mon Apr 1 09:00 2024"},
+		{Intersect(first, mondays), "Mon Apr 1 09:00 2024", "Mon Jul 1 09:00 2024"},
+		{Intersect(first, first), "Tue Jan 2 00:00 2024", "Thu Feb 1 09:00 2024"},
+	}
+	for _, test := range tests {
+		actual := test.sched.Next(getTime(test.time))
+		if expected := getTime(test.expected); !actual.Equal(expected) {
+			t.Errorf("%s: (expected) %v != %v (actual)", test.time, expected, actual)
+		}
+	}
+
+	// The search stops after five years without a common activation.
+	midnight, _ := Parse("0 0 0 * * *")
+	halfPast, _ := Parse("0 30 0 * * *")
+	assert.True(t, Intersect(midnight, halfPast).Next(getTime("Tue Jan 2 00:00 2024")).IsZero())
+	never := Intersect(first, At(getTime("Tue Jan 2 00:00 2024")))
+	assert.True(t, never.Next(getTime("Mon Jan 1 00:00 2024")).IsZero())
+}
+
+func TestComposedEntry(t *testing.T) {
+	weekdays, _ := Parse("0 0 * * * MON-FRI")
+	saturdays, _ := Parse("0 0 8 * * SAT")
+	cron := NewWithLocation(clockwork.NewFakeClock(), time.UTC, WithJitterSeed(1))
+	schedule := Between(Union(weekdays, saturdays), Window{Start: 8 * time.Hour, End: 10 * time.Hour})
+	id := cron.Schedule(schedule, FuncJob(func() {}), WithJitter(time.Minute))
+
+	runs := cron.DryRun(getTime("Fri Jul 13 12:00 2012"), 4*24*time.Hour)
+	expected := []string{"Sat Jul 14 08:00 2012", "Mon Jul 16 08:00 2012", "Mon Jul 16 09:00 2012", "Tue Jul 17 08:00 2012", "Tue Jul 17 09:00 2012"}
+	assert.Len(t, runs, len(expected))
+	for i, run := range runs {
+		assert.Equal(t, id, run.EntryID)
+		start := getTime(expected[i])
+		assert.False(t, run.Activation.Before(start))
+		assert.True(t, run.Activation.Before(start.Add(time.Minute)))
+	}
+}
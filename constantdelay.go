@@ -1,6 +1,10 @@
 package cron
 
-import "time"
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
 
 // ConstantDelaySchedule represents a simple recurring duty cycle, e.g. "Every 5 minutes".
 // It does not support jobs more frequent than once a second.
@@ -25,3 +29,282 @@ func Every(duration time.Duration) ConstantDelaySchedule {
 func (schedule ConstantDelaySchedule) Next(t time.Time) time.Time {
 	return t.Add(schedule.Delay - time.Duration(t.Nanosecond())*time.Nanosecond)
 }
+
+// Equal reports whether other is a ConstantDelaySchedule with the same delay.
+func (schedule ConstantDelaySchedule) Equal(other Schedule) bool {
+	o, ok := other.(ConstantDelaySchedule)
+	return ok && o.Delay == schedule.Delay
+}
+
+// String returns the descriptor for the schedule, e.g. "@every 1h30m0s".
+func (schedule ConstantDelaySchedule) String() string {
+	return "@every " + schedule.Delay.String()
+}
+
+// PreciseDelaySchedule is like ConstantDelaySchedule, but keeps sub-second
+// delays and activation times.
+type PreciseDelaySchedule struct {
+	Delay time.Duration
+}
+
+// EveryPrecise returns a Schedule that activates once every duration, to the
+// nanosecond. Delays of less than a millisecond round up to 1 millisecond.
+func EveryPrecise(duration time.Duration) PreciseDelaySchedule {
+	if duration < time.Millisecond {
+		duration = time.Millisecond
+	}
+	return PreciseDelaySchedule{Delay: duration}
+}
+
+// Next returns the next time this should be run.
+func (schedule PreciseDelaySchedule) Next(t time.Time) time.Time {
+	return t.Add(schedule.Delay)
+}
+
+// Equal reports whether other is a PreciseDelaySchedule with the same delay.
+func (schedule PreciseDelaySchedule) Equal(other Schedule) bool {
+	o, ok := other.(PreciseDelaySchedule)
+	return ok && o.Delay == schedule.Delay
+}
+
+// String returns the descriptor for the schedule, e.g. "@every 250ms".
+func (schedule PreciseDelaySchedule) String() string {
+	return "@every " + schedule.Delay.String()
+}
+
+// AlignedDelaySchedule is like ConstantDelaySchedule, with activations at the
+// multiples of the delay on the wall clock since midnight, in the location of
+// the times given to Next: "@every-aligned 15m" activates at 10:15, 10:30 and
+// 10:45 whenever it starts. A delay that does not divide a day starts again
+// from each midnight, so 7h activates at 00:00, 07:00, 14:00 and 21:00.
+type AlignedDelaySchedule struct {
+	Delay time.Duration
+}
+
+// EveryAligned returns a Schedule that activates at the multiples of duration
+// since midnight. Delays are truncated to the second, and are from 1 second to
+// 24 hours.
+func EveryAligned(duration time.Duration) AlignedDelaySchedule {
+	if duration > 24*time.Hour {
+		duration = 24 * time.Hour
+	}
+	return AlignedDelaySchedule{Delay: Every(duration).Delay}
+}
+
+// Next returns the next multiple of the delay since midnight after t, or the
+// next midnight.
+func (schedule AlignedDelaySchedule) Next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	since = (since/schedule.Delay + 1) * schedule.Delay
+	for {
+		// A wall clock time skipped by a spring forward is normalized past
+		// it, and one repeated by a fall back may be before t.
+		for ; since < 24*time.Hour; since += schedule.Delay {
+			if next := time.Date(y, m, d, 0, 0, 0, int(since), t.Location()); next.After(t) {
+				return next
+			}
+		}
+		y, m, d = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Date()
+		since = 0
+	}
+}
+
+// Equal reports whether other is an AlignedDelaySchedule with the same delay.
+func (schedule AlignedDelaySchedule) Equal(other Schedule) bool {
+	o, ok := other.(AlignedDelaySchedule)
+	return ok && o.Delay == schedule.Delay
+}
+
+// String returns the descriptor for the schedule, e.g. "@every-aligned 15m0s".
+func (schedule AlignedDelaySchedule) String() string {
+	return "@every-aligned " + schedule.Delay.String()
+}
+
+// AfterCompletionSchedule is like ConstantDelaySchedule, but with the delay
+// measured from the end of each run rather than from its activation, e.g.
+// "@after 5m": a run lasting 3 minutes starts 8 minutes after the previous
+// one, and runs never overlap. The first activation is the delay after the
+// Cron starts, or the entry is added.
+//
+// The Cron only knows when a run ends: while a run is in progress the entry has
+// no next activation time, and once it is over the next one is computed with
+// Next from the time it ended.
+type AfterCompletionSchedule struct {
+	Delay time.Duration
+}
+
+// AfterCompletion returns a Schedule that activates duration after each run
+// completes. Delays of less than a second round up to 1 second, and are
+// truncated to the second.
+func AfterCompletion(duration time.Duration) AfterCompletionSchedule {
+	return AfterCompletionSchedule{Delay: Every(duration).Delay}
+}
+
+// Next returns the time the delay after t, on the second.
+func (schedule AfterCompletionSchedule) Next(t time.Time) time.Time {
+	return ConstantDelaySchedule(schedule).Next(t)
+}
+
+// Equal reports whether other is an AfterCompletionSchedule with the same
+// delay.
+func (schedule AfterCompletionSchedule) Equal(other Schedule) bool {
+	o, ok := other.(AfterCompletionSchedule)
+	return ok && o.Delay == schedule.Delay
+}
+
+// String returns the descriptor for the schedule, e.g. "@after 5m0s".
+func (schedule AfterCompletionSchedule) String() string {
+	return "@after " + schedule.Delay.String()
+}
+
+// RandomDelaySchedule is like ConstantDelaySchedule, with a delay drawn at
+// random for each activation, uniformly from Min to Max to the second. As with
+// @every, the delay is measured from one activation to the next, not from the
+// end of the run.
+type RandomDelaySchedule struct {
+	Min, Max time.Duration
+	rand     *lockedRand
+}
+
+// EveryRange returns a Schedule that activates after a random delay from min
+// to max, drawn again for each activation. Delays are truncated to the second,
+// and round up to 1 second if they are less; max is raised to min if it is
+// less.
+func EveryRange(min, max time.Duration) RandomDelaySchedule {
+	min, max = Every(min).Delay, Every(max).Delay
+	if max < min {
+		max = min
+	}
+	return RandomDelaySchedule{Min: min, Max: max}.WithSeed(time.Now().UnixNano())
+}
+
+// WithSeed returns a copy of the schedule drawing its delays from a source
+// with the given seed, so that they are the same from one run to another, such
+// as in tests.
+func (schedule RandomDelaySchedule) WithSeed(seed int64) RandomDelaySchedule {
+	schedule.rand = &lockedRand{rand: rand.New(rand.NewSource(seed))}
+	return schedule
+}
+
+// Next returns the next time this should be run, after a new random delay.
+// This rounds so that the next activation time will be on the second.
+func (schedule RandomDelaySchedule) Next(t time.Time) time.Time {
+	delay := schedule.Min
+	if n := int64((schedule.Max - schedule.Min) / time.Second); n > 0 && schedule.rand != nil {
+		delay += time.Duration(schedule.rand.int63n(n+1)) * time.Second
+	}
+	return t.Add(delay - time.Duration(t.Nanosecond())*time.Nanosecond)
+}
+
+// Equal reports whether other is a RandomDelaySchedule with the same range.
+func (schedule RandomDelaySchedule) Equal(other Schedule) bool {
+	o, ok := other.(RandomDelaySchedule)
+	return ok && o.Min == schedule.Min && o.Max == schedule.Max
+}
+
+// String returns the descriptor for the schedule, e.g. "@every 5m0s~10m0s".
+func (schedule RandomDelaySchedule) String() string {
+	return "@every " + schedule.Min.String() + "~" + schedule.Max.String()
+}
+
+// lockedRand is a source of random numbers safe for concurrent use.
+type lockedRand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (r *lockedRand) int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Int63n(n)
+}
+
+// BackoffSchedule is like ConstantDelaySchedule, with a delay growing by
+// Factor after each activation, from Initial up to Max, such as for polling a
+// system that asks to be polled less often while it is idle. Reset brings the
+// delay back to Initial, for example from the job once it finds work, with the
+// schedule of EntryFromContext. Cron.ResetBackoff also reschedules the entry.
+//
+// Each call to Next moves on to the next delay: the schedule is not meant to
+// be wrapped by schedules calling it several times for an activation, such as
+// Between.
+type BackoffSchedule struct {
+	Initial, Max time.Duration
+	Factor       float64
+	state        *backoffState
+}
+
+// backoffState is the current delay of a BackoffSchedule, safe for concurrent
+// use. The delay is kept to the nanosecond, so that it grows by small factors.
+type backoffState struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+// Backoff returns a Schedule that activates after initial, then after a delay
+// multiplied by factor each time, up to max. Delays are truncated to the
+// second, and round up to 1 second if they are less; max is raised to initial
+// if it is less, and a factor less than 1 keeps the delay constant.
+func Backoff(initial, max time.Duration, factor float64) BackoffSchedule {
+	initial, max = Every(initial).Delay, Every(max).Delay
+	if max < initial {
+		max = initial
+	}
+	if factor < 1 {
+		factor = 1
+	}
+	return BackoffSchedule{Initial: initial, Max: max, Factor: factor, state: &backoffState{delay: initial}}
+}
+
+// Next returns the next time this should be run, after the current delay, and
+// grows the delay. This rounds so that the next activation time will be on the
+// second.
+func (schedule BackoffSchedule) Next(t time.Time) time.Time {
+	delay := schedule.Initial
+	if s := schedule.state; s != nil {
+		s.mu.Lock()
+		delay = Every(s.delay).Delay
+		grown := time.Duration(float64(s.delay) * schedule.Factor)
+		if grown > schedule.Max || grown < s.delay {
+			grown = schedule.Max
+		}
+		s.delay = grown
+		s.mu.Unlock()
+	}
+	return t.Add(delay - time.Duration(t.Nanosecond())*time.Nanosecond)
+}
+
+// Reset brings the delay back to Initial, from the activation after the one
+// already returned by Next.
+func (schedule BackoffSchedule) Reset() {
+	if s := schedule.state; s != nil {
+		s.mu.Lock()
+		s.delay = schedule.Initial
+		s.mu.Unlock()
+	}
+}
+
+// Delay returns the delay the next call to Next adds.
+func (schedule BackoffSchedule) Delay() time.Duration {
+	if s := schedule.state; s != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return Every(s.delay).Delay
+	}
+	return schedule.Initial
+}
+
+// clone returns a copy of the schedule with a state of its own, for planning
+// activations without moving on the delays of the schedule.
+func (schedule BackoffSchedule) clone() BackoffSchedule {
+	delay := schedule.Initial
+	if s := schedule.state; s != nil {
+		s.mu.Lock()
+		delay = s.delay
+		s.mu.Unlock()
+	}
+	schedule.state = &backoffState{delay: delay}
+	return schedule
+}
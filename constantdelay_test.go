@@ -52,3 +52,186 @@ func TestConstantDelayNext(t *testing.T) {
 		}
 	}
 }
+
+func TestConstantDelayEqualAndString(t *testing.T) {
+	sched, _ := Parse("@every 1h30m")
+	if !Every(90 * time.Minute).Equal(sched) {
+		t.Error("expected @every 1h30m to equal Every(90m)")
+	}
+	if Every(time.Hour).Equal(sched) {
+		t.Error("expected @every 1h30m not to equal Every(1h)")
+	}
+	if actual := sched.(ConstantDelaySchedule).String(); actual != "@every 1h30m0s" {
+		t.Errorf("(expected) @every 1h30m0s != %s (actual)", actual)
+	}
+}
+
+func TestPreciseDelay(t *testing.T) {
+	from := getTime("Mon Jul 9 14:45:00 2012").Add(5 * time.Millisecond)
+	if actual, expected := EveryPrecise(250*time.Millisecond).Next(from), from.Add(250*time.Millisecond); actual != expected {
+		t.Errorf("(expected) %v != %v (actual)", expected, actual)
+	}
+	if actual := EveryPrecise(0).Delay; actual != time.Millisecond {
+		t.Errorf("(expected) 1ms != %v (actual)", actual)
+	}
+
+	sched, _ := NewParser(Second | Minute | Hour | Dom | Month | Dow | Descriptor | SubsecondPrecision).Parse("@every 100ms")
+	if !EveryPrecise(100 * time.Millisecond).Equal(sched) {
+		t.Errorf("expected @every 100ms to keep its precision, got %v", sched)
+	}
+	sched, _ = Parse("@every 100ms")
+	if !Every(time.Second).Equal(sched) {
+		t.Errorf("expected @every 100ms to round up to 1s without SubsecondPrecision, got %v", sched)
+	}
+}
+
+func TestAlignedDelayNext(t *testing.T) {
+	tests := []struct {
+		time     string
+		delay    time.Duration
+		expected string
+	}{
+		// Aligned on the quarter hours whenever it starts.
+		{"Mon Jul 9 10:07 2012", 15 * time.Minute, "Mon Jul 9 10:15 2012"},
+		{"Mon Jul 9 10:07:59 2012", 15 * time.Minute, "Mon Jul 9 10:15 2012"},
+		{"Mon Jul 9 10:15 2012", 15 * time.Minute, "Mon Jul 9 10:30 2012"},
+		{"Mon Jul 9 10:52 2012", 15 * time.Minute, "Mon Jul 9 11:00 2012"},
+		{"Mon Jul 9 10:00:01 2012", 10 * time.Second, "Mon Jul 9 10:00:10 2012"},
+
+		// Across midnight.
+		{"Mon Jul 9 23:50 2012", 15 * time.Minute, "Tue Jul 10 00:00 2012"},
+		{"Mon Dec 31 23:59:45 2012", 30 * time.Second, "Tue Jan 1 00:00:00 2013"},
+
+		// Delays not dividing a day start again from midnight.
+		{"Mon Jul 9 06:00 2012", 7 * time.Hour, "Mon Jul 9 07:00 2012"},
+		{"Mon Jul 9 21:00 2012", 7 * time.Hour, "Tue Jul 10 00:00 2012"},
+		{"Mon Jul 9 00:00 2012", 24 * time.Hour, "Tue Jul 10 00:00 2012"},
+	}
+
+	for _, c := range tests {
+		actual := EveryAligned(c.delay).Next(getTime(c.time))
+		expected := getTime(c.expected)
+		if actual != expected {
+			t.Errorf("%s, \"%s\": (expected) %v != %v (actual)", c.time, c.delay, expected, actual)
+		}
+	}
+
+	// In the location of the time.
+	ist := time.FixedZone("IST", 5*3600+1800)
+	from := time.Date(2012, time.July, 9, 10, 7, 0, 0, time.UTC)
+	if actual, expected := EveryAligned(time.Hour).Next(from.In(ist)), time.Date(2012, time.July, 9, 10, 30, 0, 0, time.UTC); !actual.Equal(expected) {
+		t.Errorf("(expected) %v != %v (actual)", expected, actual)
+	}
+}
+
+func TestAlignedDelayParse(t *testing.T) {
+	sched, err := Parse("@every-aligned 15m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EveryAligned(15 * time.Minute).Equal(sched) {
+		t.Error("expected @every-aligned 15m to equal EveryAligned(15m)")
+	}
+	if Every(15 * time.Minute).Equal(sched) {
+		t.Error("expected @every-aligned 15m not to equal Every(15m)")
+	}
+	if actual := sched.(AlignedDelaySchedule).String(); actual != "@every-aligned 15m0s" {
+		t.Errorf("(expected) @every-aligned 15m0s != %s (actual)", actual)
+	}
+	for _, spec := range []string{"@every-aligned 25h", "@every-aligned -1m", "@every-aligned x"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
+
+func TestRandomDelay(t *testing.T) {
+	sched := EveryRange(5*time.Minute, 10*time.Minute).WithSeed(1)
+	from := getTime("Mon Jul 9 14:45:00.005 2012")
+	var total time.Duration
+	var low, high bool
+	const n = 10000
+	for i := 0; i < n; i++ {
+		next := sched.Next(from)
+		delay := next.Sub(from.Truncate(time.Second))
+		if delay < 5*time.Minute || delay > 10*time.Minute || next.Nanosecond() != 0 {
+			t.Fatalf("delay %v out of [5m, 10m], or next %v not on the second", delay, next)
+		}
+		low = low || delay == 5*time.Minute
+		high = high || delay == 10*time.Minute
+		total += delay
+	}
+	if mean := total / n; mean < 7*time.Minute+15*time.Second || mean > 7*time.Minute+45*time.Second {
+		t.Errorf("(expected) mean of about 7m30s != %v (actual)", mean)
+	}
+	if !low || !high {
+		t.Errorf("expected both ends of the range to be drawn, got min %v and max %v", low, high)
+	}
+
+	// The same seed gives the same delays.
+	a, b := EveryRange(time.Second, time.Hour).WithSeed(7), EveryRange(time.Second, time.Hour).WithSeed(7)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Next(from), b.Next(from); x != y {
+			t.Errorf("(expected) %v != %v (actual)", x, y)
+		}
+	}
+
+	// Inverted and sub-second ranges.
+	if s := EveryRange(time.Minute, time.Second); s.Min != time.Minute || s.Max != time.Minute {
+		t.Errorf("(expected) 1m~1m != %v~%v (actual)", s.Min, s.Max)
+	}
+	if s := EveryRange(time.Millisecond, 1500*time.Millisecond); s.Min != time.Second || s.Max != time.Second {
+		t.Errorf("(expected) 1s~1s != %v~%v (actual)", s.Min, s.Max)
+	}
+}
+
+func TestRandomDelayEqualAndString(t *testing.T) {
+	sched, err := Parse("@every 5m~10m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EveryRange(5*time.Minute, 10*time.Minute).Equal(sched) {
+		t.Error("expected @every 5m~10m to equal EveryRange(5m, 10m)")
+	}
+	if EveryRange(5*time.Minute, 11*time.Minute).Equal(sched) || Every(5*time.Minute).Equal(sched) {
+		t.Error("expected @every 5m~10m not to equal other schedules")
+	}
+	if actual := sched.(RandomDelaySchedule).String(); actual != "@every 5m0s~10m0s" {
+		t.Errorf("(expected) @every 5m0s~10m0s != %s (actual)", actual)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	sched := Backoff(10*time.Second, 10*time.Minute, 2)
+	from := getTime("Mon Jul 9 14:45:00.005 2012")
+	expected := []time.Duration{10, 20, 40, 80, 160, 320, 600, 600}
+	for _, seconds := range expected {
+		next := sched.Next(from)
+		if delay := next.Sub(from.Truncate(time.Second)); delay != seconds*time.Second {
+			t.Errorf("(expected) %v != %v (actual)", seconds*time.Second, delay)
+		}
+		from = next
+	}
+
+	// Reset starts again from the initial delay.
+	sched.Reset()
+	if delay := sched.Next(from).Sub(from); delay != 10*time.Second {
+		t.Errorf("(expected) 10s != %v (actual)", delay)
+	}
+	if delay := sched.Delay(); delay != 20*time.Second {
+		t.Errorf("(expected) 20s != %v (actual)", delay)
+	}
+
+	// Delays truncated to the second, growing from the exact ones.
+	small := Backoff(time.Second, time.Minute, 1.5)
+	for _, seconds := range []time.Duration{1, 1, 2, 3, 5, 7} {
+		if delay := small.Next(from).Sub(from); delay != seconds*time.Second {
+			t.Errorf("(expected) %v != %v (actual)", seconds*time.Second, delay)
+		}
+	}
+
+	// Invalid arguments.
+	if s := Backoff(time.Minute, time.Second, 0.5); s.Max != time.Minute || s.Factor != 1 {
+		t.Errorf("(expected) 1m, 1 != %v, %v (actual)", s.Max, s.Factor)
+	}
+}
@@ -1,10 +1,18 @@
 package cron
 
 import (
+	"container/heap"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"math/rand"
 	"runtime"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alaingilbert/clockwork"
@@ -14,26 +22,129 @@ import (
 // specified by the schedule. It may be started, stopped, and the entries may
 // be inspected while running.
 type Cron struct {
-	clock    clockwork.Clock
-	remove   chan EntryID
-	nextID   EntryID
-	entries  []*Entry
-	stop     chan struct{}
-	add      chan *Entry
-	snapshot chan []Entry
-	running  bool
-	ErrorLog *log.Logger
-	location *time.Location
-	PanicCh  chan string
+	clock     clockwork.Clock
+	nextID    EntryID
+	entries   entryHeap
+	byID      map[EntryID]*Entry
+	byName    map[string]*Entry
+	stringIDs map[string]*Entry
+	mu        sync.RWMutex // Guards the entries and running.
+	stop      chan struct{}
+	wake      chan struct{}
+	timer     clockwork.Timer // The timer of the run loop, if it is running.
+	starts    []func()        // The runs to start once the entries are unlocked.
+	rearm     chan struct{}
+	running   bool
+	ErrorLog  *log.Logger
+	logger    *slog.Logger
+	verbose   bool
+	location  *time.Location
+	locMu     sync.RWMutex
+	PanicCh   chan string
+	locker    EntryLocker
+	distLock  *distributedLock
+	hooks     jobHooks
+	metrics   Metrics
+	events    eventBus
+	store     StateStore
+	lastRuns  map[string]time.Time
+	onError   func(EntryID, error)
+	missed    MissedRunPolicy
+	parser    Parser
+	pool      *jobPool
+	maxJobs   int // The settings of pool, which is built once all the options are applied.
+	queueMax  int
+	overflow  OverflowPolicy
+	dst       DSTPolicy
+	missedMax int
+	jobWaiter sync.WaitGroup
+	rand      *rand.Rand
+	seed      int64 // The seed of rand.
+	chain     []JobWrapper
+	reloadMu  sync.Mutex
+
+	ctxMu  sync.Mutex
+	ctx    context.Context // The parent of the contexts of the runs.
+	cancel context.CancelFunc
 }
 
-type EntryID int
+var (
+	// ErrEntryNotFound is returned when an operation refers to an entry that
+	// does not exist.
+	ErrEntryNotFound = errors.New("Entry not found")
+
+	// ErrDuplicateName is returned when adding an entry with the name of an
+	// existing entry.
+	ErrDuplicateName = errors.New("Duplicate entry name")
+
+	// ErrDuplicateID is returned when adding an entry with the string ID of an
+	// existing entry.
+	ErrDuplicateID = errors.New("Duplicate entry ID")
+
+	// ErrReservedID is returned when adding an entry with a string ID of the
+	// form of the generated ones, "e-" followed by digits.
+	ErrReservedID = errors.New("Reserved entry ID")
+
+	// ErrInvalidWindow is returned when adding an entry with an end, set with
+	// WithEndAt, before its start, set with WithStartAt.
+	ErrInvalidWindow = errors.New("Activation window ends before it starts")
+
+	// ErrSkipped is returned when a requested run is skipped. A run failing
+	// with it, such as one skipped by SkipIfStillRunning, is recorded as
+	// skipped rather than failed.
+	ErrSkipped = errors.New("Run skipped")
+
+	// ErrDependencyCycle is returned when adding an entry that would depend,
+	// through WithRunAfter, on itself.
+	ErrDependencyCycle = errors.New("Dependency cycle")
+
+	// ErrTimeout is the error of a run that exceeded the timeout set with
+	// WithTimeout.
+	ErrTimeout = errors.New("Run timed out")
+)
+
+// EntryID identifies an entry within a Cron instance. IDs are assigned in
+// increasing order and are never reused, even after the entry is removed.
+type EntryID int64
 
 // Job is an interface for submitted cron jobs.
 type Job interface {
 	Run()
 }
 
+// JobWithContext is a Job that can observe cancellation through the context
+// it is run with. Run is expected to behave like RunCtx with a background
+// context.
+//
+// The Cron runs these jobs with RunCtx, with a context that is done once the
+// Cron is stopped or the entry is removed, and that carries the entry, as
+// returned by EntryFromContext. Plain jobs are run with Run.
+type JobWithContext interface {
+	Job
+	RunCtx(context.Context)
+}
+
+type entryKey struct{}
+
+type activationKey struct{}
+
+type clockKey struct{}
+
+// EntryFromContext returns the snapshot of the entry a run was started with,
+// from the context of the run, and whether there is one.
+func EntryFromContext(ctx context.Context) (Entry, bool) {
+	e, ok := ctx.Value(entryKey{}).(Entry)
+	return e, ok
+}
+
+// ActivationFromContext returns the activation time a run was started for,
+// from the context of the run, and whether there is one. It is the time the
+// run was requested for runs started with RunNow.
+func ActivationFromContext(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(activationKey{}).(time.Time)
+	return t, ok
+}
+
 // The Schedule describes a job's duty cycle.
 type Schedule interface {
 	// Return the next activation time, later than the given time.
@@ -45,6 +156,10 @@ type Schedule interface {
 type Entry struct {
 	ID EntryID
 
+	// The stable ID of the entry, set with WithID, or else "e-" followed by
+	// its ID. String IDs are unique within a Cron.
+	StringID string
+
 	// The schedule on which this job should be run.
 	Schedule Schedule
 
@@ -58,46 +173,457 @@ type Entry struct {
 
 	// The Job to run.
 	Job Job
+
+	// The Job as run: Job wrapped by the wrappers of the entry, set with
+	// WithChain, then by those of the Cron, set with WithJobWrappers.
+	// UnwrapJob and JobAs get through the wrappers of this package.
+	WrappedJob Job
+
+	// The spec the schedule was parsed from. This is empty for entries added
+	// with a Schedule.
+	Spec string
+
+	// A stable key identifying the job, set with WithKey.
+	Key string
+
+	// The name of the entry, set with WithName. Names are unique within a Cron.
+	Name string
+
+	// The tags of the entry, set with WithTags.
+	Tags []string
+
+	// Whether the entry is paused. A paused entry keeps being scheduled, but
+	// its job is not run.
+	Paused bool
+
+	// Whether the entry was paused after failing as many times in a row as
+	// set with WithDisableAfterFailures. It is cleared by Resume.
+	Disabled bool
+
+	// Whether the job is running at the time of the snapshot.
+	Active bool
+
+	// The number of runs of the job in progress at the time of the snapshot.
+	// It can be more than one if a run lasts longer than the schedule interval.
+	Running int
+
+	// The execution statistics of the entry at the time of the snapshot.
+	Stats Stats
+
+	// The time zone the schedule is evaluated in: the one set with
+	// WithLocationFor, or else the location of the Cron.
+	Location *time.Location
+
+	// Whether the entry has no location of its own, and follows the location
+	// of the Cron when changed with SetLocation.
+	cronLocation bool
+
+	// The priority of the entry, set with WithPriority. Entries due at the
+	// same time are started by decreasing priority.
+	Priority int
+
+	// How a run of the job is started while another one is in progress, set
+	// with WithConcurrencyPolicy.
+	ConcurrencyPolicy ConcurrencyPolicy
+
+	// The window the entry activates in, set with WithStartAt and WithEndAt:
+	// there is no activation before StartAt or after EndAt, unless zero.
+	StartAt, EndAt time.Time
+
+	// The number of scheduled runs left, set with WithMaxRuns. The entry is
+	// removed once no run is left. Zero without WithMaxRuns means the number of
+	// runs is unlimited.
+	RemainingRuns int
+
+	// The func called once the entry is removed after its last run.
+	onComplete func(EntryID)
+
+	// Whether the runs are limited with WithMaxRuns, and the number of the
+	// runs counted towards it whose outcome is not known yet.
+	maxRuns   bool
+	unsettled int
+
+	// The number of consecutive failures the entry is disabled after, and the
+	// func called then, set with WithDisableAfterFailures.
+	disableAfter int
+	onDisable    func(EntryID, error)
+
+	// Whether the entry is removed once its window has ended, set with
+	// WithRemoveAfterEnd.
+	removeAfterEnd bool
+
+	// Whether StringID was set with WithID.
+	customID bool
+
+	// How activations in daylight saving time transitions are handled.
+	dst DSTPolicy
+
+	// Whether to run the job when the Cron starts, set with WithRunOnStart.
+	runOnStart bool
+
+	// How long a run waits for the run it replaces with ReplaceConcurrent, set
+	// with WithReplaceGracePeriod.
+	replaceGrace time.Duration
+
+	// The maximum random delay of each activation, set with WithJitter, and
+	// the source of the delays.
+	jitter time.Duration
+	rand   *rand.Rand
+
+	// The wrappers of the job, set with WithChain.
+	chain []JobWrapper
+
+	// The entry whose runs a run of the job waits for, set with WithRunAfter,
+	// whether the run is skipped if that one failed, and how long it waits at
+	// most, set with WithRunAfterMaxWait.
+	dependsOn     EntryID
+	onlyOnSuccess bool
+	dependWait    time.Duration
+	dependency    *entryState
+
+	// The key of the crontab line the entry was loaded from with Reload, and
+	// that line.
+	crontabKey  string
+	crontabLine CrontabLine
+
+	// State shared by all the snapshots of the entry.
+	state *entryState
+
+	// The run to finish once the run of the snapshot is over, for RunNowAsync.
+	run *Run
+
+	// Whether the run of the snapshot counts towards WithMaxRuns.
+	counted bool
+
+	// Whether the entry waits for its run to be over to get its next time,
+	// with an AfterCompletionSchedule.
+	awaitingRun bool
+
+	// The index of the entry in the entry heap.
+	index int
+}
+
+// entryState is the state of an entry that is updated by its runs.
+type entryState struct {
+	running int32 // Number of runs in progress, accessed atomically.
+
+	mu      sync.Mutex
+	stats   Stats
+	ended   time.Time   // When the last run, even skipped, was over.
+	history []Execution // Ring buffer of the last runs, if enabled.
+	head    int         // Index of the oldest run in history.
+	count   int         // Number of runs in history.
+
+	cancels map[*context.CancelFunc]bool // The cancel funcs of the runs in progress.
+	removed bool                         // Whether the entry was removed.
+	active  int                          // The number of runs of the job in progress.
+	current *activeRun                   // The last run of the job started.
+
+	finished *sync.Cond // Broadcast when a run is done, or the entry removed.
+	last     Execution  // The latest run done, by start time.
+
+	saveMu sync.Mutex // Held while saving the last run to the state store.
+	saved  time.Time  // The activation time of the last run saved.
+}
+
+// activeRun is a run of a job in progress.
+type activeRun struct {
+	cancel context.CancelFunc
+	done   chan struct{} // Closed once the run is done.
+}
+
+// Execution describes a run of an entry, as recorded with WithHistory.
+type Execution struct {
+	// The time the run started and ended.
+	Start, End time.Time
+
+	// The error the run failed with, if any; a panicking job fails with an
+	// error describing the panic.
+	Err error
+
+	// Whether the run was skipped because the lock was not acquired.
+	Skipped bool
+}
+
+// Stats holds the execution statistics of an entry.
+type Stats struct {
+	// The number of completed runs.
+	Runs uint64
+
+	// The time the last run started, and the time the last completed run ended.
+	LastStart, LastEnd time.Time
+
+	// The duration of the last completed run.
+	LastDuration time.Duration
+
+	// How long the last run waited for a slot, with WithMaxConcurrentJobs.
+	LastQueued time.Duration
+
+	// The number of consecutive runs that failed, up to the last one.
+	ConsecutiveFailures int
+
+	// The number of runs skipped, such as with ForbidConcurrent, and the
+	// number of runs cancelled to be replaced with ReplaceConcurrent.
+	Skipped, Replaced uint64
+}
+
+func (s *entryState) started(start time.Time, queued time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.LastStart = start
+	s.stats.LastQueued = queued
+}
+
+// completed records a run that is over, and returns the number of consecutive
+// failures.
+func (s *entryState) completed(start, end time.Time, err error) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Runs++
+	s.stats.LastEnd = end
+	s.stats.LastDuration = end.Sub(start)
+	if err != nil {
+		s.stats.ConsecutiveFailures++
+	} else {
+		s.stats.ConsecutiveFailures = 0
+	}
+	return s.stats.ConsecutiveFailures
+}
+
+// resetFailures resets the number of consecutive failures.
+func (s *entryState) resetFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.ConsecutiveFailures = 0
+}
+
+// track registers the cancel func of a run starting, and returns the func to
+// call once the run is done. The run is cancelled right away if the entry was
+// removed.
+func (s *entryState) track(cancel context.CancelFunc) (done func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.removed {
+		cancel()
+		return func() {}
+	}
+	if s.cancels == nil {
+		s.cancels = make(map[*context.CancelFunc]bool)
+	}
+	s.cancels[&cancel] = true
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.cancels, &cancel)
+	}
+}
+
+// cancelRuns cancels the runs in progress, and the runs started from now on.
+func (s *entryState) cancelRuns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removed = true
+	for cancel := range s.cancels {
+		(*cancel)()
+	}
+	s.cancels = nil
+	s.cond().Broadcast()
+}
+
+// cond returns the condition broadcast when a run is done. s.mu must be held.
+func (s *entryState) cond() *sync.Cond {
+	if s.finished == nil {
+		s.finished = sync.NewCond(&s.mu)
+	}
+	return s.finished
+}
+
+// record adds the given run to the history, if enabled, evicting the oldest
+// run if it is full.
+func (s *entryState) record(run Execution) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run.Skipped {
+		s.stats.Skipped++
+	}
+	if !run.Start.Before(s.last.Start) {
+		s.last = run
+		s.cond().Broadcast()
+	}
+	if len(s.history) == 0 {
+		return
+	}
+	if s.count < len(s.history) {
+		s.history[(s.head+s.count)%len(s.history)] = run
+		s.count++
+		return
+	}
+	s.history[s.head] = run
+	s.head = (s.head + 1) % len(s.history)
+}
+
+// runs returns a copy of the history, oldest run first.
+func (s *entryState) runs() []Execution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := make([]Execution, s.count)
+	for i := range runs {
+		runs[i] = s.history[(s.head+i)%len(s.history)]
+	}
+	return runs
+}
+
+func (s *entryState) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// next returns the next activation time of the entry after now, evaluating its
+// schedule in the entry's location.
+func (e *Entry) next(now time.Time) time.Time {
+	if e.Location != nil {
+		now = now.In(e.Location)
+	}
+	if !e.StartAt.IsZero() && now.Before(e.StartAt) {
+		// Delays count from the start of the window, and other schedules
+		// can activate right at it.
+		now = e.StartAt.In(now.Location())
+		if !isDelaySchedule(e.Schedule) {
+			now = now.Add(-time.Nanosecond)
+		}
+	}
+	next := nextWithDST(e.Schedule, now, e.dst)
+	if e.jitter > 0 && !next.IsZero() {
+		// Delay the activation by at most the gap to the one after it.
+		max := e.jitter
+		if after := nextWithDST(detached(e.Schedule), next, e.dst); !after.IsZero() && after.Sub(next) < max {
+			max = after.Sub(next)
+		}
+		next = next.Add(time.Duration(e.rand.Int63n(int64(max))))
+	}
+	if !e.EndAt.IsZero() && next.After(e.EndAt) {
+		return time.Time{}
+	}
+	return next
+}
+
+// detached returns the schedule, or a copy of it with a state of its own if
+// its Next changes its state, so that activations can be computed ahead
+// without changing the ones of the schedule.
+func detached(s Schedule) Schedule {
+	if b, ok := s.(BackoffSchedule); ok {
+		return b.clone()
+	}
+	return s
+}
+
+// ended reports whether the entry is to be removed, its window having ended
+// with WithRemoveAfterEnd.
+func (e *Entry) ended() bool {
+	return e.removeAfterEnd && !e.EndAt.IsZero() && e.Next.IsZero() && !e.awaitingRun
+}
+
+// hasTags reports whether the entry has all the given tags.
+func (e Entry) hasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range e.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 // byTime is a wrapper for sorting the entry array by time
-// (with zero time at the end).
+// (with zero time at the end). Entries with the same time are sorted by
+// decreasing priority, then in the order they were added.
 type byTime []*Entry
 
 func (s byTime) Len() int      { return len(s) }
 func (s byTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s byTime) Less(i, j int) bool {
-	// Two zero times should return false.
-	// Otherwise, zero is "greater" than any other time.
+	// Zero is "greater" than any other time.
 	// (To sort it at the end of the list.)
-	if s[i].Next.IsZero() {
-		return false
+	if s[i].Next.IsZero() != s[j].Next.IsZero() {
+		return s[j].Next.IsZero()
 	}
-	if s[j].Next.IsZero() {
-		return true
+	if !s[i].Next.Equal(s[j].Next) {
+		return s[i].Next.Before(s[j].Next)
 	}
-	return s[i].Next.Before(s[j].Next)
+	if s[i].Priority != s[j].Priority {
+		return s[i].Priority > s[j].Priority
+	}
+	return s[i].ID < s[j].ID
+}
+
+// entryHeap is a min-heap of entries ordered as byTime, so that the run loop
+// finds the next entries to run without sorting all of them.
+type entryHeap []*Entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return byTime(h).Less(i, j) }
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*Entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
 }
 
 // New returns a new Cron job runner, in the Local time zone.
-func New(clock clockwork.Clock) *Cron {
-	return NewWithLocation(clock, clock.Now().Location())
+func New(clock clockwork.Clock, opts ...Option) *Cron {
+	return NewWithLocation(clock, clock.Now().Location(), opts...)
 }
 
-// NewWithLocation returns a new Cron job runner.
-func NewWithLocation(clock clockwork.Clock, location *time.Location) *Cron {
-	return &Cron{
+// NewWithLocation returns a new Cron job runner, modified by the given options.
+func NewWithLocation(clock clockwork.Clock, location *time.Location, opts ...Option) *Cron {
+	seed := time.Now().UnixNano()
+	c := &Cron{
 		clock:    clock,
 		entries:  nil,
-		add:      make(chan *Entry),
-		stop:     make(chan struct{}),
-		snapshot: make(chan []Entry),
-		remove:   make(chan EntryID),
+		byID:     make(map[EntryID]*Entry),
+		parser:   defaultParser,
+		wake:     make(chan struct{}, 1),
+		rearm:    make(chan struct{}, 1),
 		running:  false,
 		ErrorLog: nil,
 		location: location,
 		PanicCh:  make(chan string, 10),
+		rand:     rand.New(rand.NewSource(seed)),
+		seed:     seed,
+	}
+	c.byName = make(map[string]*Entry)
+	c.stringIDs = make(map[string]*Entry)
+	c.missedMax = defaultMissedRunLimit
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.maxJobs > 0 {
+		c.pool = newJobPool(c.maxJobs)
+		c.pool.max = c.queueMax
+		c.pool.overflow = c.overflow
 	}
+	return c
 }
 
 // A wrapper that turns a func() into a cron.Job
@@ -105,200 +631,1571 @@ type FuncJob func()
 
 func (f FuncJob) Run() { f() }
 
+// FuncJobCtx is a wrapper that turns a func(context.Context) into a
+// JobWithContext.
+type FuncJobCtx func(context.Context)
+
+func (f FuncJobCtx) Run() { f(context.Background()) }
+
+func (f FuncJobCtx) RunCtx(ctx context.Context) { f(ctx) }
+
 // AddFunc adds a func to the Cron to be run on the given schedule.
-func (c *Cron) AddFunc(spec string, cmd func()) (EntryID, error) {
-	return c.AddJob(spec, FuncJob(cmd))
+func (c *Cron) AddFunc(spec string, cmd func(), opts ...EntryOption) (EntryID, error) {
+	return c.AddJob(spec, FuncJob(cmd), opts...)
+}
+
+// ErrorJob is a job whose runs can fail with an error, which is passed to the
+// handler set with WithErrorHandler and counted in the stats of the entry. It
+// is added with AddErrorJob.
+type ErrorJob interface {
+	Run() error
+}
+
+// FuncJobE is a wrapper that turns a func() error into an ErrorJob.
+type FuncJobE func() error
+
+func (f FuncJobE) Run() error { return f() }
+
+// errorJobAdapter turns an ErrorJob into a Job.
+type errorJobAdapter struct {
+	job ErrorJob
+}
+
+func (j errorJobAdapter) Run() { j.job.Run() }
+
+func (j errorJobAdapter) runErr(context.Context) error { return j.job.Run() }
+
+// AddErrorJob adds an ErrorJob to the Cron to be run on the given schedule, as
+// with AddJob. The Job of the entry is an adapter for it.
+func (c *Cron) AddErrorJob(spec string, cmd ErrorJob, opts ...EntryOption) (EntryID, error) {
+	return c.AddJob(spec, errorJobAdapter{cmd}, opts...)
+}
+
+// AddFuncE adds a func returning an error to the Cron to be run on the given
+// schedule, as an ErrorJob.
+func (c *Cron) AddFuncE(spec string, cmd func() error, opts ...EntryOption) (EntryID, error) {
+	return c.AddErrorJob(spec, FuncJobE(cmd), opts...)
+}
+
+// AddFuncCtx adds a func to the Cron to be run on the given schedule with the
+// context of each run, as a JobWithContext.
+func (c *Cron) AddFuncCtx(spec string, cmd func(ctx context.Context), opts ...EntryOption) (EntryID, error) {
+	return c.AddJob(spec, FuncJobCtx(cmd), opts...)
 }
 
-// AddJob adds a Job to the Cron to be run on the given schedule.
-func (c *Cron) AddJob(spec string, cmd Job) (EntryID, error) {
-	schedule, err := Parse(spec)
+// AddJob adds a Job to the Cron to be run on the given schedule. It returns
+// ErrDuplicateName if the entry is named after an existing entry,
+// ErrDuplicateID if it has the string ID of an existing entry, ErrReservedID if
+// its string ID is of the form of the generated ones, ErrInvalidWindow if its
+// activation window ends before it starts, and the errors of WithRunAfter.
+func (c *Cron) AddJob(spec string, cmd Job, opts ...EntryOption) (EntryID, error) {
+	schedule, err := c.parse(spec, hashKeyOf(opts))
 	if err != nil {
 		return 0, err
 	}
-	return c.Schedule(schedule, cmd), nil
+	return c.schedule(schedule, cmd, append([]EntryOption{withSpec(spec)}, opts...))
 }
 
-// Schedule adds a Job to the Cron to be run on the given schedule.
-func (c *Cron) Schedule(schedule Schedule, cmd Job) EntryID {
-	c.nextID++
-	entry := &Entry{
-		ID:       c.nextID,
-		Schedule: schedule,
-		Job:      cmd,
+// hashKeyOf returns the key of the H expressions of an entry with the given
+// options: its name, or its key if it has no name.
+func hashKeyOf(opts []EntryOption) string {
+	probe := &Entry{state: &entryState{}}
+	for _, opt := range opts {
+		opt(probe)
 	}
-	if !c.running {
-		c.entries = append(c.entries, entry)
-	} else {
-		c.add <- entry
+	if probe.Name != "" {
+		return probe.Name
 	}
+	return probe.Key
+}
 
-	return entry.ID
+// parse parses the spec of an entry, picking the values of its H expressions
+// from the given key, or from the spec if the key is empty. The key is
+// prefixed with the one set with WithHashKey.
+func (c *Cron) parse(spec, key string) (Schedule, error) {
+	return c.parseWith(c.parser, spec, key)
 }
 
-// Entries returns a snapshot of the cron entries.
-func (c *Cron) Entries() []Entry {
-	if c.running {
-		c.snapshot <- nil
-		return <-c.snapshot
+// parseWith is like parse, with the given parser.
+func (c *Cron) parseWith(p Parser, spec, key string) (Schedule, error) {
+	if key == "" {
+		key = spec
 	}
-	return c.entrySnapshot()
+	if c.parser.hashKey != "" {
+		key = c.parser.hashKey + "/" + key
+	}
+	return p.WithHashKey(key).Parse(spec)
 }
 
-// Entry returns a snapshot of the given entry, or nil if it couldn't be found.
-func (c *Cron) Entry(id EntryID) Entry {
-	for _, entry := range c.Entries() {
-		if id == entry.ID {
-			return entry
-		}
-	}
-	return Entry{}
+// Schedule adds a Job to the Cron to be run on the given schedule. It returns 0
+// if the entry cannot be added, such as when it is named after an existing
+// entry; use AddJob to get the error.
+func (c *Cron) Schedule(schedule Schedule, cmd Job, opts ...EntryOption) EntryID {
+	id, _ := c.schedule(schedule, cmd, opts)
+	return id
 }
 
-// Remove an entry from being run in the future.
-func (c *Cron) Remove(id EntryID) {
-	if c.running {
-		c.remove <- id
-	} else {
-		c.removeEntry(id)
+func (c *Cron) schedule(schedule Schedule, cmd Job, opts []EntryOption) (EntryID, error) {
+	entry, err := c.newEntry(schedule, cmd, opts)
+	if err != nil {
+		return 0, err
+	}
+	c.apply(func() {
+		if err = c.checkEntry(entry); err == nil {
+			c.addEntry(entry, c.now())
+		}
+	})
+	if err != nil {
+		return 0, err
 	}
+	return entry.ID, nil
 }
 
-// Location gets the time zone location
-func (c *Cron) Location() *time.Location {
-	return c.location
+// newEntry returns a new entry running cmd on the given schedule, with the
+// given options.
+func (c *Cron) newEntry(schedule Schedule, cmd Job, opts []EntryOption) (*Entry, error) {
+	entry := &Entry{
+		ID:       EntryID(atomic.AddInt64((*int64)(&c.nextID), 1)),
+		Schedule: schedule,
+		Job:      cmd,
+		state:    &entryState{},
+		dst:      c.dst,
+		rand:     c.rand,
+	}
+	for _, opt := range opts {
+		opt(entry)
+	}
+	if _, ok := schedule.(RebootSchedule); ok {
+		entry.runOnStart = true
+	}
+	entry.WrappedJob = Chain(c.chain...)(Chain(entry.chain...)(cmd))
+	entry.cronLocation = entry.Location == nil
+	if !entry.customID {
+		entry.StringID = generatedID(entry.ID)
+	} else if isGeneratedID(entry.StringID) {
+		return nil, ErrReservedID
+	}
+	if !entry.StartAt.IsZero() && !entry.EndAt.IsZero() && entry.EndAt.Before(entry.StartAt) {
+		return nil, ErrInvalidWindow
+	}
+	return entry, nil
 }
 
-// Start the cron scheduler in its own go-routine, or no-op if already started.
-func (c *Cron) Start() {
-	if c.running {
-		return
+// checkEntry returns an error if the entry cannot be added next to the
+// existing ones. It must be called under apply.
+func (c *Cron) checkEntry(entry *Entry) error {
+	if entry.Name != "" && c.entryByName(entry.Name) != nil {
+		return ErrDuplicateName
 	}
-	c.running = true
-	go c.run()
+	if entry.customID && c.entryByStringID(entry.StringID) != nil {
+		return ErrDuplicateID
+	}
+	if entry.dependsOn != 0 {
+		return c.checkDependency(entry)
+	}
+	return nil
 }
 
-// Run the cron scheduler, or no-op if already running.
-func (c *Cron) Run() {
+// addEntry adds a checked entry, computing its next run time from now if the
+// scheduler is running. It must be called under apply.
+func (c *Cron) addEntry(entry *Entry, now time.Time) {
+	if entry.cronLocation {
+		entry.Location = c.location
+	}
+	if entry.dependsOn != 0 {
+		entry.dependency = c.byID[entry.dependsOn].state
+	}
 	if c.running {
-		return
+		entry.Next = entry.next(now)
+		c.logEntry(slog.LevelDebug, entry, "cron: schedule", slog.Time("now", now), slog.Time("next", entry.Next))
+		c.runOnStart(entry, now)
+		c.runMissed(entry, now, nil)
 	}
-	c.running = true
-	c.run()
+	heap.Push(&c.entries, entry)
+	c.index(entry)
+	c.event(EntryAdded, entry, Event{})
 }
 
-func (c *Cron) runWithRecovery(j Job) {
-	defer func() {
-		if r := recover(); r != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
-			buf = buf[:runtime.Stack(buf, false)]
-			select {
-			case c.PanicCh <- fmt.Sprintf("cron: panic running job: %v\n%s", r, buf):
-			default:
-			}
+// checkDependency returns ErrEntryNotFound if the entry the given entry
+// depends on does not exist, or ErrDependencyCycle if it depends on the given
+// entry.
+func (c *Cron) checkDependency(entry *Entry) error {
+	for id := entry.dependsOn; id != 0; {
+		if id == entry.ID {
+			return ErrDependencyCycle
 		}
-	}()
-	j.Run()
+		dep := c.byID[id]
+		if dep == nil {
+			return ErrEntryNotFound
+		}
+		id = dep.dependsOn
+	}
+	return nil
 }
 
-// Run the scheduler. this is private just due to the need to synchronize
-// access to the 'running' state variable.
-func (c *Cron) run() {
-	// Figure out the next activation times for each entry.
-	now := c.now()
-	for _, entry := range c.entries {
-		entry.Next = entry.Schedule.Next(now)
-	}
+// Entries returns a snapshot of the cron entries.
+func (c *Cron) Entries() []Entry {
+	var entries []Entry
+	c.read(func() {
+		entries = c.entrySnapshot()
+	})
+	return entries
+}
 
-	for {
-		// Determine the next entry to run.
-		sort.Sort(byTime(c.entries))
-
-		var timer clockwork.Timer
-		if len(c.entries) == 0 || c.entries[0].Next.IsZero() {
-			// If there are no entries yet, just sleep - it still handles new entries
-			// and stop requests.
-			timer = c.clock.NewTimer(100000 * time.Hour)
-		} else {
-			timer = c.clock.NewTimer(c.entries[0].Next.Sub(now))
-		}
+// Entry returns a snapshot of the given entry, or a zero Entry, with an ID of
+// 0, if it couldn't be found.
+func (c *Cron) Entry(id EntryID) Entry {
+	entry, _ := c.snapshotOf(func() *Entry { return c.entryByID(id) })
+	return entry
+}
 
-		for {
-			select {
-			case now = <-timer.C():
-				now = now.In(c.location)
-				// Run every entry whose next time was less than now
-				for _, e := range c.entries {
-					if e.Next.After(now) || e.Next.IsZero() {
-						break
-					}
-					go c.runWithRecovery(e.Job)
-					e.Prev = e.Next
-					e.Next = e.Schedule.Next(now)
-				}
+// EntryByName returns a snapshot of the entry with the given name, and whether
+// it was found.
+func (c *Cron) EntryByName(name string) (Entry, bool) {
+	return c.snapshotOf(func() *Entry { return c.entryByName(name) })
+}
 
-			case newEntry := <-c.add:
-				timer.Stop()
-				now = c.now()
-				newEntry.Next = newEntry.Schedule.Next(now)
-				c.entries = append(c.entries, newEntry)
+// RemoveByName removes the entry with the given name from being run in the
+// future, and reports whether there was one. The contexts of its runs in
+// progress are cancelled.
+func (c *Cron) RemoveByName(name string) bool {
+	removed := false
+	c.apply(func() {
+		if e := c.entryByName(name); e != nil {
+			c.cancelEntry(e.ID)
+			removed = true
+		}
+	})
+	return removed
+}
 
-			case <-c.snapshot:
-				c.snapshot <- c.entrySnapshot()
-				continue
+// EntryByStringID returns a snapshot of the entry with the given string ID,
+// and whether it was found.
+func (c *Cron) EntryByStringID(id string) (Entry, bool) {
+	return c.snapshotOf(func() *Entry { return c.entryByStringID(id) })
+}
 
-			case id := <-c.remove:
-				c.removeEntry(id)
+// RemoveByStringID removes the entry with the given string ID from being run
+// in the future, and reports whether there was one. The contexts of its runs
+// in progress are cancelled.
+func (c *Cron) RemoveByStringID(id string) bool {
+	removed := false
+	c.apply(func() {
+		if e := c.entryByStringID(id); e != nil {
+			c.cancelEntry(e.ID)
+			removed = true
+		}
+	})
+	return removed
+}
 
-			case <-c.stop:
-				timer.Stop()
-				return
+// EntriesFunc returns a snapshot of the entries for which pred returns true.
+// pred is called while the entries are locked, so it must not call the Cron.
+func (c *Cron) EntriesFunc(pred func(Entry) bool) []Entry {
+	var entries []Entry
+	c.read(func() {
+		for _, e := range c.entries {
+			if pred(*e) {
+				entries = append(entries, copyEntries([]*Entry{e})...)
 			}
+		}
+	})
+	return entries
+}
 
-			break
+// EntriesPage returns a snapshot of limit entries, starting at offset, of the
+// entries sorted with less, along with the total number of entries. If less is
+// nil the entries are sorted by their next activation time. less is called
+// while the entries are locked, so it must not call the Cron.
+func (c *Cron) EntriesPage(offset, limit int, less func(a, b Entry) bool) ([]Entry, int) {
+	var page []Entry
+	total := 0
+	c.read(func() {
+		sorted := append([]*Entry(nil), c.entries...)
+		if less == nil {
+			sort.Sort(byTime(sorted))
+		} else {
+			sort.SliceStable(sorted, func(i, j int) bool {
+				return less(*sorted[i], *sorted[j])
+			})
 		}
-	}
+		total = len(sorted)
+		if offset < 0 {
+			offset = 0
+		}
+		if offset > total {
+			offset = total
+		}
+		end := offset + limit
+		if limit < 0 || end > total {
+			end = total
+		}
+		page = copyEntries(sorted[offset:end])
+	})
+	return page, total
 }
 
-// Logs an error to stderr or to the configured error log
-func (c *Cron) logf(format string, args ...interface{}) {
-	if c.ErrorLog != nil {
-		c.ErrorLog.Printf(format, args...)
-	} else {
-		log.Printf(format, args...)
-	}
+// EntriesByTag returns a snapshot of the entries having the given tag.
+func (c *Cron) EntriesByTag(tag string) []Entry {
+	return c.EntriesByTags(tag)
 }
 
-// Stop stops the cron scheduler if it is running; otherwise it does nothing.
-func (c *Cron) Stop() {
-	if !c.running {
-		return
+// EntriesByTags returns a snapshot of the entries having all the given tags.
+func (c *Cron) EntriesByTags(tags ...string) []Entry {
+	var entries []Entry
+	for _, entry := range c.Entries() {
+		if entry.hasTags(tags) {
+			entries = append(entries, entry)
+		}
 	}
-	c.stop <- struct{}{}
-	c.running = false
+	return entries
 }
 
-// entrySnapshot returns a copy of the current cron entry list.
+// RemoveByTag removes all the entries having the given tag from being run in
+// the future, and returns how many were removed. None of them runs after
+// RemoveByTag returns; the contexts of the runs already started are cancelled,
+// but plain jobs keep running.
+func (c *Cron) RemoveByTag(tag string) int {
+	removed := 0
+	c.apply(func() {
+		var entries []*Entry
+		for _, e := range c.entries {
+			if e.hasTags([]string{tag}) {
+				e.state.cancelRuns()
+				c.event(EntryRemoved, e, Event{})
+				removed++
+			} else {
+				entries = append(entries, e)
+			}
+		}
+		c.setEntries(entries)
+	})
+	return removed
+}
+
+// Pause stops the given entry from being run until it is resumed, and reports
+// whether the entry exists. A run already in progress is not interrupted.
+func (c *Cron) Pause(id EntryID) bool {
+	return c.setPaused(id, true)
+}
+
+// Resume lets a paused entry be run again from its next activation, and
+// reports whether the entry exists. Activations missed while paused are not
+// caught up. An entry disabled with WithDisableAfterFailures is enabled again,
+// with no failure counted.
+func (c *Cron) Resume(id EntryID) bool {
+	return c.setPaused(id, false)
+}
+
+func (c *Cron) setPaused(id EntryID, paused bool) bool {
+	found := false
+	c.apply(func() {
+		if e := c.entryByID(id); e != nil {
+			e.Paused = paused
+			if !paused && e.Disabled {
+				e.Disabled = false
+				e.state.resetFailures()
+			}
+			found = true
+		}
+	})
+	return found
+}
+
+// disable pauses the entry after its failures of the given run, and calls the
+// func set with WithDisableAfterFailures, unless it is already disabled.
+func (c *Cron) disable(e Entry, failures int, err error) {
+	disabled := false
+	c.apply(func() {
+		if entry := c.entryByID(e.ID); entry != nil && !entry.Disabled {
+			entry.Paused, entry.Disabled = true, true
+			disabled = true
+		}
+	})
+	if !disabled {
+		return
+	}
+	c.logEntry(slog.LevelWarn, &e, "cron: entry disabled after consecutive failures",
+		slog.Int("failures", failures), slog.Any("error", err))
+	c.event(EntryDisabled, &e, Event{Err: err})
+	if e.onDisable != nil {
+		e.onDisable(e.ID, err)
+	}
+}
+
+// NextN returns the next n activation times of the given entry, from now. It
+// returns fewer times if the schedule stops being satisfiable, and nil if
+// there is no such entry.
+func (c *Cron) NextN(id EntryID, n int) []time.Time {
+	var times []time.Time
+	c.apply(func() {
+		if e := c.entryByID(id); e != nil {
+			planned := *e
+			planned.Schedule = detached(e.Schedule)
+			times = nextN(planned.next, c.now(), n)
+		}
+	})
+	return times
+}
+
+// History returns the last runs of the given entry recorded with WithHistory,
+// oldest first. It returns nil if there is no such entry.
+func (c *Cron) History(id EntryID) []Execution {
+	var state *entryState
+	c.read(func() {
+		if e := c.entryByID(id); e != nil {
+			state = e.state
+		}
+	})
+	if state == nil {
+		return nil
+	}
+	return state.runs()
+}
+
+// UpdateSchedule replaces the schedule of the given entry with the one parsed
+// from spec, keeping its ID and its state. The next activation time is
+// recomputed from now. If spec is invalid the entry is left unchanged and the
+// parse error is returned; it returns ErrEntryNotFound if there is no such
+// entry.
+func (c *Cron) UpdateSchedule(id EntryID, spec string) error {
+	e := c.Entry(id)
+	key := e.Name
+	if key == "" {
+		key = e.Key
+	}
+	schedule, err := c.parse(spec, key)
+	if err != nil {
+		return err
+	}
+	return c.updateSchedule(id, schedule, spec)
+}
+
+// UpdateScheduleTo is like UpdateSchedule, but with a Schedule.
+func (c *Cron) UpdateScheduleTo(id EntryID, schedule Schedule) error {
+	return c.updateSchedule(id, schedule, "")
+}
+
+func (c *Cron) updateSchedule(id EntryID, schedule Schedule, spec string) error {
+	err := ErrEntryNotFound
+	c.apply(func() {
+		e := c.entryByID(id)
+		if e == nil {
+			return
+		}
+		e.Schedule = schedule
+		e.Spec = spec
+		if c.running {
+			e.Next = e.next(c.now())
+			heap.Fix(&c.entries, e.index)
+		}
+		err = nil
+	})
+	return err
+}
+
+// ResetBackoff resets the BackoffSchedule of the given entry to its initial
+// delay, and reschedules the entry from its last activation, or from now if
+// it has none, so that the initial delay is the next one. It returns false if
+// there is no such entry, or if its schedule is not a BackoffSchedule.
+func (c *Cron) ResetBackoff(id EntryID) bool {
+	found := false
+	c.apply(func() {
+		e := c.entryByID(id)
+		if e == nil {
+			return
+		}
+		schedule, ok := e.Schedule.(BackoffSchedule)
+		if !ok {
+			return
+		}
+		found = true
+		schedule.Reset()
+		if c.running && !e.Next.IsZero() {
+			from := e.Prev
+			if from.IsZero() {
+				from = c.now()
+			}
+			e.Next = e.next(from)
+			heap.Fix(&c.entries, e.index)
+		}
+	})
+	return found
+}
+
+// Remove an entry from being run in the future, and report whether it existed.
+// Removing an unknown or already removed entry does nothing. The contexts of
+// its runs in progress are cancelled.
+func (c *Cron) Remove(id EntryID) bool {
+	removed := false
+	c.apply(func() {
+		removed = c.cancelEntry(id)
+	})
+	return removed
+}
+
+// RemoveAll removes all the entries, and returns how many were removed. No
+// entry is run after it returns; the contexts of the runs already in progress
+// are cancelled, but plain jobs keep running.
+func (c *Cron) RemoveAll() int {
+	removed := 0
+	c.apply(func() {
+		removed = len(c.entries)
+		for _, e := range c.entries {
+			e.state.cancelRuns()
+			c.event(EntryRemoved, e, Event{})
+		}
+		c.setEntries(nil)
+	})
+	return removed
+}
+
+// Trigger is like RunNow, but leaves the entry's Prev time unchanged.
+//
+// Deprecated: use RunNow, which also records the run in Entry.Prev.
+func (c *Cron) Trigger(id EntryID) error {
+	return c.RunNow(id, runNowKeepPrev)
+}
+
+// RunNowOption modifies how RunNow runs an entry.
+type RunNowOption int
+
+const (
+	// RunNowSkipIfRunning skips the run if the entry is already running.
+	RunNowSkipIfRunning RunNowOption = 1 << iota
+
+	// runNowKeepPrev leaves the Prev time of the entry unchanged, for Trigger.
+	runNowKeepPrev
+)
+
+// RunNow runs the job of the given entry now, in its own goroutine, in the
+// same way as a scheduled run, whether or not the scheduler is running. The run
+// is recorded in the entry's Prev time, but its next activation time is left
+// unchanged.
+//
+// It returns ErrEntryNotFound if there is no such entry, and ErrSkipped if the
+// run was skipped because of RunNowSkipIfRunning.
+func (c *Cron) RunNow(id EntryID, opts ...RunNowOption) error {
+	return c.runNow(id, nil, opts)
+}
+
+// Run is a run of a job started with RunNowAsync.
+type Run struct {
+	done     chan struct{}
+	err      error
+	started  time.Time
+	duration time.Duration
+}
+
+// Done returns a channel closed once the run is over, whether it completed or
+// was skipped.
+func (r *Run) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns the error the run failed with once it is over: the one returned
+// by the job, a *PanicError if it panicked, an error matching ErrSkipped if it
+// was skipped, such as by a concurrency policy, or ErrEntryNotFound. It
+// returns nil until then.
+func (r *Run) Err() error {
+	select {
+	case <-r.done:
+		return r.err
+	default:
+		return nil
+	}
+}
+
+// Started returns the time the job started once the run is over, or the zero
+// time if it was skipped.
+func (r *Run) Started() time.Time {
+	select {
+	case <-r.done:
+		return r.started
+	default:
+		return time.Time{}
+	}
+}
+
+// Duration returns how long the job ran once the run is over.
+func (r *Run) Duration() time.Duration {
+	select {
+	case <-r.done:
+		return r.duration
+	default:
+		return 0
+	}
+}
+
+func (r *Run) finish(started time.Time, duration time.Duration, err error) {
+	r.started, r.duration, r.err = started, duration, err
+	close(r.done)
+}
+
+// RunNowAsync is like RunNow, but returns the run, to wait for it to be over
+// and get its outcome.
+func (c *Cron) RunNowAsync(id EntryID, opts ...RunNowOption) *Run {
+	run := &Run{done: make(chan struct{})}
+	if err := c.runNow(id, run, opts); err != nil {
+		run.finish(time.Time{}, 0, err)
+	}
+	return run
+}
+
+// RunNowWait is like RunNow, but waits for the run to be over and returns its
+// error, as returned by Run.Err. If ctx is done first it returns the error of
+// ctx; the job keeps running.
+func (c *Cron) RunNowWait(ctx context.Context, id EntryID, opts ...RunNowOption) error {
+	run := c.RunNowAsync(id, opts...)
+	select {
+	case <-run.Done():
+		return run.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runNow starts a run of the given entry now, finishing run, if not nil, once
+// it is over.
+func (c *Cron) runNow(id EntryID, run *Run, opts []RunNowOption) error {
+	var options RunNowOption
+	for _, opt := range opts {
+		options |= opt
+	}
+	err := ErrEntryNotFound
+	c.apply(func() {
+		e := c.entryByID(id)
+		if e == nil {
+			return
+		}
+		if options&RunNowSkipIfRunning > 0 && atomic.LoadInt32(&e.state.running) > 0 {
+			err = ErrSkipped
+			return
+		}
+		now := c.now()
+		if options&runNowKeepPrev == 0 {
+			e.Prev = now
+		}
+		snap := *e
+		snap.run = run
+		c.startJob(snap, now)
+		err = nil
+	})
+	return err
+}
+
+// Location gets the time zone location
+func (c *Cron) Location() *time.Location {
+	c.locMu.RLock()
+	defer c.locMu.RUnlock()
+	return c.location
+}
+
+// SetLocation changes the time zone location of the Cron, nil meaning UTC.
+// The entries without a location of their own, set with WithLocationFor or a
+// CRON_TZ line of a crontab, are evaluated in the new location from now on:
+// their next activation times are recomputed from now, and take effect right
+// away. The entries on a delay, such as with Every, are left unchanged.
+func (c *Cron) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	c.apply(func() {
+		c.locMu.Lock()
+		c.location = loc
+		c.locMu.Unlock()
+		now := c.now()
+		for _, e := range c.entries {
+			if !e.cronLocation {
+				continue
+			}
+			e.Location = loc
+			if c.running && !isDelaySchedule(e.Schedule) {
+				e.Next = e.next(now)
+			}
+		}
+		heap.Init(&c.entries)
+	})
+}
+
+// isDelaySchedule reports whether the activations of the schedule are
+// independent of the location they are evaluated in.
+func isDelaySchedule(s Schedule) bool {
+	switch s.(type) {
+	case ConstantDelaySchedule, PreciseDelaySchedule, RandomDelaySchedule, AfterCompletionSchedule, BackoffSchedule:
+		return true
+	}
+	return false
+}
+
+// Start the cron scheduler in its own go-routine, or no-op if already started.
+func (c *Cron) Start() {
+	if stop, ok := c.start(); ok {
+		go c.run(stop)
+	}
+}
+
+// IsRunning reports whether the cron scheduler is running.
+func (c *Cron) IsRunning() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.running
+}
+
+// Run the cron scheduler, or no-op if already running.
+func (c *Cron) Run() {
+	if stop, ok := c.start(); ok {
+		c.run(stop)
+	}
+}
+
+// start marks the scheduler as running and computes the next activation times
+// of the entries, unless it is already running. It returns the channel
+// stopping the run loop, and whether the scheduler was started.
+func (c *Cron) start() (chan struct{}, bool) {
+	// The state is loaded, and the missed runs computed, with the entries
+	// unlocked, and its failure reported once they are unlocked again.
+	lastRuns, loadErr := c.loadState()
+	now := c.now()
+	missed := c.missedRuns(lastRuns, now)
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.running = true
+	c.stop = make(chan struct{})
+	if lastRuns != nil {
+		c.lastRuns = lastRuns
+	}
+	for _, entry := range c.entries {
+		entry.Next = entry.next(now)
+		entry.awaitingRun = false
+		c.logEntry(slog.LevelDebug, entry, "cron: schedule", slog.Time("now", now), slog.Time("next", entry.Next))
+		c.runOnStart(entry, now)
+		c.runMissed(entry, now, missed)
+	}
+	heap.Init(&c.entries)
+	stop := c.stop
+	c.unlock()
+	if loadErr != nil {
+		c.handleError(Entry{}, loadErr)
+	}
+	c.event(SchedulerStarted, nil, Event{})
+	return stop, true
+}
+
+// errorJob is a Job whose runs can fail with an error, such as the jobs
+// returned by WithTimeout.
+type errorJob interface {
+	Job
+	runErr(ctx context.Context) error
+}
+
+// PanicError is the error of a run that panicked.
+type PanicError struct {
+	// The value the job panicked with.
+	Value interface{}
+
+	// The stack of the goroutine of the job when it panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("cron: panic running job: %v", e.Value)
+}
+
+// runWithRecovery runs the job with the given context, recovering from any
+// panic. The panic is reported on PanicCh and returned as an error, as is the
+// error of an errorJob.
+func (c *Cron) runWithRecovery(ctx context.Context, j Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			select {
+			case c.PanicCh <- fmt.Sprintf("cron: panic running job: %v\n%s", r, buf):
+			default:
+			}
+			err = &PanicError{Value: r, Stack: buf}
+		}
+	}()
+	return runJob(ctx, j)
+}
+
+// startJob runs the job of the given entry snapshot for the activation at
+// fireTime, in its own goroutine. It must be called with the entries locked:
+// the run counts as in progress right away, but only starts once they are
+// unlocked, so that neither a full pool nor the hooks of a skipped run hold
+// up the callers of the Cron, such as the jobs themselves.
+func (c *Cron) startJob(e Entry, fireTime time.Time) {
+	c.jobWaiter.Add(1)
+	atomic.AddInt32(&e.state.running, 1)
+	c.starts = append(c.starts, func() { c.launch(e, fireTime) })
+}
+
+// launch starts a run counted as in progress by startJob.
+func (c *Cron) launch(e Entry, fireTime time.Time) {
+	c.event(JobScheduled, &e, Event{Activation: fireTime})
+	if c.pool == nil {
+		go c.runEntry(e, fireTime, 0)
+		return
+	}
+	submitted := c.clock.Now()
+	if !c.pool.submit(func() { c.runEntry(e, fireTime, c.clock.Since(submitted)) }) {
+		c.logEntry(slog.LevelWarn, &e, "cron: skipped run: too many runs queued",
+			slog.String("reason", "queue full"), slog.Time("activation", fireTime))
+		e.state.record(Execution{Start: submitted, End: submitted, Skipped: true})
+		c.jobSkipped(e, fireTime, "queue full")
+		if e.run != nil {
+			e.run.finish(time.Time{}, 0, skipError("queue full"))
+		}
+		if e.counted {
+			c.settleRun(e.ID, true)
+		}
+		atomic.AddInt32(&e.state.running, -1)
+		c.runEnded(e)
+		c.jobWaiter.Done()
+	}
+}
+
+// runEntry runs the job of the given entry snapshot for the activation at
+// fireTime, provided the entry lock (if any) can be acquired.
+func (c *Cron) runEntry(e Entry, fireTime time.Time, queued time.Duration) {
+	defer c.jobWaiter.Done()
+	defer c.runEnded(e)
+	defer atomic.AddInt32(&e.state.running, -1)
+	var (
+		start  time.Time
+		dur    time.Duration
+		result error = ErrSkipped
+	)
+	if e.counted {
+		defer func() { c.settleRun(e.ID, errors.Is(result, ErrSkipped)) }()
+	}
+	if e.run != nil {
+		defer func() { e.run.finish(start, dur, result) }()
+	}
+	ctx, cancel := context.WithCancel(c.runContext())
+	defer cancel()
+	defer e.state.track(cancel)()
+	ctx = context.WithValue(ctx, entryKey{}, e)
+	ctx = context.WithValue(ctx, activationKey{}, fireTime)
+	ctx = context.WithValue(ctx, clockKey{}, c.clock)
+	if e.dependency != nil && !c.awaitDependency(ctx, e, fireTime) {
+		now := c.clock.Now()
+		e.state.record(Execution{Start: now, End: now, Skipped: true})
+		c.jobSkipped(e, fireTime, "dependency")
+		result = skipError("dependency")
+		return
+	}
+	if c.locker != nil {
+		release, acquired, err := c.locker.Acquire(ctx, e.ID, fireTime)
+		if err != nil {
+			c.logEntry(slog.LevelError, &e, "cron: failed to acquire lock",
+				slog.Time("activation", fireTime), slog.Any("error", err))
+		}
+		if err != nil || !acquired {
+			now := c.clock.Now()
+			e.state.record(Execution{Start: now, End: now, Skipped: true})
+			c.jobSkipped(e, fireTime, "lock")
+			result = skipError("lock")
+			return
+		}
+		defer release()
+	}
+	if c.distLock != nil {
+		key := c.distLock.activationKey(e, fireTime)
+		release, ok, err := c.distLock.locker.Acquire(ctx, key, c.distLock.ttl)
+		if err != nil {
+			c.handleError(e, fmt.Errorf("cron: failed to acquire lock %s: %v", key, err))
+		} else if !ok {
+			c.logEntry(slog.LevelInfo, &e, "cron: skipped run: lock held by another instance",
+				slog.String("reason", "lock"), slog.String("lock", key), slog.Time("activation", fireTime))
+		}
+		if err != nil || !ok {
+			now := c.clock.Now()
+			e.state.record(Execution{Start: now, End: now, Skipped: true})
+			c.jobSkipped(e, fireTime, "lock")
+			result = skipError("lock")
+			return
+		}
+		defer release()
+	}
+	done, admitted := c.admit(ctx, e, cancel)
+	if !admitted {
+		now := c.clock.Now()
+		e.state.record(Execution{Start: now, End: now, Skipped: true})
+		c.jobSkipped(e, fireTime, "still running")
+		result = skipError("still running")
+		return
+	}
+	defer done()
+	start = c.clock.Now()
+	e.state.started(start, queued)
+	c.jobStarted(e, fireTime, start)
+	c.logEntry(slog.LevelDebug, &e, "cron: run", slog.Time("activation", fireTime), slog.Time("start", start))
+	err := c.runWithRecovery(ctx, e.WrappedJob)
+	end := c.clock.Now()
+	result = err
+	if errors.Is(err, ErrSkipped) {
+		e.state.record(Execution{Start: start, End: end, Skipped: true})
+		if c.metrics != nil {
+			c.metrics.JobCompleted(e, end.Sub(start), err)
+		}
+		c.jobSkipped(e, fireTime, skipReason(err))
+		start = time.Time{}
+		return
+	}
+	dur = end.Sub(start)
+	failures := e.state.completed(start, end, err)
+	e.state.record(Execution{Start: start, End: end, Err: err})
+	if err != nil {
+		c.handleError(e, err)
+	}
+	c.saveState(e, fireTime)
+	c.jobCompleted(e, fireTime, end, end.Sub(start), err)
+	if err != nil && e.disableAfter > 0 && failures >= e.disableAfter {
+		c.disable(e, failures, err)
+	}
+}
+
+// admit reports whether a run of the entry can start according to its
+// concurrency policy, after cancelling the run in progress, if any, with
+// ReplaceConcurrent. It returns the func to call once the run is done.
+func (c *Cron) admit(ctx context.Context, e Entry, cancel context.CancelFunc) (done func(), admitted bool) {
+	s := e.state
+	s.mu.Lock()
+	switch e.ConcurrencyPolicy {
+	case ForbidConcurrent:
+		if s.active > 0 {
+			s.mu.Unlock()
+			return nil, false
+		}
+	case ReplaceConcurrent:
+		// Another run may have replaced the one waited for in the meantime.
+		for prev := s.current; prev != nil; prev = s.current {
+			s.stats.Replaced++
+			s.mu.Unlock()
+			prev.cancel()
+			c.waitReplaced(ctx, prev, e.replaceGrace)
+			s.mu.Lock()
+			if s.current == prev {
+				break
+			}
+		}
+	}
+	run := &activeRun{cancel: cancel, done: make(chan struct{})}
+	s.active++
+	s.current = run
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.active--
+		if s.current == run {
+			s.current = nil
+		}
+		close(run.done)
+	}, true
+}
+
+// awaitDependency waits for a run of the entry e depends on, started at or
+// after fireTime, to be done, for at most the max wait of e unless it is zero,
+// or until ctx is done. It reports whether e can run: if there was such a run,
+// and it succeeded unless e runs only after successful runs.
+func (c *Cron) awaitDependency(ctx context.Context, e Entry, fireTime time.Time) bool {
+	s := e.dependency
+	expired := false
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		var timeout <-chan time.Time
+		if e.dependWait > 0 {
+			timer := c.clock.NewTimer(e.dependWait)
+			defer timer.Stop()
+			timeout = timer.C()
+		}
+		select {
+		case <-timeout:
+		case <-ctx.Done():
+		case <-stop:
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		expired = true
+		s.cond().Broadcast()
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ran := func() bool { return !s.last.Start.IsZero() && !s.last.Start.Before(fireTime) }
+	for !ran() && !expired && !s.removed {
+		s.cond().Wait()
+	}
+	switch {
+	case !ran():
+		c.logEntry(slog.LevelInfo, &e, "cron: skipped run: dependency did not run",
+			slog.String("reason", "dependency"), slog.Int64("dependency", int64(e.dependsOn)), slog.Time("activation", fireTime))
+		return false
+	case e.onlyOnSuccess && (s.last.Err != nil || s.last.Skipped):
+		c.logEntry(slog.LevelInfo, &e, "cron: skipped run: dependency did not succeed",
+			slog.String("reason", "dependency"), slog.Int64("dependency", int64(e.dependsOn)), slog.Time("activation", fireTime))
+		return false
+	}
+	return true
+}
+
+// waitReplaced waits for the replaced run to be done, for at most grace unless
+// it is zero, or until ctx is done.
+func (c *Cron) waitReplaced(ctx context.Context, run *activeRun, grace time.Duration) {
+	var expired <-chan time.Time
+	if grace > 0 {
+		timer := c.clock.NewTimer(grace)
+		defer timer.Stop()
+		expired = timer.C()
+	}
+	select {
+	case <-run.done:
+	case <-expired:
+	case <-ctx.Done():
+	}
+}
+
+// handleError reports the failure of a run to the error handler, or logs it if
+// there is none. A panicking handler is recovered from.
+func (c *Cron) handleError(e Entry, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logEntry(slog.LevelError, &e, "cron: panic in error handler", slog.Any("panic", r))
+		}
+	}()
+	if c.onError != nil {
+		c.onError(e.ID, err)
+		return
+	}
+	var panicked *PanicError
+	if errors.As(err, &panicked) {
+		c.logEntry(slog.LevelError, &e, "cron: panic running job",
+			slog.Any("panic", panicked.Value), slog.String("stack", string(panicked.Stack)))
+		return
+	}
+	c.logEntry(slog.LevelError, &e, "cron: run failed", slog.Any("error", err))
+}
+
+// run runs the scheduler until the given channel is received from. The
+// entries are only locked while the loop updates them, so that they can be
+// read and changed without waiting for the loop.
+func (c *Cron) run(stop chan struct{}) {
+	for {
+		// Determine the next entry to run. If there are no entries yet, just
+		// sleep - it still handles new entries and stop requests.
+		c.mu.Lock()
+		d := 100000 * time.Hour
+		if len(c.entries) > 0 && !c.entries[0].Next.IsZero() {
+			d = c.entries[0].Next.Sub(c.now())
+		}
+		timer := c.clock.NewTimer(d)
+		c.timer = timer
+		c.mu.Unlock()
+
+		select {
+		case now := <-timer.C():
+			c.mu.Lock()
+			if c.running {
+				c.runDue(now.In(c.Location()))
+			}
+			c.unlock()
+
+		case <-c.wake:
+			timer.Stop()
+
+		case <-c.rearm:
+			timer.Stop()
+			c.mu.Lock()
+			c.rearmEntries()
+			c.mu.Unlock()
+
+		case <-stop:
+			c.mu.Lock()
+			timer.Stop()
+			c.timer = nil
+			c.mu.Unlock()
+			return
+		}
+	}
+}
+
+// runDue starts the runs of the entries due at now, and computes their next
+// activation times.
+func (c *Cron) runDue(now time.Time) {
+	c.logEntry(slog.LevelDebug, nil, "cron: wake", slog.Time("now", now))
+	// Run every entry whose next time was less than now
+	var completed []*Entry
+	for len(c.entries) > 0 {
+		e := c.entries[0]
+		if e.Next.After(now) || e.Next.IsZero() {
+			break
+		}
+		done, started := false, false
+		if !e.Paused && (!e.maxRuns || e.RemainingRuns > 0) {
+			for _, fireTime := range c.dueActivations(e, now) {
+				snap := *e
+				snap.counted = e.maxRuns
+				c.startJob(snap, fireTime)
+				e.Prev = fireTime
+				started = true
+				if e.maxRuns {
+					e.unsettled++
+					e.RemainingRuns--
+					if e.RemainingRuns == 0 {
+						break
+					}
+				}
+			}
+		}
+		e.Next = e.next(now)
+		if started {
+			c.awaitRun(e)
+		}
+		if e.ended() {
+			done = true
+		}
+		c.logEntry(slog.LevelDebug, e, "cron: schedule", slog.Time("now", now), slog.Time("next", e.Next))
+		if _, ok := e.Schedule.(OneShotSchedule); ok && e.Next.IsZero() {
+			done = true
+		}
+		if done {
+			completed = append(completed, e)
+		}
+		heap.Fix(&c.entries, 0)
+	}
+	for _, e := range completed {
+		c.removeEntry(e.ID)
+		if e.onComplete != nil {
+			go e.onComplete(e.ID)
+		}
+	}
+}
+
+// settleRun accounts for the outcome of a run counted towards WithMaxRuns: a
+// skipped run is given back, and the entry is removed once no run is left and
+// the outcomes of all its runs are known.
+func (c *Cron) settleRun(id EntryID, skipped bool) {
+	var completed *Entry
+	c.apply(func() {
+		e := c.entryByID(id)
+		if e == nil {
+			return
+		}
+		e.unsettled--
+		if skipped {
+			e.RemainingRuns++
+		}
+		if e.RemainingRuns == 0 && e.unsettled == 0 {
+			c.removeEntry(id)
+			completed = e
+		}
+	})
+	if completed != nil && completed.onComplete != nil {
+		go completed.onComplete(id)
+	}
+}
+
+// runOnStart runs the entry now if it was added with WithRunOnStart, or with
+// a RebootSchedule.
+func (c *Cron) runOnStart(e *Entry, now time.Time) {
+	if e.runOnStart && !e.Paused {
+		c.startJob(*e, now)
+		e.Prev = now
+		c.awaitRun(e)
+	}
+}
+
+// awaitRun makes an entry with an AfterCompletionSchedule that was just
+// started wait for its run to be over to get its next time.
+func (c *Cron) awaitRun(e *Entry) {
+	if _, ok := e.Schedule.(AfterCompletionSchedule); ok {
+		e.Next = time.Time{}
+		e.awaitingRun = true
+	}
+}
+
+// runEnded records that a run of the entry is over, and wakes up the run loop
+// to compute the next time of the entry if it has an AfterCompletionSchedule.
+func (c *Cron) runEnded(e Entry) {
+	if _, ok := e.Schedule.(AfterCompletionSchedule); !ok {
+		return
+	}
+	e.state.mu.Lock()
+	e.state.ended = c.clock.Now()
+	e.state.mu.Unlock()
+	select {
+	case c.rearm <- struct{}{}:
+	default:
+	}
+}
+
+// rearmEntries computes the next times of the entries waiting for their runs, from the
+// end of their last run, once they have no run in progress.
+func (c *Cron) rearmEntries() {
+	for _, e := range c.entries {
+		if !e.awaitingRun || atomic.LoadInt32(&e.state.running) > 0 {
+			continue
+		}
+		e.state.mu.Lock()
+		ended := e.state.ended
+		e.state.mu.Unlock()
+		e.awaitingRun = false
+		e.Next = e.next(ended.In(c.Location()))
+		c.logEntry(slog.LevelDebug, e, "cron: schedule", slog.Time("now", ended), slog.Time("next", e.Next))
+	}
+	heap.Init(&c.entries)
+	for _, e := range append([]*Entry(nil), c.entries...) {
+		if e.ended() {
+			c.removeEntry(e.ID)
+		}
+	}
+}
+
+// dueActivations returns the activation times to run the entry for at now,
+// starting with its next time. If activations were missed since then, they
+// are handled according to the missed run policy.
+func (c *Cron) dueActivations(e *Entry, now time.Time) []time.Time {
+	max := 2
+	if c.missed == RunAll {
+		max = c.missedMax
+	}
+	times := []time.Time{e.Next}
+	switch e.Schedule.(type) {
+	case AfterCompletionSchedule:
+		// The next activation is only known once the run is over.
+		return times
+	case BackoffSchedule:
+		// Looking for missed activations would grow the delay.
+		return times
+	}
+	for t := e.next(e.Next); max <= 0 || len(times) < max; t = e.next(t) {
+		if t.IsZero() || t.After(now) || !t.After(times[len(times)-1]) {
+			break
+		}
+		times = append(times, t)
+	}
+	if len(times) == 1 {
+		return times
+	}
+	switch c.missed {
+	case RunOnce:
+		return times[:1]
+	case RunAll:
+		return times
+	default:
+		return nil
+	}
+}
+
+// logf logs to the given logger, or to the standard logger if it is nil.
+func logf(logger *log.Logger, format string, args ...interface{}) {
+	if logger != nil {
+		logger.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// Stop stops the cron scheduler if it is running; otherwise it does nothing.
+// The contexts of the runs in progress are cancelled.
+func (c *Cron) Stop() {
+	if c.stopScheduler() {
+		c.cancelRunContexts()
+	}
+}
+
+// stopScheduler stops the run loop, leaving the runs in progress alone. It
+// reports whether the scheduler was running.
+func (c *Cron) stopScheduler() bool {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return false
+	}
+	c.running = false
+	stop := c.stop
+	c.mu.Unlock()
+	stop <- struct{}{}
+	c.event(SchedulerStopped, nil, Event{})
+	return true
+}
+
+// cancelRunContexts cancels the contexts of the runs in progress, but not the
+// ones of the runs started from now on.
+func (c *Cron) cancelRunContexts() {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	c.cancel()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+}
+
+// runContext returns the parent of the contexts of the runs starting.
+func (c *Cron) runContext() context.Context {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	return c.ctx
+}
+
+// Shutdown stops the cron scheduler, then waits for the jobs already running to
+// complete. If ctx is done first, Shutdown cancels the contexts of the runs in
+// progress and returns ctx.Err(), and the remaining jobs complete in the
+// background.
+func (c *Cron) Shutdown(ctx context.Context) error {
+	c.stopScheduler()
+	done := make(chan struct{})
+	go func() {
+		c.jobWaiter.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		c.cancelRunContexts()
+		return ctx.Err()
+	}
+}
+
+// StopError is the error of StopAndWait when runs are still in progress after
+// the timeout.
+type StopError struct {
+	// The timeout of StopAndWait.
+	Timeout time.Duration
+
+	// The entries whose runs were still in progress.
+	Running []Entry
+}
+
+func (e *StopError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cron: runs still in progress after %v:", e.Timeout)
+	for i, entry := range e.Running {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, " entry %d", entry.ID)
+		if entry.Name != "" {
+			fmt.Fprintf(&b, " (%s)", entry.Name)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns ErrTimeout.
+func (e *StopError) Unwrap() error {
+	return ErrTimeout
+}
+
+// StopAndWait stops the cron scheduler, then waits for at most timeout for the
+// runs in progress to complete, or as long as it takes if timeout is zero. If
+// runs are still in progress after the timeout, it cancels their contexts and
+// returns a *StopError listing their entries, without waiting for them to
+// end; only jobs implementing JobWithContext observe the cancellation. Unlike
+// Stop, it leaves the contexts of the runs alone while waiting. It can be
+// called again, or after Stop.
+func (c *Cron) StopAndWait(timeout time.Duration) error {
+	c.stopScheduler()
+	done := make(chan struct{})
+	go func() {
+		c.jobWaiter.Wait()
+		close(done)
+	}()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := c.clock.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-expired:
+	}
+	err := &StopError{Timeout: timeout}
+	for _, e := range c.Entries() {
+		if e.Running > 0 {
+			err.Running = append(err.Running, e)
+		}
+	}
+	c.cancelRunContexts()
+	return err
+}
+
+// entrySnapshot returns a copy of the current cron entry list, sorted by time.
 func (c *Cron) entrySnapshot() []Entry {
-	var entries = make([]Entry, len(c.entries))
-	for i, e := range c.entries {
+	sorted := append([]*Entry(nil), c.entries...)
+	sort.Sort(byTime(sorted))
+	return copyEntries(sorted)
+}
+
+// snapshotOf returns a snapshot of the entry returned by find, which is called
+// with the entries locked, and whether there is one.
+func (c *Cron) snapshotOf(find func() *Entry) (Entry, bool) {
+	var entry Entry
+	found := false
+	c.read(func() {
+		if e := find(); e != nil {
+			entry, found = copyEntries([]*Entry{e})[0], true
+		}
+	})
+	return entry, found
+}
+
+// copyEntries returns a copy of the given entries.
+func copyEntries(from []*Entry) []Entry {
+	var entries = make([]Entry, len(from))
+	for i, e := range from {
 		entries[i] = *e
+		entries[i].Tags = append([]string(nil), e.Tags...)
+		entries[i].Running = int(atomic.LoadInt32(&e.state.running))
+		entries[i].Active = entries[i].Running > 0
+		entries[i].Stats = e.state.snapshot()
 	}
 	return entries
 }
 
+// nextN returns the first n times returned by repeatedly calling next from the
+// given time, stopping early at the first zero or non-increasing time.
+func nextN(next func(time.Time) time.Time, from time.Time, n int) []time.Time {
+	times := []time.Time{}
+	for len(times) < n {
+		t := next(from)
+		if t.IsZero() || !t.After(from) {
+			break
+		}
+		times = append(times, t)
+		from = t
+	}
+	return times
+}
+
 // now returns current time in c location
 func (c *Cron) now() time.Time {
 	return c.clock.Now().In(c.Location())
 }
 
-func (c *Cron) removeEntry(id EntryID) {
-	var entries []*Entry
-	for _, e := range c.entries {
-		if e.ID != id {
-			entries = append(entries, e)
+// apply calls fn to operate on the entries with the entries locked, then wakes
+// up the run loop so that it picks up the changes. The timer of the loop is
+// stopped right away, so that it never fires for the entries as they were.
+// The runs started by fn start once the entries are unlocked.
+func (c *Cron) apply(fn func()) {
+	c.mu.Lock()
+	fn()
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	c.unlock()
+}
+
+// unlock unlocks the entries, then starts the runs started by startJob while
+// they were locked.
+func (c *Cron) unlock() {
+	starts := c.starts
+	c.starts = nil
+	c.mu.Unlock()
+	for _, start := range starts {
+		start()
+	}
+}
+
+// read calls fn to read the entries with the entries locked for reading.
+func (c *Cron) read(fn func()) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fn()
+}
+
+// entryByID returns the entry with the given ID, or nil.
+func (c *Cron) entryByID(id EntryID) *Entry {
+	return c.byID[id]
+}
+
+// entryByName returns the entry with the given name, or nil.
+func (c *Cron) entryByName(name string) *Entry {
+	return c.byName[name]
+}
+
+// entryByStringID returns the entry with the given string ID, or nil.
+func (c *Cron) entryByStringID(id string) *Entry {
+	return c.stringIDs[id]
+}
+
+// generatedID returns the string ID of an entry added without WithID.
+func generatedID(id EntryID) string {
+	return fmt.Sprintf("e-%d", id)
+}
+
+// isGeneratedID reports whether id is of the form of the generated ones.
+func isGeneratedID(id string) bool {
+	digits := strings.TrimPrefix(id, "e-")
+	if digits == id || digits == "" {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
 		}
 	}
+	return true
+}
+
+// removeEntry removes the entry with the given ID, and reports whether it
+// existed.
+func (c *Cron) removeEntry(id EntryID) bool {
+	e := c.byID[id]
+	if e == nil {
+		return false
+	}
+	heap.Remove(&c.entries, e.index)
+	delete(c.byID, id)
+	delete(c.byName, e.Name)
+	delete(c.stringIDs, e.StringID)
+	c.event(EntryRemoved, e, Event{})
+	return true
+}
+
+// cancelEntry removes the entry with the given ID, like removeEntry, and
+// cancels its runs in progress.
+func (c *Cron) cancelEntry(id EntryID) bool {
+	e := c.byID[id]
+	if e == nil {
+		return false
+	}
+	e.state.cancelRuns()
+	return c.removeEntry(id)
+}
+
+// setEntries replaces the entries with the given ones.
+func (c *Cron) setEntries(entries []*Entry) {
 	c.entries = entries
+	c.byID = make(map[EntryID]*Entry, len(entries))
+	c.byName = make(map[string]*Entry)
+	c.stringIDs = make(map[string]*Entry, len(entries))
+	for i, e := range entries {
+		e.index = i
+		c.index(e)
+	}
+	heap.Init(&c.entries)
+}
+
+// index adds the entry to the maps of the entries by ID, name and string ID.
+func (c *Cron) index(e *Entry) {
+	c.byID[e.ID] = e
+	if e.Name != "" {
+		c.byName[e.Name] = e
+	}
+	c.stringIDs[e.StringID] = e
 }
@@ -1,7 +1,14 @@
 package cron
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,7 +23,8 @@ const OneSecond = 1*time.Second + 10*time.Millisecond
 
 func TestFuncPanicRecovery(t *testing.T) {
 	clock := clockwork.NewFakeClock()
-	cron := New(clock)
+	// The clock jumps past several activations, which are run once.
+	cron := New(clock, WithMissedRunPolicy(RunOnce))
 	cron.Start()
 	defer cron.Stop()
 	nbCall := 0
@@ -33,7 +41,8 @@ func TestFuncPanicRecovery(t *testing.T) {
 
 func TestFuncPanicRecovery1(t *testing.T) {
 	clock := clockwork.NewFakeClock()
-	cron := New(clock)
+	// The clock jumps past several activations, which are run once.
+	cron := New(clock, WithMissedRunPolicy(RunOnce))
 	cron.Start()
 	defer cron.Stop()
 	nbCall := 0
@@ -48,393 +57,2325 @@ func TestFuncPanicRecovery1(t *testing.T) {
 	assert.Equal(t, 1, nbCall)
 }
 
-//type DummyJob struct{}
-//
-//func (d DummyJob) Run() {
-//	panic("YOLO")
-//}
-//
-//func TestJobPanicRecovery(t *testing.T) {
-//	var job DummyJob
-//
-//	cron := New()
-//	cron.Start()
-//	defer cron.Stop()
-//	cron.AddJob("* * * * * ?", job)
-//
-//	select {
-//	case <-time.After(OneSecond):
-//		return
-//	}
-//}
-//
-//// Start and stop cron with no entries.
-//func TestNoEntries(t *testing.T) {
-//	cron := New()
-//	cron.Start()
-//
-//	select {
-//	case <-time.After(OneSecond):
-//		t.Fatal("expected cron will be stopped immediately")
-//	case <-stop(cron):
-//	}
-//}
-//
-//// Start, stop, then add an entry. Verify entry doesn't run.
-//func TestStopCausesJobsToNotRun(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(1)
-//
-//	cron := New()
-//	cron.Start()
-//	cron.Stop()
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//
-//	select {
-//	case <-time.After(OneSecond):
-//		// No job ran!
-//	case <-wait(wg):
-//		t.Fatal("expected stopped cron does not run any job")
-//	}
-//}
-//
-//// Add a job, start cron, expect it runs.
-//func TestAddBeforeRunning(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(1)
-//
-//	cron := New()
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	// Give cron 2 seconds to run our job (which is always activated).
-//	select {
-//	case <-time.After(OneSecond):
-//		t.Fatal("expected job runs")
-//	case <-wait(wg):
-//	}
-//}
-//
-//// Start cron, add a job, expect it runs.
-//func TestAddWhileRunning(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(1)
-//
-//	cron := New()
-//	cron.Start()
-//	defer cron.Stop()
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//
-//	select {
-//	case <-time.After(OneSecond):
-//		t.Fatal("expected job runs")
-//	case <-wait(wg):
-//	}
-//}
-//
-//// Test for #34. Adding a job after calling start results in multiple job invocations
-//func TestAddWhileRunningWithDelay(t *testing.T) {
-//	cron := New()
-//	cron.Start()
-//	defer cron.Stop()
-//	time.Sleep(5 * time.Second)
-//	var calls = 0
-//	cron.AddFunc("* * * * * *", func() { calls += 1 })
-//
-//	<-time.After(OneSecond)
-//	if calls != 1 {
-//		t.Errorf("called %d times, expected 1\n", calls)
-//	}
-//}
-//
-//// Test timing with Entries.
-//func TestSnapshotEntries(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(1)
-//
-//	cron := New()
-//	cron.AddFunc("@every 2s", func() { wg.Done() })
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	// Cron should fire in 2 seconds. After 1 second, call Entries.
-//	select {
-//	case <-time.After(OneSecond):
-//		cron.Entries()
-//	}
-//
-//	// Even though Entries was called, the cron should fire at the 2 second mark.
-//	select {
-//	case <-time.After(OneSecond):
-//		t.Error("expected job runs at 2 second mark")
-//	case <-wait(wg):
-//	}
-//
-//}
-//
-//// Test that the entries are correctly sorted.
-//// Add a bunch of long-in-the-future entries, and an immediate entry, and ensure
-//// that the immediate entry runs immediately.
-//// Also: Test that multiple jobs run in the same instant.
-//func TestMultipleEntries(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(2)
-//
-//	cron := New()
-//	cron.AddFunc("0 0 0 1 1 ?", func() {})
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//	cron.AddFunc("0 0 0 31 12 ?", func() {})
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	select {
-//	case <-time.After(OneSecond):
-//		t.Error("expected job run in proper order")
-//	case <-wait(wg):
-//	}
-//}
-//
-//// Test running the same job twice.
-//func TestRunningJobTwice(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(2)
-//
-//	cron := New()
-//	cron.AddFunc("0 0 0 1 1 ?", func() {})
-//	cron.AddFunc("0 0 0 31 12 ?", func() {})
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	select {
-//	case <-time.After(2 * OneSecond):
-//		t.Error("expected job fires 2 times")
-//	case <-wait(wg):
-//	}
-//}
-//
-//func TestRunningMultipleSchedules(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(2)
-//
-//	cron := New()
-//	cron.AddFunc("0 0 0 1 1 ?", func() {})
-//	cron.AddFunc("0 0 0 31 12 ?", func() {})
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//	cron.Schedule(Every(time.Minute), FuncJob(func() {}))
-//	cron.Schedule(Every(time.Second), FuncJob(func() { wg.Done() }))
-//	cron.Schedule(Every(time.Hour), FuncJob(func() {}))
-//
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	select {
-//	case <-time.After(2 * OneSecond):
-//		t.Error("expected job fires 2 times")
-//	case <-wait(wg):
-//	}
-//}
-//
-//// Test that the cron is run in the local time zone (as opposed to UTC).
-//func TestLocalTimezone(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(2)
-//
-//	now := time.Now()
-//	spec := fmt.Sprintf("%d,%d %d %d %d %d ?",
-//		now.Second()+1, now.Second()+2, now.Minute(), now.Hour(), now.Day(), now.Month())
-//
-//	cron := New()
-//	cron.AddFunc(spec, func() { wg.Done() })
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	select {
-//	case <-time.After(OneSecond * 2):
-//		t.Error("expected job fires 2 times")
-//	case <-wait(wg):
-//	}
-//}
-//
-//// Test that the cron is run in the given time zone (as opposed to local).
-//func TestNonLocalTimezone(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(2)
-//
-//	loc, err := time.LoadLocation("Atlantic/Cape_Verde")
-//	if err != nil {
-//		fmt.Printf("Failed to load time zone Atlantic/Cape_Verde: %+v", err)
-//		t.Fail()
-//	}
-//
-//	now := time.Now().In(loc)
-//	spec := fmt.Sprintf("%d,%d %d %d %d %d ?",
-//		now.Second()+1, now.Second()+2, now.Minute(), now.Hour(), now.Day(), now.Month())
-//
-//	cron := NewWithLocation(loc)
-//	cron.AddFunc(spec, func() { wg.Done() })
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	select {
-//	case <-time.After(OneSecond * 2):
-//		t.Error("expected job fires 2 times")
-//	case <-wait(wg):
-//	}
-//}
-//
-//// Test that calling stop before start silently returns without
-//// blocking the stop channel.
-//func TestStopWithoutStart(t *testing.T) {
-//	cron := New()
-//	cron.Stop()
-//}
-//
-//type testJob struct {
-//	wg   *sync.WaitGroup
-//	name string
-//}
-//
-//func (t testJob) Run() {
-//	t.wg.Done()
-//}
-//
-//// Test that adding an invalid job spec returns an error
-//func TestInvalidJobSpec(t *testing.T) {
-//	cron := New()
-//	err := cron.AddJob("this will not parse", nil)
-//	if err == nil {
-//		t.Errorf("expected an error with invalid spec, got nil")
-//	}
-//}
-//
-//// Test blocking run method behaves as Start()
-//func TestBlockingRun(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(1)
-//
-//	cron := New()
-//	cron.AddFunc("* * * * * ?", func() { wg.Done() })
-//
-//	var unblockChan = make(chan struct{})
-//
-//	go func() {
-//		cron.Run()
-//		close(unblockChan)
-//	}()
-//	defer cron.Stop()
-//
-//	select {
-//	case <-time.After(OneSecond):
-//		t.Error("expected job fires")
-//	case <-unblockChan:
-//		t.Error("expected that Run() blocks")
-//	case <-wait(wg):
-//	}
-//}
-//
-//// Test that double-running is a no-op
-//func TestStartNoop(t *testing.T) {
-//	var tickChan = make(chan struct{}, 2)
-//
-//	cron := New()
-//	cron.AddFunc("* * * * * ?", func() {
-//		tickChan <- struct{}{}
-//	})
-//
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	// Wait for the first firing to ensure the runner is going
-//	<-tickChan
-//
-//	cron.Start()
-//
-//	<-tickChan
-//
-//	// Fail if this job fires again in a short period, indicating a double-run
-//	select {
-//	case <-time.After(time.Millisecond):
-//	case <-tickChan:
-//		t.Error("expected job fires exactly twice")
-//	}
-//}
-//
-//// Simple test using Runnables.
-//func TestJob(t *testing.T) {
-//	wg := &sync.WaitGroup{}
-//	wg.Add(1)
-//
-//	cron := New()
-//	cron.AddJob("0 0 0 30 Feb ?", testJob{wg, "job0"})
-//	cron.AddJob("0 0 0 1 1 ?", testJob{wg, "job1"})
-//	cron.AddJob("* * * * * ?", testJob{wg, "job2"})
-//	cron.AddJob("1 0 0 1 1 ?", testJob{wg, "job3"})
-//	cron.Schedule(Every(5*time.Second+5*time.Nanosecond), testJob{wg, "job4"})
-//	cron.Schedule(Every(5*time.Minute), testJob{wg, "job5"})
-//
-//	cron.Start()
-//	defer cron.Stop()
-//
-//	select {
-//	case <-time.After(OneSecond):
-//		t.FailNow()
-//	case <-wait(wg):
-//	}
-//
-//	// Ensure the entries are in the right order.
-//	expecteds := []string{"job2", "job4", "job5", "job1", "job3", "job0"}
-//
-//	var actuals []string
-//	for _, entry := range cron.Entries() {
-//		actuals = append(actuals, entry.Job.(testJob).name)
-//	}
-//
-//	for i, expected := range expecteds {
-//		if actuals[i] != expected {
-//			t.Fatalf("Jobs not in the right order.  (expected) %s != %s (actual)", expecteds, actuals)
-//		}
-//	}
-//}
-//
-//type ZeroSchedule struct{}
-//
-//func (*ZeroSchedule) Next(time.Time) time.Time {
-//	return time.Time{}
-//}
-//
-//// Tests that job without time does not run
-//func TestJobWithZeroTimeDoesNotRun(t *testing.T) {
-//	cron := New()
-//	calls := 0
-//	cron.AddFunc("* * * * * *", func() { calls += 1 })
-//	cron.Schedule(new(ZeroSchedule), FuncJob(func() { t.Error("expected zero task will not run") }))
-//	cron.Start()
-//	defer cron.Stop()
-//	<-time.After(OneSecond)
-//	if calls != 1 {
-//		t.Errorf("called %d times, expected 1\n", calls)
-//	}
-//}
-//
-//func wait(wg *sync.WaitGroup) chan bool {
-//	ch := make(chan bool)
-//	go func() {
-//		wg.Wait()
-//		ch <- true
-//	}()
-//	return ch
-//}
-//
-//func stop(cron *Cron) chan bool {
-//	ch := make(chan bool)
-//	go func() {
-//		cron.Stop()
-//		ch <- true
-//	}()
-//	return ch
-//}
+func TestEntryIDsAreNotReused(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	id1, _ := cron.AddFunc("* * * * * ?", func() {})
+	id2, _ := cron.AddFunc("* * * * * ?", func() {})
+	assert.True(t, cron.Remove(id2))
+	assert.False(t, cron.Remove(id2))
+	assert.False(t, cron.Remove(EntryID(42)))
+	assert.Equal(t, Entry{}, cron.Entry(id2))
+	id3, _ := cron.AddFunc("* * * * * ?", func() {})
+	assert.True(t, id3 > id2 && id2 > id1)
+	assert.Len(t, cron.Entries(), 2)
+}
+
+func TestTrigger(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	ran := make(chan struct{}, 1)
+	id, _ := cron.AddFunc("@daily", func() { ran <- struct{}{} })
+	assert.Equal(t, ErrEntryNotFound, cron.Trigger(id+1))
+
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	next := cron.Entry(id).Next
+	assert.NoError(t, cron.Trigger(id))
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected triggered job to run")
+	}
+	assert.Equal(t, next, cron.Entry(id).Next)
+	assert.True(t, cron.Entry(id).Prev.IsZero())
+}
+
+func TestErrorHandler(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	errs := make(chan error, 1)
+	var failedID EntryID
+	cron := New(clock, WithErrorHandler(func(id EntryID, err error) {
+		failedID = id
+		errs <- err
+		panic("handler panics too")
+	}))
+	id, _ := cron.AddFunc("* * * * * ?", func() { panic("YOLO") })
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "YOLO")
+		assert.Equal(t, id, failedID)
+	case <-time.After(OneSecond):
+		t.Fatal("expected error handler to be called")
+	}
+}
+
+func TestErrorJob(t *testing.T) {
+	var mu sync.Mutex
+	errs := map[EntryID][]error{}
+	cron := New(clockwork.NewFakeClock(), WithErrorHandler(func(id EntryID, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs[id] = append(errs[id], err)
+	}))
+	failure := errors.New("failure")
+	failing, _ := cron.AddFuncE("@every 1h", func() error { return failure })
+	ok, _ := cron.AddFuncE("@every 1h", func() error { return nil })
+	panicking, _ := cron.AddFuncE("@every 1h", func() error { panic("YOLO") })
+	for _, id := range []EntryID{failing, failing, ok, panicking} {
+		assert.NoError(t, cron.RunNow(id))
+		assert.NoError(t, cron.Shutdown(context.Background()))
+	}
+
+	assert.Equal(t, []error{failure, failure}, errs[failing])
+	assert.Equal(t, 2, cron.Entry(failing).Stats.ConsecutiveFailures)
+	assert.Equal(t, 0, len(errs[ok]))
+	assert.Equal(t, uint64(1), cron.Entry(ok).Stats.Runs)
+	assert.Equal(t, 1, len(errs[panicking]))
+	perr, isPanic := errs[panicking][0].(*PanicError)
+	assert.True(t, isPanic, "expected a PanicError")
+	assert.Equal(t, "YOLO", perr.Value)
+	assert.Contains(t, perr.Error(), "YOLO")
+	assert.Contains(t, string(perr.Stack), "TestErrorJob")
+
+	// Without a handler, failures are logged.
+	var buf syncBuffer
+	cron = New(clockwork.NewFakeClock(), WithErrorHandler(nil))
+	cron.ErrorLog = log.New(&buf, "", 0)
+	id, _ := cron.AddFuncE("@every 1h", func() error { return failure })
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Contains(t, buf.String(), "failure")
+}
+
+func TestConcurrencyPolicy(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	started := make(chan int, 10)
+	release := make(chan struct{})
+	var n int32
+	blocking := func(ctx context.Context) {
+		started <- int(atomic.AddInt32(&n, 1))
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+	}
+
+	// Allow overlaps the runs.
+	allow, _ := cron.AddFuncCtx("@every 1h", blocking)
+	assert.Equal(t, AllowConcurrent, cron.Entry(allow).ConcurrencyPolicy)
+	assert.NoError(t, cron.RunNow(allow))
+	assert.NoError(t, cron.RunNow(allow))
+	<-started
+	<-started
+	assert.Equal(t, 2, cron.Entry(allow).Running)
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+
+	// Forbid skips the runs while one is in progress.
+	release = make(chan struct{})
+	forbid, _ := cron.AddFuncCtx("@every 1h", blocking, WithConcurrencyPolicy(ForbidConcurrent), WithHistory(2))
+	assert.Equal(t, ForbidConcurrent, cron.Entry(forbid).ConcurrencyPolicy)
+	assert.NoError(t, cron.RunNow(forbid))
+	<-started
+	assert.NoError(t, cron.RunNow(forbid))
+	for cron.Entry(forbid).Stats.Skipped == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, uint64(1), cron.Entry(forbid).Stats.Runs)
+	assert.True(t, cron.History(forbid)[0].Skipped, "expected the second run to be recorded as skipped")
+
+	// Replace cancels the run in progress, then starts the new one.
+	release = make(chan struct{})
+	cancelled := make(chan int, 1)
+	replace, _ := cron.AddFuncCtx("@every 1h", func(ctx context.Context) {
+		i := int(atomic.AddInt32(&n, 1))
+		started <- i
+		select {
+		case <-release:
+		case <-ctx.Done():
+			cancelled <- i
+		}
+	}, WithConcurrencyPolicy(ReplaceConcurrent))
+	assert.NoError(t, cron.RunNow(replace))
+	first := <-started
+	assert.NoError(t, cron.RunNow(replace))
+	assert.Equal(t, first, <-cancelled)
+	assert.Equal(t, first+1, <-started)
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, uint64(1), cron.Entry(replace).Stats.Replaced)
+
+	// A run not observing the cancellation is replaced after the grace period.
+	release = make(chan struct{})
+	stubborn, _ := cron.AddFunc("@every 1h", func() {
+		started <- int(atomic.AddInt32(&n, 1))
+		<-release
+	}, WithConcurrencyPolicy(ReplaceConcurrent), WithReplaceGracePeriod(time.Minute))
+	assert.NoError(t, cron.RunNow(stubborn))
+	first = <-started
+	assert.NoError(t, cron.RunNow(stubborn))
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, first+1, <-started)
+	assert.Equal(t, 2, cron.Entry(stubborn).Running)
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+}
+
+func TestRunAfter(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithErrorHandler(func(EntryID, error) {}))
+	var mu sync.Mutex
+	var order []string
+	release := make(chan struct{})
+	extract, _ := cron.AddFuncE("@every 1h", func() error {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "extract")
+		return nil
+	})
+	transform, err := cron.AddFunc("@every 1h", func() {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "transform")
+	}, WithRunAfter(extract, true))
+	assert.NoError(t, err)
+
+	// Transform waits for extract, whichever is started first.
+	assert.NoError(t, cron.RunNow(transform))
+	assert.NoError(t, cron.RunNow(extract))
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 0, len(order))
+	mu.Unlock()
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, []string{"extract", "transform"}, order)
+
+	// Transform is skipped if extract fails, but not a job depending on any
+	// run of it.
+	failing, _ := cron.AddFuncE("@every 1h", func() error { return errors.New("no data") })
+	calls := 0
+	onSuccess, _ := cron.AddFunc("@every 1h", func() { calls++ }, WithRunAfter(failing, true), WithHistory(1))
+	anyway, _ := cron.AddFunc("@every 1h", func() { calls++ }, WithRunAfter(failing, false))
+	assert.NoError(t, cron.RunNow(onSuccess))
+	assert.NoError(t, cron.RunNow(anyway))
+	assert.NoError(t, cron.RunNow(failing))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, 1, calls)
+	assert.True(t, cron.History(onSuccess)[0].Skipped, "expected the run to be skipped")
+
+	// Runs are skipped after the max wait, or if the entry waited for is
+	// removed.
+	clock.Advance(time.Hour)
+	waiting, _ := cron.AddFunc("@every 1h", func() { calls++ }, WithRunAfter(extract, false), WithRunAfterMaxWait(time.Minute))
+	assert.NoError(t, cron.RunNow(waiting))
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	waiting, _ = cron.AddFunc("@every 1h", func() { calls++ }, WithRunAfter(failing, false))
+	assert.NoError(t, cron.RunNow(waiting))
+	cron.Remove(failing)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, uint64(1), cron.Entry(waiting).Stats.Skipped)
+}
+
+func TestRunAfterErrors(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	a, _ := cron.AddFunc("@every 1h", func() {})
+	_, err := cron.AddFunc("@every 1h", func() {}, WithRunAfter(a+10, false))
+	assert.Equal(t, ErrEntryNotFound, err)
+
+	// Entry IDs are assigned in order, so the next one can depend on itself.
+	_, err = cron.AddFunc("@every 1h", func() {}, WithRunAfter(a+2, false))
+	assert.Equal(t, ErrDependencyCycle, err)
+	assert.Equal(t, 1, len(cron.Entries()))
+}
+
+func TestStopAndWait(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var calls int32
+	cron.AddFunc("@every 1m", func() { atomic.AddInt32(&calls, 1) })
+	cron.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, cron.StopAndWait(time.Hour))
+	assert.False(t, cron.IsRunning())
+
+	// Again, and after Stop.
+	assert.NoError(t, cron.StopAndWait(0))
+	cron.Stop()
+	assert.NoError(t, cron.StopAndWait(time.Second))
+}
+
+func TestStopAndWaitTimeout(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	defer close(release)
+	hung, _ := cron.AddFunc("@every 1h", func() {
+		started <- struct{}{}
+		<-release
+	}, WithName("hung"))
+	cancelled := make(chan struct{})
+	polite, _ := cron.AddFuncCtx("@every 1h", func(ctx context.Context) {
+		started <- struct{}{}
+		<-ctx.Done()
+		close(cancelled)
+	})
+	cron.AddFunc("@every 1h", func() {})
+	assert.NoError(t, cron.RunNow(hung))
+	assert.NoError(t, cron.RunNow(polite))
+	<-started
+	<-started
+
+	errs := make(chan error)
+	go func() { errs <- cron.StopAndWait(time.Minute) }()
+
+	// The runs are not cancelled before the timeout.
+	clock.BlockUntil(1)
+	select {
+	case <-cancelled:
+		t.Fatal("expected the run not to be cancelled before the timeout")
+	default:
+	}
+	clock.Advance(time.Minute)
+	err := <-errs
+	<-cancelled
+
+	stopErr, ok := err.(*StopError)
+	assert.True(t, ok, "expected a StopError")
+	assert.True(t, errors.Is(err, ErrTimeout), "expected a timeout")
+	assert.Equal(t, 2, len(stopErr.Running))
+	assert.Equal(t, "cron: runs still in progress after 1m0s: entry 1 (hung), entry 2", err.Error())
+}
+
+func TestStopAndWaitConcurrentChanges(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	hung, _ := cron.AddFunc("@every 1h", func() {
+		close(started)
+		<-release
+	})
+	assert.NoError(t, cron.RunNow(hung))
+	<-started
+
+	done := make(chan struct{})
+	changing := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			id, _ := cron.AddFunc("@every 1s", func() {})
+			cron.Remove(id)
+			if i == 0 {
+				close(changing)
+			}
+		}
+	}()
+	<-changing
+	errs := make(chan error)
+	go func() { errs <- cron.StopAndWait(time.Minute) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	err := <-errs
+	close(done)
+	wg.Wait()
+	assert.True(t, errors.Is(err, ErrTimeout), "expected a timeout")
+	assert.Equal(t, 1, len(err.(*StopError).Running))
+}
+
+func TestShutdown(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	started, release := make(chan struct{}), make(chan struct{})
+	cron.AddFunc("* * * * * ?", func() {
+		close(started)
+		<-release
+	})
+	cron.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, cron.Shutdown(ctx))
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+}
+
+func TestShutdownDrainsContextJobs(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	started, release := make(chan struct{}), make(chan struct{})
+	cancelled := make(chan bool, 1)
+	id := cron.Schedule(Every(time.Hour), FuncJobCtx(func(ctx context.Context) {
+		close(started)
+		select {
+		case <-release:
+			cancelled <- false
+		case <-ctx.Done():
+			cancelled <- true
+		}
+	}))
+	cron.Start()
+	assert.NoError(t, cron.RunNow(id))
+	<-started
+
+	// The run is left to complete until the context of Shutdown is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- cron.Shutdown(ctx) }()
+	select {
+	case <-cancelled:
+		t.Fatal("expected Shutdown not to cancel the run right away")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-shutdown)
+	assert.True(t, <-cancelled)
+}
+
+func TestNamedEntries(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	_, err := cron.AddFunc("@daily", func() {}, WithName("daily"))
+	assert.NoError(t, err)
+	cron.AddFunc("@hourly", func() {})
+	cron.Start()
+	defer cron.Stop()
+
+	ran := make(chan struct{}, 1)
+	id, err := cron.AddFunc("* * * * * ?", func() { ran <- struct{}{} }, WithName("secondly"))
+	assert.NoError(t, err)
+	entry, ok := cron.EntryByName("secondly")
+	assert.True(t, ok)
+	assert.Equal(t, id, entry.ID)
+	assert.Equal(t, "secondly", entry.Name)
+
+	_, err = cron.AddFunc("@weekly", func() {}, WithName("daily"))
+	assert.Equal(t, ErrDuplicateName, err)
+	assert.Len(t, cron.Entries(), 3)
+
+	assert.True(t, cron.RemoveByName("daily"))
+	assert.False(t, cron.RemoveByName("daily"))
+	_, ok = cron.EntryByName("daily")
+	assert.False(t, ok)
+	assert.Len(t, cron.Entries(), 2)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected named job added while running to run")
+	}
+}
+
+func TestStringIDs(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	cron.Start()
+	defer cron.Stop()
+
+	id, err := cron.AddFunc("@daily", func() {}, WithID("backup"))
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		cron.AddFunc("@hourly", func() {})
+	}
+	entry, ok := cron.EntryByStringID("backup")
+	assert.True(t, ok)
+	assert.Equal(t, id, entry.ID)
+	entry, ok = cron.EntryByStringID("e-2")
+	assert.True(t, ok)
+	assert.Equal(t, EntryID(2), entry.ID)
+
+	// Supplied IDs collide neither with other supplied IDs nor generated ones.
+	_, err = cron.AddFunc("@weekly", func() {}, WithID("backup"))
+	assert.Equal(t, ErrDuplicateID, err)
+	_, err = cron.AddFunc("@weekly", func() {}, WithID("e-9"))
+	assert.Equal(t, ErrReservedID, err)
+	id, err = cron.AddFunc("@weekly", func() {}, WithID("e-x"))
+	assert.NoError(t, err)
+	assert.Equal(t, "e-x", cron.Entry(id).StringID)
+	seen := make(map[string]bool)
+	for _, e := range cron.Entries() {
+		assert.False(t, seen[e.StringID], e.StringID)
+		seen[e.StringID] = true
+	}
+	assert.Len(t, seen, 5)
+
+	assert.True(t, cron.RemoveByStringID("backup"))
+	assert.False(t, cron.RemoveByStringID("backup"))
+	_, ok = cron.EntryByStringID("backup")
+	assert.False(t, ok)
+	assert.Len(t, cron.Entries(), 4)
+
+	// The ID is free again once its entry is removed.
+	_, err = cron.AddFunc("@daily", func() {}, WithID("backup"), WithName("backup"))
+	assert.NoError(t, err)
+
+	// And once all the entries are.
+	cron.RemoveAll()
+	_, ok = cron.EntryByStringID("backup")
+	assert.False(t, ok)
+	_, ok = cron.EntryByName("backup")
+	assert.False(t, ok)
+	_, err = cron.AddFunc("@daily", func() {}, WithID("backup"), WithName("backup"))
+	assert.NoError(t, err)
+}
+
+func TestTaggedEntries(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("@daily", func() {}, WithTags("tenant:acme", "type:report"))
+	cron.AddFunc("@daily", func() {}, WithTags("tenant:acme"))
+	cron.AddFunc("@daily", func() {}, WithTags("tenant:other", "type:report"))
+
+	assert.Len(t, cron.EntriesByTag("tenant:acme"), 2)
+	assert.Len(t, cron.EntriesByTag("type:report"), 2)
+	assert.Len(t, cron.EntriesByTags("tenant:acme", "type:report"), 1)
+	assert.Len(t, cron.EntriesByTag("tenant"), 0)
+
+	// Snapshots don't share their tags with the scheduler.
+	cron.EntriesByTag("tenant:other")[0].Tags[0] = "tenant:acme"
+	assert.Len(t, cron.EntriesByTag("tenant:acme"), 2)
+
+	cron.Start()
+	defer cron.Stop()
+	ran := make(chan struct{}, 1000)
+	for i := 0; i < 1000; i++ {
+		cron.AddFunc("* * * * * ?", func() { ran <- struct{}{} }, WithTags("bulk"))
+	}
+	assert.Equal(t, 1000, cron.RemoveByTag("bulk"))
+	assert.Equal(t, 2, cron.RemoveByTag("tenant:acme"))
+	assert.Len(t, cron.Entries(), 1)
+
+	clock.Advance(time.Second)
+	select {
+	case <-ran:
+		t.Fatal("expected removed entries not to run")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	ran := make(chan time.Time, 10)
+	id, _ := cron.AddFunc("@every 1s", func() { ran <- clock.Now() })
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+
+	assert.True(t, cron.Pause(id))
+	assert.True(t, cron.Entry(id).Paused)
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+	clock.BlockUntil(1)
+	assert.True(t, cron.Entry(id).Prev.IsZero())
+	assert.Len(t, ran, 0)
+
+	assert.True(t, cron.Resume(id))
+	assert.False(t, cron.Entry(id).Paused)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected resumed job to run")
+	}
+	assert.Len(t, ran, 0)
+	assert.False(t, cron.Pause(id+1))
+	assert.False(t, cron.Resume(id+1))
+}
+
+func TestDisableAfterFailures(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithErrorHandler(func(EntryID, error) {}))
+	events, unsubscribe := cron.Subscribe(50)
+	defer unsubscribe()
+	var failing int32 = 1
+	ran := make(chan struct{}, 10)
+	disabled := make(chan error, 10)
+	id, _ := cron.AddFuncE("@every 1m", func() error {
+		defer func() { ran <- struct{}{} }()
+		if atomic.LoadInt32(&failing) == 0 {
+			return nil
+		}
+		panic("boom")
+	}, WithDisableAfterFailures(3, func(id EntryID, err error) { disabled <- err }))
+	cron.Start()
+	defer cron.Stop()
+
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		<-ran
+	}
+	var panicked *PanicError
+	assert.True(t, errors.As(<-disabled, &panicked))
+	entry := cron.Entry(id)
+	assert.True(t, entry.Paused)
+	assert.True(t, entry.Disabled)
+	assert.Equal(t, 3, entry.Stats.ConsecutiveFailures)
+
+	// The fourth activation is not run.
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	assert.Len(t, ran, 0)
+
+	// Once resumed, a success clears the streak, and the func is only
+	// called again after three more failures.
+	assert.True(t, cron.Resume(id))
+	entry = cron.Entry(id)
+	assert.False(t, entry.Disabled)
+	assert.Equal(t, 0, entry.Stats.ConsecutiveFailures)
+	atomic.StoreInt32(&failing, 0)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-ran
+	atomic.StoreInt32(&failing, 1)
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		<-ran
+	}
+	cron.jobWaiter.Wait()
+	assert.False(t, cron.Entry(id).Disabled)
+	assert.Equal(t, 2, cron.Entry(id).Stats.ConsecutiveFailures)
+	assert.Len(t, disabled, 0)
+
+	var types []EventType
+	for len(events) > 0 {
+		ev := <-events
+		if ev.Type == EntryDisabled {
+			assert.Equal(t, id, ev.EntryID)
+			assert.Equal(t, "boom", ev.Err.(*PanicError).Value)
+		}
+		types = append(types, ev.Type)
+	}
+	assert.Contains(t, types, EntryDisabled)
+}
+
+func TestRunNow(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	ran, release := make(chan struct{}, 2), make(chan struct{})
+	id, _ := cron.AddFunc("@daily", func() {
+		ran <- struct{}{}
+		<-release
+	})
+	assert.Equal(t, ErrEntryNotFound, cron.RunNow(id+1))
+
+	// Before the scheduler is started.
+	assert.NoError(t, cron.RunNow(id))
+	<-ran
+	assert.Equal(t, ErrSkipped, cron.RunNow(id, RunNowSkipIfRunning))
+	assert.Equal(t, clock.Now(), cron.Entry(id).Prev)
+	release <- struct{}{}
+	cron.Shutdown(context.Background())
+
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	next := cron.Entry(id).Next
+	clock.Advance(time.Minute)
+	assert.NoError(t, cron.RunNow(id, RunNowSkipIfRunning))
+	<-ran
+	assert.Equal(t, ErrSkipped, cron.RunNow(id, RunNowSkipIfRunning))
+	assert.NoError(t, cron.RunNow(id))
+	<-ran
+	close(release)
+	assert.Equal(t, clock.Now(), cron.Entry(id).Prev)
+	assert.Equal(t, next, cron.Entry(id).Next)
+}
+
+func TestRunNowAsync(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithErrorHandler(func(EntryID, error) {}))
+	ok, _ := cron.AddFuncE("@daily", func() error {
+		clock.Advance(time.Second)
+		return nil
+	})
+	failing, _ := cron.AddFuncE("@daily", func() error { return errors.New("failed") })
+	panicking, _ := cron.AddFunc("@daily", func() { panic("boom") })
+	release := make(chan struct{})
+	blocking, _ := cron.AddFunc("@daily", func() { <-release }, WithConcurrencyPolicy(ForbidConcurrent))
+
+	start := clock.Now()
+	run := cron.RunNowAsync(ok)
+	<-run.Done()
+	assert.NoError(t, run.Err())
+	assert.Equal(t, start, run.Started())
+	assert.Equal(t, time.Second, run.Duration())
+
+	assert.Equal(t, "failed", cron.RunNowWait(context.Background(), failing).Error())
+	var perr *PanicError
+	assert.True(t, errors.As(cron.RunNowWait(context.Background(), panicking), &perr), "expected a *PanicError")
+	assert.Equal(t, "boom", perr.Value)
+	assert.Equal(t, ErrEntryNotFound, cron.RunNowAsync(blocking+1).Err())
+
+	// A run skipped by the concurrency policy, and one skipped right away.
+	first := cron.RunNowAsync(blocking)
+	for cron.Entry(blocking).Stats.LastStart.IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+	skipped := cron.RunNowAsync(blocking)
+	<-skipped.Done()
+	assert.True(t, errors.Is(skipped.Err(), ErrSkipped), "expected the run to be skipped")
+	assert.True(t, skipped.Started().IsZero())
+	assert.Equal(t, ErrSkipped, cron.RunNowAsync(blocking, RunNowSkipIfRunning).Err())
+
+	// Only the wait is abandoned when ctx is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	waiting, _ := cron.AddFunc("@daily", func() { <-release })
+	assert.Equal(t, context.Canceled, cron.RunNowWait(ctx, waiting))
+	assert.Equal(t, 1, cron.Entry(waiting).Running)
+	assert.NoError(t, first.Err())
+	close(release)
+	<-first.Done()
+	assert.NoError(t, first.Err())
+}
+
+func TestUpdateSchedule(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	calls := make(chan struct{}, 10)
+	id, _ := cron.AddFunc("@every 1h", func() { calls <- struct{}{} })
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+
+	_, err := Parse("@every x")
+	assert.Equal(t, err, cron.UpdateSchedule(id, "@every x"))
+	assert.Equal(t, "@every 1h", cron.Entry(id).Spec)
+	assert.Equal(t, ErrEntryNotFound, cron.UpdateSchedule(id+1, "@every 10ms"))
+
+	assert.NoError(t, cron.UpdateSchedule(id, "@every 10ms"))
+	entry := cron.Entry(id)
+	assert.Equal(t, id, entry.ID)
+	assert.Equal(t, "@every 10ms", entry.Spec)
+	assert.Equal(t, clock.Now().Add(time.Second), entry.Next)
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		select {
+		case <-calls:
+		case <-time.After(OneSecond):
+			t.Fatal("expected the updated schedule to run")
+		}
+	}
+
+	assert.NoError(t, cron.UpdateScheduleTo(id, Every(time.Hour)))
+	assert.Equal(t, "", cron.Entry(id).Spec)
+	assert.Equal(t, id, cron.Entry(id).ID)
+}
+
+func TestRemoveAll(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var calls int32
+	for i := 0; i < 3; i++ {
+		cron.AddFunc("* * * * * ?", func() { atomic.AddInt32(&calls, 1) })
+	}
+	assert.Equal(t, 3, cron.RemoveAll())
+	assert.Empty(t, cron.Entries())
+
+	cron.AddFunc("* * * * * ?", func() { atomic.AddInt32(&calls, 1) })
+	cron.AddFunc("* * * * * ?", func() { atomic.AddInt32(&calls, 1) })
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	assert.Equal(t, 2, cron.RemoveAll())
+	assert.Empty(t, cron.Entries())
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, 0, cron.RemoveAll())
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestIsRunning(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	assert.False(t, cron.IsRunning())
+	cron.Start()
+	assert.True(t, cron.IsRunning())
+	cron.Stop()
+	assert.False(t, cron.IsRunning())
+
+	ran := make(chan struct{}, 1)
+	cron.AddFunc("* * * * * ?", func() { ran <- struct{}{} })
+	done := make(chan struct{})
+	go func() {
+		cron.Run()
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-ran
+	assert.True(t, cron.IsRunning())
+	cron.Stop()
+	<-done
+	assert.False(t, cron.IsRunning())
+}
+
+func TestActiveEntry(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	id, _ := cron.AddFunc("@daily", func() {
+		started <- struct{}{}
+		<-release
+	})
+	assert.False(t, cron.Entry(id).Active)
+
+	cron.RunNow(id)
+	cron.RunNow(id)
+	<-started
+	<-started
+	entry := cron.Entry(id)
+	assert.True(t, entry.Active)
+	assert.Equal(t, 2, entry.Running)
+
+	close(release)
+	cron.Shutdown(context.Background())
+	entry = cron.Entry(id)
+	assert.False(t, entry.Active)
+	assert.Equal(t, 0, entry.Running)
+}
+
+func TestMaxRuns(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var once, thrice int32
+	completed := make(chan EntryID, 2)
+	id1, _ := cron.AddFunc("* * * * * ?", func() { atomic.AddInt32(&once, 1) },
+		WithMaxRuns(1), WithOnComplete(func(id EntryID) { completed <- id }))
+	id3, _ := cron.AddFunc("* * * * * ?", func() { atomic.AddInt32(&thrice, 1) },
+		WithMaxRuns(3), WithOnComplete(func(id EntryID) { completed <- id }))
+	assert.Equal(t, 3, cron.Entry(id3).RemainingRuns)
+	cron.Start()
+	defer cron.Stop()
+
+	for i := 0; i < 5; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+	assert.ElementsMatch(t, []EntryID{id1, id3}, []EntryID{<-completed, <-completed})
+	assert.Empty(t, cron.Entries())
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&once))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&thrice))
+}
+
+func TestMaxRunsSkippedRuns(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var runs int32
+	release := make(chan struct{})
+	completed := make(chan EntryID, 1)
+	id, _ := cron.AddJob("* * * * * ?", SkipIfStillRunning(log.New(&syncBuffer{}, "", 0))(FuncJob(func() {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			<-release
+		}
+	})), WithMaxRuns(3), WithOnComplete(func(id EntryID) { completed <- id }))
+	cron.Start()
+	defer cron.Stop()
+
+	// The activations skipped while the first run hangs do not count.
+	for i := 0; i < 10 && cron.Entry(id).Stats.Skipped < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 2, cron.Entry(id).RemainingRuns)
+	close(release)
+	for i := 0; i < 10 && len(cron.Entries()) > 0; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case done := <-completed:
+		assert.Equal(t, id, done)
+	case <-time.After(OneSecond):
+		t.Fatal("expected the entry to complete")
+	}
+	assert.Empty(t, cron.Entries())
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+}
+
+func TestSecondsOptional(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.July, 9, 14, 46, 10, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC, WithSecondsOptional())
+	minutes, err := cron.AddFunc("*/5 * * * *", func() {})
+	assert.NoError(t, err)
+	seconds, err := cron.AddFunc("30 */5 * * * *", func() {})
+	assert.NoError(t, err)
+	_, err = cron.AddFunc("* * * *", func() {})
+	assert.Error(t, err)
+	cron.Start()
+	defer cron.Stop()
+	assert.Equal(t, time.Date(2012, time.July, 9, 14, 50, 0, 0, time.UTC), cron.Entry(minutes).Next)
+	assert.Equal(t, time.Date(2012, time.July, 9, 14, 50, 30, 0, time.UTC), cron.Entry(seconds).Next)
+}
+
+func TestActivationWindow(t *testing.T) {
+	start := time.Date(2012, time.July, 9, 0, 0, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(start)
+	cron := NewWithLocation(clock, time.UTC)
+	runs := make(chan time.Time, 10)
+	from, to := start.Add(2*time.Hour+30*time.Minute), start.Add(5*time.Hour)
+	kept, _ := cron.AddFunc("0 0 * * * *", func() { runs <- clock.Now() }, WithStartAt(from), WithEndAt(to))
+	removed, _ := cron.AddFunc("0 0 * * * *", func() {}, WithStartAt(from), WithEndAt(to), WithRemoveAfterEnd())
+	every := cron.Schedule(Every(time.Hour), FuncJob(func() {}), WithStartAt(from))
+	_, err := cron.AddFunc("@hourly", func() {}, WithStartAt(to), WithEndAt(from))
+	assert.Equal(t, ErrInvalidWindow, err)
+	cron.Start()
+	defer cron.Stop()
+
+	assert.Equal(t, start.Add(3*time.Hour), cron.Entry(kept).Next)
+	assert.Equal(t, from.Add(time.Hour), cron.Entry(every).Next)
+	assert.Equal(t, from, cron.Entry(kept).StartAt)
+	assert.Equal(t, to, cron.Entry(kept).EndAt)
+
+	for hour := 1; hour <= 6; hour++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+		if hour < 3 || hour > 5 {
+			continue
+		}
+		select {
+		case at := <-runs:
+			assert.Equal(t, start.Add(time.Duration(hour)*time.Hour), at)
+		case <-time.After(OneSecond):
+			t.Fatalf("expected a run at %d:00", hour)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, runs, 0)
+	assert.True(t, cron.Entry(kept).Next.IsZero())
+	assert.Equal(t, EntryID(0), cron.Entry(removed).ID)
+}
+
+func TestLocationFor(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	// DST starts in New York on Sun Mar 11 2012.
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.March, 10, 12, 0, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC)
+	idUTC, _ := cron.AddFunc("0 0 9 * * ?", func() {})
+	idNY, _ := cron.AddFunc("0 0 9 * * ?", func() {}, WithLocationFor(ny))
+	assert.Equal(t, time.UTC, cron.Entry(idUTC).Location)
+	assert.Equal(t, ny, cron.Entry(idNY).Location)
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+
+	assert.Equal(t, time.Date(2012, time.March, 11, 9, 0, 0, 0, time.UTC), cron.Entry(idUTC).Next)
+	assert.Equal(t, time.Date(2012, time.March, 10, 14, 0, 0, 0, time.UTC), cron.Entry(idNY).Next.UTC())
+	clock.Advance(2 * time.Hour)
+	clock.BlockUntil(1)
+	assert.Equal(t, time.Date(2012, time.March, 11, 13, 0, 0, 0, time.UTC), cron.Entry(idNY).Next.UTC())
+	assert.Equal(t, time.Date(2012, time.March, 11, 9, 0, 0, 0, time.UTC), cron.Entry(idUTC).Next)
+}
+
+func TestPriority(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	priorities := []int{3, -1, 7, 0, 3, 9, -5, 0, 3, 1}
+	var ids []EntryID
+	for _, p := range priorities {
+		id, _ := cron.AddFunc("* * * * * ?", func() {}, WithPriority(p))
+		ids = append(ids, id)
+	}
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+
+	// The run loop starts the due entries in the order of the entry list.
+	var order []EntryID
+	for _, entry := range cron.Entries() {
+		order = append(order, entry.ID)
+	}
+	expected := []EntryID{ids[5], ids[2], ids[0], ids[4], ids[8], ids[9], ids[3], ids[7], ids[1], ids[6]}
+	assert.Equal(t, expected, order)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	order = order[:0]
+	for _, entry := range cron.Entries() {
+		order = append(order, entry.ID)
+	}
+	assert.Equal(t, expected, order)
+}
+
+func TestEntriesFuncAndPage(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var ids []EntryID
+	for i := 1; i <= 10; i++ {
+		ids = append(ids, cron.Schedule(Every(time.Duration(11-i)*time.Minute), FuncJob(func() {}),
+			WithPriority(i%3)))
+	}
+	matched := cron.EntriesFunc(func(e Entry) bool { return e.Priority == 0 })
+	assert.Len(t, matched, 3)
+	for _, e := range matched {
+		assert.Equal(t, 0, e.Priority)
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	page, total := cron.EntriesPage(2, 3, nil)
+	assert.Equal(t, 10, total)
+	assert.Len(t, page, 3)
+	assert.Equal(t, []EntryID{ids[7], ids[6], ids[5]}, []EntryID{page[0].ID, page[1].ID, page[2].ID})
+
+	byID := func(a, b Entry) bool { return a.ID < b.ID }
+	page, total = cron.EntriesPage(8, 5, byID)
+	assert.Equal(t, 10, total)
+	assert.Len(t, page, 2)
+	assert.Equal(t, ids[8], page[0].ID)
+	page, _ = cron.EntriesPage(20, 5, byID)
+	assert.Empty(t, page)
+}
+
+func benchmarkEntries(b *testing.B, entries func(c *Cron)) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	for i := 0; i < 20000; i++ {
+		cron.Schedule(Every(time.Duration(i+1)*time.Second), FuncJob(func() {}))
+	}
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries(cron)
+	}
+}
+
+func BenchmarkEntries(b *testing.B) {
+	benchmarkEntries(b, func(c *Cron) { c.Entries() })
+}
+
+func BenchmarkEntriesPage(b *testing.B) {
+	benchmarkEntries(b, func(c *Cron) { c.EntriesPage(0, 50, nil) })
+}
+
+// BenchmarkEntriesParallel lists the entries of a running Cron of 10k entries
+// from several goroutines, while entries are added and removed.
+func BenchmarkEntriesParallel(b *testing.B) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	for i := 0; i < 10000; i++ {
+		cron.Schedule(Every(time.Duration(i+1)*time.Second), FuncJob(func() {}))
+	}
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				cron.Remove(cron.Schedule(Every(time.Hour), FuncJob(func() {})))
+			}
+		}
+	}()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cron.Entries()
+		}
+	})
+}
+
+// Test adding, removing and reading entries from several goroutines while
+// jobs run.
+func TestConcurrentAccess(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var calls int32
+	cron.AddFunc("@every 1s", func() { atomic.AddInt32(&calls, 1) })
+	cron.Start()
+	defer cron.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id, _ := cron.AddFunc("@every 1s", func() {})
+				cron.Entries()
+				cron.EntriesPage(0, 10, nil)
+				cron.Pause(id)
+				assert.True(t, cron.Remove(id))
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+	assert.Len(t, cron.Entries(), 1)
+	assert.True(t, cron.IsRunning())
+	cron.Stop()
+	cron.jobWaiter.Wait()
+	assert.True(t, atomic.LoadInt32(&calls) > 0, "expected the job to run")
+}
+
+func TestJobRemovesItsEntry(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var runs int32
+	removed := make(chan bool, 10)
+	var id EntryID
+	id, _ = cron.AddFunc("@every 1s", func() {
+		atomic.AddInt32(&runs, 1)
+		removed <- cron.Remove(id)
+	})
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case ok := <-removed:
+		assert.True(t, ok)
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to remove its entry")
+	}
+	assert.Len(t, cron.Entries(), 0)
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+}
+
+func TestJobAddsEntries(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithSubsecondPrecision())
+	fired := make(chan struct{}, 10)
+	added := make(chan error, 1)
+	cron.AddFunc("@every 1h", func() {
+		id, err := cron.AddFunc("@every 500ms", func() { fired <- struct{}{} })
+		if err == nil {
+			cron.Pause(id)
+			cron.Resume(id)
+			err = cron.UpdateSchedule(id, "@every 250ms")
+		}
+		cron.Entries()
+		added <- err
+	}, WithRunOnStart())
+	cron.Start()
+	defer cron.Stop()
+	select {
+	case err := <-added:
+		assert.NoError(t, err)
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to add an entry")
+	}
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(250 * time.Millisecond)
+		select {
+		case <-fired:
+		case <-time.After(OneSecond):
+			t.Fatal("expected the entry added by the job to run")
+		}
+	}
+	assert.Len(t, cron.Entries(), 2)
+}
+
+func TestHandlersMutateEntries(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var cron *Cron
+	seen := make(chan int, 10)
+	cron = New(clock,
+		WithJobWrappers(WrapperFunc(func(ctx context.Context, run func(context.Context) error) error {
+			seen <- len(cron.Entries())
+			return run(ctx)
+		})),
+		WithErrorHandler(func(id EntryID, err error) {
+			cron.Pause(id)
+			cron.AddFunc("@daily", func() {})
+		}))
+	id, _ := cron.AddFuncE("@every 1s", func() error { return errors.New("failed") })
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case n := <-seen:
+		assert.Equal(t, 1, n)
+	case <-time.After(OneSecond):
+		t.Fatal("expected the wrapper to list the entries")
+	}
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.True(t, cron.Entry(id).Paused)
+	assert.Len(t, cron.Entries(), 2)
+}
+
+func TestJobAddsEntryWithFullPool(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithMaxConcurrentJobs(1), WithJobQueueLimit(1, OverflowBlock))
+	added := make(chan error, 1)
+	cron.AddFunc("@every 1s", func() {
+		// Let the scheduler submit the third run, over the queue limit.
+		time.Sleep(10 * time.Millisecond)
+		_, err := cron.AddFunc("@daily", func() {})
+		added <- err
+	}, WithPriority(1))
+	cron.AddFunc("@every 1s", func() {})
+	cron.AddFunc("@every 1s", func() {})
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case err := <-added:
+		assert.NoError(t, err)
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to add an entry while the pool is full")
+	}
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Len(t, cron.Entries(), 4)
+}
+
+func TestStats(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithErrorHandler(func(EntryID, error) {}))
+	runs := 0
+	id, _ := cron.AddFunc("@daily", func() {
+		runs++
+		clock.Advance(time.Duration(runs) * time.Second)
+		if runs%2 == 0 {
+			panic("even run")
+		}
+	})
+	assert.Equal(t, Stats{}, cron.Entry(id).Stats)
+
+	for i := 1; i <= 4; i++ {
+		start := clock.Now()
+		cron.RunNow(id)
+		cron.Shutdown(context.Background())
+		stats := cron.Entry(id).Stats
+		assert.Equal(t, uint64(i), stats.Runs)
+		assert.Equal(t, start, stats.LastStart)
+		assert.Equal(t, clock.Now(), stats.LastEnd)
+		assert.Equal(t, time.Duration(i)*time.Second, stats.LastDuration)
+		assert.Equal(t, (i+1)%2, stats.ConsecutiveFailures)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	locker := &toggleLocker{}
+	cron := New(clock, WithEntryLocker(locker), WithErrorHandler(func(EntryID, error) {}))
+	runs := 0
+	id, _ := cron.AddFunc("@daily", func() {
+		runs++
+		if runs == 3 {
+			panic("third run")
+		}
+	}, WithHistory(3))
+	other, _ := cron.AddFunc("@daily", func() {})
+	disabled, _ := cron.AddFunc("@daily", func() {}, WithHistory(0))
+	negative, err := cron.AddFunc("@daily", func() {}, WithHistory(-1))
+	assert.NoError(t, err)
+	assert.Empty(t, cron.History(id))
+
+	var starts []time.Time
+	for i := 0; i < 5; i++ {
+		locker.deny = i == 3
+		starts = append(starts, clock.Now())
+		cron.RunNow(id)
+		cron.RunNow(disabled)
+		cron.RunNow(negative)
+		cron.Shutdown(context.Background())
+		clock.Advance(time.Second)
+	}
+	history := cron.History(id)
+	assert.Len(t, history, 3)
+	assert.Equal(t, starts[2], history[0].Start)
+	assert.Error(t, history[0].Err)
+	assert.False(t, history[0].Skipped)
+	assert.Equal(t, starts[3], history[1].Start)
+	assert.True(t, history[1].Skipped)
+	assert.Equal(t, starts[4], history[2].Start)
+	assert.NoError(t, history[2].Err)
+	assert.False(t, history[2].Skipped)
+
+	history[0].Skipped = true
+	assert.False(t, cron.History(id)[0].Skipped)
+	assert.Empty(t, cron.History(other))
+	assert.Empty(t, cron.History(disabled))
+	assert.Empty(t, cron.History(negative))
+	assert.Equal(t, uint64(4), cron.Entry(negative).Stats.Runs)
+	assert.Nil(t, cron.History(id+negative+1))
+}
+
+// toggleLocker grants every lock unless deny is set.
+type toggleLocker struct {
+	deny bool
+}
+
+func (l *toggleLocker) Acquire(ctx context.Context, id EntryID, fireTime time.Time) (func(), bool, error) {
+	return func() {}, !l.deny, nil
+}
+
+func TestNextN(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.January, 30, 10, 0, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC)
+	id, _ := cron.AddFunc("0 30 9 */2 * ?", func() {})
+	expected := []time.Time{
+		time.Date(2012, time.January, 31, 9, 30, 0, 0, time.UTC),
+		time.Date(2012, time.February, 1, 9, 30, 0, 0, time.UTC),
+		time.Date(2012, time.February, 3, 9, 30, 0, 0, time.UTC),
+		time.Date(2012, time.February, 5, 9, 30, 0, 0, time.UTC),
+		time.Date(2012, time.February, 7, 9, 30, 0, 0, time.UTC),
+	}
+	assert.Equal(t, expected, cron.NextN(id, 5))
+	assert.Nil(t, cron.NextN(id+1, 5))
+
+	never := cron.Schedule(At(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)), FuncJob(func() {}))
+	assert.Equal(t, []time.Time{}, cron.NextN(never, 5))
+}
+
+func BenchmarkWake(b *testing.B) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	for i := 0; i < 50000; i++ {
+		cron.Schedule(Every(24*time.Hour), FuncJob(func() {}))
+	}
+	cron.Schedule(Every(time.Second), FuncJob(func() {}))
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clock.Advance(time.Second)
+		clock.BlockUntil(1)
+	}
+}
+
+// Test that the fake clock drives the scheduler: an hourly job runs once per
+// hour of fake time, without sleeping.
+func TestFakeClockRunsHourlyJob(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var calls int32
+	cron.AddFunc("@every 1h", func() { atomic.AddInt32(&calls, 1) })
+	cron.Start()
+	defer cron.Stop()
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+	}
+	clock.BlockUntil(1)
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestMissedRunPolicy(t *testing.T) {
+	tests := []struct {
+		opts     []Option
+		expected int32
+	}{
+		{nil, 0},
+		{[]Option{WithMissedRunPolicy(SkipMissed)}, 0},
+		{[]Option{WithMissedRunPolicy(RunOnce)}, 1},
+		{[]Option{WithMissedRunPolicy(RunAll)}, 6},
+		{[]Option{WithMissedRunPolicy(RunAll), WithMissedRunLimit(4)}, 4},
+	}
+	for _, test := range tests {
+		clock := clockwork.NewFakeClockAt(time.Date(2012, time.June, 15, 12, 0, 0, 0, time.UTC))
+		cron := New(clock, test.opts...)
+		var calls int32
+		id, _ := cron.AddFunc("@hourly", func() { atomic.AddInt32(&calls, 1) })
+		cron.Start()
+		clock.BlockUntil(1)
+		clock.Advance(6 * time.Hour)
+		clock.BlockUntil(1)
+		assert.Equal(t, time.Date(2012, time.June, 15, 19, 0, 0, 0, time.UTC), cron.Entry(id).Next)
+
+		// The next activation runs as usual.
+		clock.Advance(time.Hour)
+		clock.BlockUntil(1)
+		cron.Stop()
+		cron.Shutdown(context.Background())
+		assert.Equal(t, test.expected+1, atomic.LoadInt32(&calls))
+	}
+}
+
+func TestDSTPolicy(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.March, 11, 0, 0, 0, 0, ny))
+	cron := NewWithLocation(clock, ny, WithDSTPolicy(RunAtNextValid))
+	shifted, _ := cron.AddFunc("0 30 2 * * ?", func() {})
+	skipped, _ := cron.AddFunc("0 30 2 * * ?", func() {}, WithDSTPolicyFor(SkipNonexistent))
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	assert.Equal(t, time.Date(2012, time.March, 11, 3, 30, 0, 0, ny), cron.Entry(shifted).Next)
+	assert.Equal(t, time.Date(2012, time.March, 12, 2, 30, 0, 0, ny), cron.Entry(skipped).Next)
+}
+
+// Test that entries due at the same time start in the order they were added,
+// including the ones added while running.
+func TestSimultaneousLaunchOrder(t *testing.T) {
+	var expected []int
+	for i := 0; i < 50; i++ {
+		expected = append(expected, i)
+	}
+	for run := 0; run < 3; run++ {
+		clock := clockwork.NewFakeClock()
+		// With a single slot, jobs start in the order they are launched.
+		cron := New(clock, WithMaxConcurrentJobs(1))
+		started := make(chan int, 50)
+		add := func(i int) {
+			cron.AddFunc("@every 1s", func() { started <- i }, WithMaxRuns(1))
+		}
+		for i := 0; i < 25; i++ {
+			add(i)
+		}
+		cron.Start()
+		for i := 25; i < 50; i++ {
+			add(i)
+		}
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+
+		var order []int
+		for i := 0; i < 50; i++ {
+			order = append(order, <-started)
+		}
+		cron.Shutdown(context.Background())
+		assert.Equal(t, expected, order)
+	}
+}
+
+func TestRunOnStart(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	ran := make(chan string, 2)
+	before, _ := cron.AddFunc("@every 1h", func() { ran <- "before" }, WithRunOnStart())
+	cron.AddFunc("@every 1h", func() { t.Error("expected the entry not to run on start") })
+	assert.Len(t, ran, 0)
+
+	cron.Start()
+	defer cron.Stop()
+	assert.Equal(t, "before", <-ran)
+	clock.Advance(time.Minute)
+	during, _ := cron.AddFunc("@every 1h", func() { ran <- "during" }, WithRunOnStart())
+	assert.Equal(t, "during", <-ran)
+
+	cron.Shutdown(context.Background())
+	entry := cron.Entry(before)
+	assert.Equal(t, uint64(1), entry.Stats.Runs)
+	assert.Equal(t, entry.Prev.Add(time.Hour), entry.Next)
+	entry = cron.Entry(during)
+	assert.Equal(t, uint64(1), entry.Stats.Runs)
+	assert.Equal(t, clock.Now().Add(time.Hour), entry.Next)
+}
+
+func TestReboot(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	ran := make(chan string, 4)
+	id, _ := cron.AddFunc("@reboot", func() { ran <- "start" })
+	assert.Len(t, ran, 0)
+
+	cron.Start()
+	assert.Equal(t, "start", <-ran)
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+	}
+	added, _ := cron.AddFunc("@reboot", func() { ran <- "added" })
+	assert.Equal(t, "added", <-ran)
+	cron.Shutdown(context.Background())
+	assert.Len(t, ran, 0)
+	entry := cron.Entry(id)
+	assert.Equal(t, uint64(1), entry.Stats.Runs)
+	assert.True(t, entry.Next.IsZero())
+	assert.Equal(t, "@reboot", entry.Schedule.(RebootSchedule).String())
+
+	// Each start runs the entries again.
+	cron.Start()
+	assert.ElementsMatch(t, []string{"start", "added"}, []string{<-ran, <-ran})
+	cron.Shutdown(context.Background())
+	assert.Equal(t, uint64(2), cron.Entry(added).Stats.Runs)
+}
+
+func TestOneShot(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2025, time.July, 1, 8, 0, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC)
+	ran := make(chan time.Time, 2)
+	completed := make(chan EntryID, 1)
+	id, err := cron.AddFunc("@at 2025-07-01T09:00:00+02:00", func() { t.Error("expected the entry in the past not to run") })
+	assert.NoError(t, err)
+	shot := cron.Schedule(At(clock.Now().Add(time.Hour)), FuncJob(func() { ran <- clock.Now() }),
+		WithOnComplete(func(id EntryID) { completed <- id }))
+	cron.Start()
+	defer cron.Stop()
+
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Minute)
+	clock.BlockUntil(1)
+	assert.Len(t, ran, 0)
+	clock.Advance(time.Minute)
+	assert.Equal(t, time.Date(2025, time.July, 1, 9, 0, 0, 0, time.UTC), <-ran)
+	assert.Equal(t, shot, <-completed)
+	clock.BlockUntil(1)
+	clock.Advance(24 * time.Hour)
+	clock.BlockUntil(1)
+
+	cron.Shutdown(context.Background())
+	assert.Len(t, ran, 0)
+	entries := cron.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, id, entries[0].ID)
+	assert.True(t, entries[0].Next.IsZero())
+}
+
+func TestAfterCompletion(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	starts, finish := make(chan time.Time, 10), make(chan struct{})
+	id, _ := cron.AddFunc("@after 5m", func() {
+		starts <- clock.Now()
+		<-finish
+	})
+	start := clock.Now()
+	cron.Start()
+	defer cron.Stop()
+
+	// awaitNext waits for the entry to get its next time after a run.
+	awaitNext := func() time.Time {
+		for cron.Entry(id).Next.IsZero() {
+			time.Sleep(time.Millisecond)
+		}
+		return cron.Entry(id).Next
+	}
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Minute)
+	assert.Equal(t, start.Add(5*time.Minute), <-starts)
+	assert.True(t, cron.Entry(id).Next.IsZero())
+
+	// A 3 minute run starts the next one 8 minutes after.
+	clock.Advance(3 * time.Minute)
+	finish <- struct{}{}
+	assert.Equal(t, start.Add(13*time.Minute), awaitNext())
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Minute)
+	assert.Equal(t, start.Add(13*time.Minute), <-starts)
+
+	// No run starts while one is in progress, however long it lasts.
+	for i := 0; i < 4; i++ {
+		clock.Advance(5 * time.Minute)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Len(t, starts, 0)
+	finish <- struct{}{}
+	assert.Equal(t, start.Add(38*time.Minute), awaitNext())
+	close(finish)
+
+	sched, err := Parse("@after 5m")
+	assert.NoError(t, err)
+	assert.True(t, AfterCompletion(5*time.Minute).Equal(sched))
+	assert.Equal(t, "@after 5m0s", sched.(AfterCompletionSchedule).String())
+}
+
+func TestBackoffEntry(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	start := clock.Now()
+	starts := make(chan time.Duration, 10)
+	var id EntryID
+	id = cron.Schedule(Backoff(10*time.Second, 10*time.Minute, 2), FuncJobCtx(func(ctx context.Context) {
+		elapsed := clock.Since(start)
+		switch elapsed {
+		case 70 * time.Second:
+			// The entry is rescheduled after the initial delay.
+			assert.True(t, cron.ResetBackoff(id))
+		case 140 * time.Second:
+			// The activation after the next one is after the initial delay.
+			entry, _ := EntryFromContext(ctx)
+			entry.Schedule.(BackoffSchedule).Reset()
+		}
+		starts <- elapsed
+	}))
+	cron.Start()
+	defer cron.Stop()
+
+	previous := time.Duration(0)
+	for _, seconds := range []time.Duration{10, 30, 70, 80, 100, 140, 220, 230} {
+		clock.BlockUntil(1)
+		clock.Advance(seconds*time.Second - previous)
+		assert.Equal(t, seconds*time.Second, <-starts)
+		previous = seconds * time.Second
+	}
+	assert.False(t, cron.ResetBackoff(id+1))
+	other, _ := cron.AddFunc("@every 1m", func() {})
+	assert.False(t, cron.ResetBackoff(other))
+}
+
+func TestAfterCompletionRunOnStart(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	starts := make(chan time.Time, 10)
+	id, _ := cron.AddFunc("@after 1m", func() { starts <- clock.Now() }, WithRunOnStart())
+	start := clock.Now()
+	cron.Start()
+	defer cron.Stop()
+	assert.Equal(t, start, <-starts)
+	for cron.Entry(id).Next.IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, start.Add(time.Minute), cron.Entry(id).Next)
+}
+
+func TestJitter(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2025, time.July, 1, 9, 0, 30, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC, WithJitterSeed(1))
+	jittered, _ := cron.AddFunc("0 * * * * *", func() {}, WithJitter(30*time.Second))
+	clamped, _ := cron.AddFunc("0 0 * * * *", func() {}, WithJitter(24*time.Hour))
+	cron.Start()
+	defer cron.Stop()
+
+	offsets := make(map[time.Duration]bool)
+	var prev time.Time
+	for i := 0; i < 10; i++ {
+		clock.BlockUntil(1)
+		entry := cron.Entry(jittered)
+		assert.Equal(t, prev, entry.Prev)
+		base := entry.Next.Truncate(time.Minute)
+		assert.True(t, base.After(entry.Prev), "expected one run per activation")
+		offset := entry.Next.Sub(base)
+		assert.True(t, offset < 30*time.Second, "expected the delay to be less than the jitter")
+		offsets[offset] = true
+		prev = entry.Next
+		clock.Advance(entry.Next.Sub(clock.Now()))
+	}
+	assert.True(t, len(offsets) > 1, "expected the delays to vary")
+
+	next := cron.Entry(clamped).Next
+	assert.True(t, !next.Before(time.Date(2025, time.July, 1, 10, 0, 0, 0, time.UTC)) &&
+		next.Before(time.Date(2025, time.July, 1, 11, 0, 0, 0, time.UTC)),
+		"expected the delay to be clamped to the next activation")
+
+	// The same seed gives the same delays.
+	seeded := func() []time.Time {
+		cron := NewWithLocation(clockwork.NewFakeClockAt(time.Date(2025, time.July, 1, 9, 0, 30, 0, time.UTC)), time.UTC, WithJitterSeed(7))
+		id, _ := cron.AddFunc("0 * * * * *", func() {}, WithJitter(30*time.Second))
+		return cron.NextN(id, 5)
+	}
+	assert.Equal(t, seeded(), seeded())
+}
+
+func TestSubsecondPrecision(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithSubsecondPrecision())
+	var calls int32
+	id, _ := cron.AddFunc("@every 100ms", func() { atomic.AddInt32(&calls, 1) })
+	cron.Start()
+	defer cron.Stop()
+	for i := 0; i < 10; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(100 * time.Millisecond)
+	}
+	clock.BlockUntil(1)
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(10), atomic.LoadInt32(&calls))
+	assert.Equal(t, clock.Now().Add(100*time.Millisecond), cron.Entry(id).Next)
+
+	// Without the option, the delay is still rounded to the second.
+	cron = New(clock)
+	id, _ = cron.AddFunc("@every 100ms", func() {})
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	assert.Equal(t, clock.Now().Add(time.Second), cron.Entry(id).Next)
+}
+
+func TestJobContext(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	started := make(chan Entry, 2)
+	cancelled := make(chan EntryID, 2)
+	job := func(ctx context.Context) {
+		e, _ := EntryFromContext(ctx)
+		started <- e
+		<-ctx.Done()
+		cancelled <- e.ID
+	}
+	a, _ := cron.AddFuncCtx("@every 1h", job, WithName("a"))
+	b, _ := cron.AddFuncCtx("@every 1h", job, WithName("b"))
+	cron.Start()
+	assert.NoError(t, cron.RunNow(a))
+	assert.NoError(t, cron.RunNow(b))
+	names := map[EntryID]string{}
+	for i := 0; i < 2; i++ {
+		e := <-started
+		names[e.ID] = e.Name
+	}
+	assert.Equal(t, map[EntryID]string{a: "a", b: "b"}, names)
+
+	// Removing an entry cancels only its runs.
+	cron.Remove(a)
+	assert.Equal(t, a, <-cancelled)
+	select {
+	case id := <-cancelled:
+		t.Errorf("expected the run of entry %d to go on", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Stopping cancels the others, but not the runs started afterwards.
+	cron.Stop()
+	assert.Equal(t, b, <-cancelled)
+	assert.NoError(t, cron.RunNow(b))
+	<-started
+	select {
+	case <-cancelled:
+		t.Error("expected the run started after Stop to go on")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cron.Remove(b)
+	<-cancelled
+
+	_, ok := EntryFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestAlignedDelayEntries(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.July, 9, 10, 7, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC)
+	idUTC, _ := cron.AddFunc("@every-aligned 1h", func() {})
+	idIST, _ := cron.AddFunc("@every-aligned 1h", func() {}, WithLocationFor(ist))
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	assert.Equal(t, time.Date(2012, time.July, 9, 11, 0, 0, 0, time.UTC), cron.Entry(idUTC).Next.UTC())
+	assert.Equal(t, time.Date(2012, time.July, 9, 10, 30, 0, 0, time.UTC), cron.Entry(idIST).Next.UTC())
+	clock.Advance(23 * time.Minute)
+	clock.BlockUntil(1)
+	assert.Equal(t, time.Date(2012, time.July, 9, 11, 30, 0, 0, time.UTC), cron.Entry(idIST).Next.UTC())
+}
+
+func TestRandomDelayEntries(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var calls int32
+	id := cron.Schedule(EveryRange(5*time.Second, 10*time.Second).WithSeed(3), FuncJob(func() { atomic.AddInt32(&calls, 1) }))
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	for i := 0; i < 20; i++ {
+		delay := cron.Entry(id).Next.Sub(clock.Now())
+		assert.True(t, delay >= 5*time.Second && delay <= 10*time.Second, "delay out of range")
+		clock.Advance(delay)
+		clock.BlockUntil(1)
+	}
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(20), atomic.LoadInt32(&calls))
+}
+
+func TestRegisteredDescriptor(t *testing.T) {
+	p := NewParser(Second | Minute | Hour | Dom | Month | DowOptional | Descriptor)
+	err := p.RegisterDescriptor("every-few", func(arg string) (Schedule, error) {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, err
+		}
+		return Every(time.Duration(n) * time.Second), nil
+	})
+	assert.NoError(t, err)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithParser(p))
+	var calls int32
+	id, err := cron.AddFunc("@every-few 5", func() { atomic.AddInt32(&calls, 1) })
+	assert.NoError(t, err)
+	_, err = cron.AddFunc("@every-few x", func() {})
+	assert.Error(t, err)
+	cron.Start()
+	defer cron.Stop()
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(5 * time.Second)
+	}
+	clock.BlockUntil(1)
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, clock.Now().Add(5*time.Second), cron.Entry(id).Next)
+
+	// Other crons do not know the descriptor.
+	_, err = New(clock).AddFunc("@every-few 5", func() {})
+	assert.Error(t, err)
+}
+
+func TestYearScheduleEntries(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.June, 15, 12, 0, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC)
+	sched, _ := NewParser(Second | Minute | Hour | Dom | Month | Dow | Year).Parse("0 0 12 1 1 ? 2014")
+	id := cron.Schedule(sched, FuncJob(func() {}))
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	assert.Equal(t, time.Date(2014, time.January, 1, 12, 0, 0, 0, time.UTC), cron.Entry(id).Next)
+}
+
+func TestHashKey(t *testing.T) {
+	schedules := func(opts ...Option) []Schedule {
+		cron := New(clockwork.NewFakeClock(), opts...)
+		var ids []EntryID
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			id, err := cron.AddFunc("H H * * * *", func() {}, WithName(name))
+			assert.NoError(t, err)
+			ids = append(ids, id)
+		}
+		var scheds []Schedule
+		for _, id := range ids {
+			scheds = append(scheds, cron.Entry(id).Schedule)
+		}
+		return scheds
+	}
+
+	first, again, other := schedules(), schedules(), schedules(WithHashKey("svc"))
+	distinct := 0
+	for i := range first {
+		assert.True(t, first[i].(*SpecSchedule).Equal(again[i]), "expected the same name to pick the same times")
+		if !first[i].(*SpecSchedule).Equal(first[0]) {
+			distinct++
+		}
+		if !first[i].(*SpecSchedule).Equal(other[i]) {
+			distinct++
+		}
+	}
+	assert.True(t, distinct > 0, "expected other names and hash keys to pick other times")
+}
+
+type DummyJob struct{}
+
+func (d DummyJob) Run() {
+	panic("YOLO")
+}
+
+func TestJobPanicRecovery(t *testing.T) {
+	var job DummyJob
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.Start()
+	defer cron.Stop()
+	cron.AddJob("* * * * * ?", job)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-time.After(OneSecond):
+		t.Fatal("expected the panic to be reported")
+	case msg := <-cron.PanicCh:
+		assert.Contains(t, msg, "YOLO")
+	}
+}
+
+// Start and stop cron with no entries.
+func TestNoEntries(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	cron.Start()
+
+	select {
+	case <-time.After(OneSecond):
+		t.Fatal("expected cron will be stopped immediately")
+	case <-stop(cron):
+	}
+}
+
+// Start, stop, then add an entry. Verify entry doesn't run.
+func TestStopCausesJobsToNotRun(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.Start()
+	cron.Stop()
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+	clock.Advance(2 * time.Second)
+
+	select {
+	case <-time.After(10 * time.Millisecond):
+		// No job ran!
+	case <-wait(wg):
+		t.Fatal("expected stopped cron does not run any job")
+	}
+}
+
+// Add a job, start cron, expect it runs.
+func TestAddBeforeRunning(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-time.After(OneSecond):
+		t.Fatal("expected job runs")
+	case <-wait(wg):
+	}
+}
+
+// Start cron, add a job, expect it runs.
+func TestAddWhileRunning(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.Start()
+	defer cron.Stop()
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-time.After(OneSecond):
+		t.Fatal("expected job runs")
+	case <-wait(wg):
+	}
+}
+
+// Test for #34. Adding a job after calling start results in multiple job invocations
+func TestAddWhileRunningWithDelay(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Second)
+	var calls int32
+	cron.AddFunc("* * * * * *", func() { atomic.AddInt32(&calls, 1) })
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+
+	cron.Shutdown(context.Background())
+	if calls != 1 {
+		t.Errorf("called %d times, expected 1\n", calls)
+	}
+}
+
+// Test timing with Entries.
+func TestSnapshotEntries(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("@every 2s", func() { wg.Done() })
+	cron.Start()
+	defer cron.Stop()
+
+	// Cron should fire in 2 seconds. After 1 second, call Entries.
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	cron.Entries()
+
+	// Even though Entries was called, the cron should fire at the 2 second mark.
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case <-time.After(OneSecond):
+		t.Error("expected job runs at 2 second mark")
+	case <-wait(wg):
+	}
+}
+
+// Test that the entries are correctly sorted.
+// Add a bunch of long-in-the-future entries, and an immediate entry, and ensure
+// that the immediate entry runs immediately.
+// Also: Test that multiple jobs run in the same instant.
+func TestMultipleEntries(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("0 0 0 1 1 ?", func() {})
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+	cron.AddFunc("0 0 0 31 12 ?", func() {})
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-time.After(OneSecond):
+		t.Error("expected job run in proper order")
+	case <-wait(wg):
+	}
+}
+
+// Test running the same job twice.
+func TestRunningJobTwice(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("0 0 0 1 1 ?", func() {})
+	cron.AddFunc("0 0 0 31 12 ?", func() {})
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+
+	cron.Start()
+	defer cron.Stop()
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+
+	select {
+	case <-time.After(OneSecond):
+		t.Error("expected job fires 2 times")
+	case <-wait(wg):
+	}
+}
+
+func TestRunningMultipleSchedules(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("0 0 0 1 1 ?", func() {})
+	cron.AddFunc("0 0 0 31 12 ?", func() {})
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+	cron.Schedule(Every(time.Minute), FuncJob(func() {}))
+	cron.Schedule(Every(time.Second), FuncJob(func() { wg.Done() }))
+	cron.Schedule(Every(time.Hour), FuncJob(func() {}))
+
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-time.After(OneSecond):
+		t.Error("expected job fires 2 times")
+	case <-wait(wg):
+	}
+}
+
+// Test that the cron is run in the local time zone (as opposed to UTC).
+func TestLocalTimezone(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.June, 15, 12, 30, 10, 0, time.Local))
+	now := clock.Now()
+	spec := fmt.Sprintf("%d,%d %d %d %d %d ?",
+		now.Second()+1, now.Second()+2, now.Minute(), now.Hour(), now.Day(), now.Month())
+
+	cron := New(clock)
+	cron.AddFunc(spec, func() { wg.Done() })
+	cron.Start()
+	defer cron.Stop()
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+
+	select {
+	case <-time.After(OneSecond):
+		t.Error("expected job fires 2 times")
+	case <-wait(wg):
+	}
+}
+
+// Test that the cron is run in the given time zone (as opposed to local).
+func TestNonLocalTimezone(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	loc, err := time.LoadLocation("Atlantic/Cape_Verde")
+	if err != nil {
+		t.Skipf("Failed to load time zone Atlantic/Cape_Verde: %+v", err)
+	}
+
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.June, 15, 12, 30, 10, 0, time.UTC))
+	now := clock.Now().In(loc)
+	spec := fmt.Sprintf("%d,%d %d %d %d %d ?",
+		now.Second()+1, now.Second()+2, now.Minute(), now.Hour(), now.Day(), now.Month())
+
+	cron := NewWithLocation(clock, loc)
+	cron.AddFunc(spec, func() { wg.Done() })
+	cron.Start()
+	defer cron.Stop()
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+
+	select {
+	case <-time.After(OneSecond):
+		t.Error("expected job fires 2 times")
+	case <-wait(wg):
+	}
+}
+
+func TestSetLocation(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.July, 9, 6, 0, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC)
+	ran := make(chan time.Time, 2)
+	daily, _ := cron.AddFunc("0 0 9 * * *", func() { ran <- clock.Now() })
+	pinned, _ := cron.AddCrontab(strings.NewReader("CRON_TZ=UTC\n0 9 * * * pinned\n"), func(CrontabLine) Job {
+		return FuncJob(func() {})
+	})
+	every := cron.Schedule(Every(25*time.Minute), FuncJob(func() {}))
+	cron.Start()
+	defer cron.Stop()
+	everyNext := cron.Entry(every).Next
+
+	est := time.FixedZone("EST", -5*3600)
+	cron.SetLocation(est)
+	assert.Equal(t, est, cron.Location())
+	assert.True(t, cron.Entry(daily).Next.Equal(time.Date(2012, time.July, 9, 14, 0, 0, 0, time.UTC)),
+		"expected the daily entry to move to 09:00 EST")
+	assert.Equal(t, est, cron.Entry(daily).Location)
+	assert.True(t, cron.Entry(pinned[0]).Next.Equal(time.Date(2012, time.July, 9, 9, 0, 0, 0, time.UTC)),
+		"expected the pinned entry to stay at 09:00 UTC")
+	assert.Equal(t, everyNext, cron.Entry(every).Next)
+
+	// The run loop wakes up at the new time.
+	clock.BlockUntil(1)
+	clock.Advance(8 * time.Hour)
+	select {
+	case at := <-ran:
+		assert.True(t, at.Equal(time.Date(2012, time.July, 9, 14, 0, 0, 0, time.UTC)), at.String())
+	case <-time.After(OneSecond):
+		t.Fatal("expected the daily entry to run at 09:00 EST")
+	}
+	assert.Len(t, ran, 0)
+}
+
+// Test that calling stop before start silently returns without
+// blocking the stop channel.
+func TestStopWithoutStart(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	cron.Stop()
+}
+
+type testJob struct {
+	wg   *sync.WaitGroup
+	name string
+}
+
+func (t testJob) Run() {
+	t.wg.Done()
+}
+
+// Test that adding an invalid job spec returns an error
+func TestInvalidJobSpec(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	_, err := cron.AddJob("this will not parse", nil)
+	if err == nil {
+		t.Errorf("expected an error with invalid spec, got nil")
+	}
+}
+
+// Test blocking run method behaves as Start()
+func TestBlockingRun(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("* * * * * ?", func() { wg.Done() })
+
+	var unblockChan = make(chan struct{})
+
+	go func() {
+		cron.Run()
+		close(unblockChan)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-time.After(OneSecond):
+		t.Error("expected job fires")
+	case <-unblockChan:
+		t.Error("expected that Run() blocks")
+	case <-wait(wg):
+	}
+	cron.Stop()
+	<-unblockChan
+}
+
+// Test that double-running is a no-op
+func TestStartNoop(t *testing.T) {
+	var tickChan = make(chan struct{}, 2)
+
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	cron.AddFunc("* * * * * ?", func() {
+		tickChan <- struct{}{}
+	})
+
+	cron.Start()
+	defer cron.Stop()
+
+	// Wait for the first firing to ensure the runner is going
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-tickChan
+
+	cron.Start()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-tickChan
+
+	// Fail if this job fires again in a short period, indicating a double-run
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-tickChan:
+		t.Error("expected job fires exactly twice")
+	}
+}
+
+// Simple test using Runnables.
+func TestJob(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.June, 15, 12, 0, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, time.UTC)
+	// February 30th, which the parser rejects.
+	cron.Schedule(&SpecSchedule{Second: 1, Minute: 1, Hour: 1, Dom: 1 << 30, Month: 1 << 2, Dow: all(dow)}, testJob{wg, "job0"})
+	cron.AddJob("0 0 0 1 1 ?", testJob{wg, "job1"})
+	cron.AddJob("* * * * * ?", testJob{wg, "job2"})
+	cron.AddJob("1 0 0 1 1 ?", testJob{wg, "job3"})
+	cron.Schedule(Every(5*time.Second+5*time.Nanosecond), testJob{wg, "job4"})
+	cron.Schedule(Every(5*time.Minute), testJob{wg, "job5"})
+
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-time.After(OneSecond):
+		t.FailNow()
+	case <-wait(wg):
+	}
+
+	// Ensure the entries are in the right order.
+	expecteds := []string{"job2", "job4", "job5", "job1", "job3", "job0"}
+
+	var actuals []string
+	for _, entry := range cron.Entries() {
+		actuals = append(actuals, entry.Job.(testJob).name)
+	}
+
+	for i, expected := range expecteds {
+		if actuals[i] != expected {
+			t.Fatalf("Jobs not in the right order.  (expected) %s != %s (actual)", expecteds, actuals)
+		}
+	}
+}
+
+type ZeroSchedule struct{}
+
+func (*ZeroSchedule) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+// Tests that job without time does not run
+func TestJobWithZeroTimeDoesNotRun(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	var calls int32
+	cron.AddFunc("* * * * * *", func() { atomic.AddInt32(&calls, 1) })
+	cron.Schedule(new(ZeroSchedule), FuncJob(func() { t.Error("expected zero task will not run") }))
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	cron.Shutdown(context.Background())
+	if calls != 1 {
+		t.Errorf("called %d times, expected 1\n", calls)
+	}
+}
+
+func wait(wg *sync.WaitGroup) chan bool {
+	ch := make(chan bool)
+	go func() {
+		wg.Wait()
+		ch <- true
+	}()
+	return ch
+}
+
+func stop(cron *Cron) chan bool {
+	ch := make(chan bool)
+	go func() {
+		cron.Stop()
+		ch <- true
+	}()
+	return ch
+}
@@ -0,0 +1,212 @@
+// Package cronadmin provides an HTTP handler to inspect and manage the entries
+// of a cron.Cron, for admin endpoints and dashboards. It is a package of its
+// own so that package cron does not depend on net/http.
+//
+// The handler serves:
+//
+//	GET    /entries             the entries, as {"entries": [...]}
+//	POST   /entries/{id}/run    runs the entry now
+//	POST   /entries/{id}/pause  pauses the entry
+//	POST   /entries/{id}/resume resumes the entry
+//	DELETE /entries/{id}        removes the entry
+//	GET    /healthz             whether the Cron is running, as {"running": true}
+//
+// where {id} is the string ID of the entry, or its numeric ID.
+//
+// The requests changing the entries are only served if allowed by the func set
+// with WithAuthorizer. Errors are returned as {"error": "..."}, with a 404
+// status for unknown entries.
+package cronadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// Option represents a modification to the handler.
+type Option func(*handler)
+
+// WithAuthorizer sets the func reporting whether a request changing the
+// entries is allowed, such as by checking its credentials. The requests not
+// allowed get a 403 status. By default, none is allowed.
+func WithAuthorizer(authorize func(r *http.Request) bool) Option {
+	return func(h *handler) {
+		h.authorize = authorize
+	}
+}
+
+type handler struct {
+	cron      *cron.Cron
+	authorize func(*http.Request) bool
+	mux       *http.ServeMux
+}
+
+// NewHandler returns an http.Handler serving the entries of c.
+func NewHandler(c *cron.Cron, opts ...Option) http.Handler {
+	h := &handler{cron: c, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /entries", h.entries)
+	h.mux.HandleFunc("POST /entries/{id}/run", h.mutate(h.run))
+	h.mux.HandleFunc("POST /entries/{id}/pause", h.mutate(h.pause))
+	h.mux.HandleFunc("POST /entries/{id}/resume", h.mutate(h.resume))
+	h.mux.HandleFunc("DELETE /entries/{id}", h.mutate(h.remove))
+	h.mux.HandleFunc("GET /healthz", h.healthz)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Entry is the JSON shape of an entry. Times are in RFC 3339, or null if zero.
+type Entry struct {
+	ID       cron.EntryID `json:"id"`
+	StringID string       `json:"stringId"`
+	Name     string       `json:"name"`
+	Key      string       `json:"key"`
+	Tags     []string     `json:"tags"`
+	Spec     string       `json:"spec"`
+	Next     *time.Time   `json:"next"`
+	Prev     *time.Time   `json:"prev"`
+	Paused   bool         `json:"paused"`
+	Disabled bool         `json:"disabled"`
+	Running  int          `json:"running"`
+	Stats    Stats        `json:"stats"`
+}
+
+// Stats is the JSON shape of the stats of an entry.
+type Stats struct {
+	Runs                uint64     `json:"runs"`
+	Skipped             uint64     `json:"skipped"`
+	Replaced            uint64     `json:"replaced"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastStart           *time.Time `json:"lastStart"`
+	LastEnd             *time.Time `json:"lastEnd"`
+	LastDurationMs      int64      `json:"lastDurationMs"`
+}
+
+func newEntry(e cron.Entry) Entry {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return Entry{
+		ID:       e.ID,
+		StringID: e.StringID,
+		Name:     e.Name,
+		Key:      e.Key,
+		Tags:     tags,
+		Spec:     e.Spec,
+		Next:     timeOrNil(e.Next),
+		Prev:     timeOrNil(e.Prev),
+		Paused:   e.Paused,
+		Disabled: e.Disabled,
+		Running:  e.Running,
+		Stats: Stats{
+			Runs:                e.Stats.Runs,
+			Skipped:             e.Stats.Skipped,
+			Replaced:            e.Stats.Replaced,
+			ConsecutiveFailures: e.Stats.ConsecutiveFailures,
+			LastStart:           timeOrNil(e.Stats.LastStart),
+			LastEnd:             timeOrNil(e.Stats.LastEnd),
+			LastDurationMs:      e.Stats.LastDuration.Milliseconds(),
+		},
+	}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (h *handler) entries(w http.ResponseWriter, r *http.Request) {
+	entries := h.cron.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	list := make([]Entry, len(entries))
+	for i, e := range entries {
+		list[i] = newEntry(e)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entries": list})
+}
+
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if !h.cron.IsRunning() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]bool{"running": h.cron.IsRunning()})
+}
+
+// mutate returns a handler calling fn with the ID of the entry of the request,
+// if the request is allowed. The entry is looked up by string ID first, then
+// by numeric ID.
+func (h *handler) mutate(fn func(w http.ResponseWriter, id cron.EntryID)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.authorize == nil || !h.authorize(r) {
+			writeError(w, http.StatusForbidden, errors.New("Forbidden"))
+			return
+		}
+		if e, ok := h.cron.EntryByStringID(r.PathValue("id")); ok {
+			fn(w, e.ID)
+			return
+		}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusNotFound, cron.ErrEntryNotFound)
+			return
+		}
+		fn(w, cron.EntryID(id))
+	}
+}
+
+func (h *handler) run(w http.ResponseWriter, id cron.EntryID) {
+	if err := h.cron.RunNow(id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]cron.EntryID{"id": id})
+}
+
+func (h *handler) pause(w http.ResponseWriter, id cron.EntryID) {
+	h.setPaused(w, id, h.cron.Pause(id))
+}
+
+func (h *handler) resume(w http.ResponseWriter, id cron.EntryID) {
+	h.setPaused(w, id, h.cron.Resume(id))
+}
+
+func (h *handler) setPaused(w http.ResponseWriter, id cron.EntryID, found bool) {
+	if !found {
+		writeError(w, http.StatusNotFound, cron.ErrEntryNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, newEntry(h.cron.Entry(id)))
+}
+
+func (h *handler) remove(w http.ResponseWriter, id cron.EntryID) {
+	if !h.cron.Remove(id) {
+		writeError(w, http.StatusNotFound, cron.ErrEntryNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
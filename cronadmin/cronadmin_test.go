@@ -0,0 +1,139 @@
+package cronadmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/robfig/cron"
+	"github.com/stretchr/testify/assert"
+)
+
+func allowAll(*http.Request) bool { return true }
+
+func do(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestEntries(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.July, 9, 14, 45, 0, 0, time.UTC))
+	c := cron.New(clock)
+	c.AddFunc("@every 1h", func() {}, cron.WithName("report"), cron.WithTags("daily"))
+	c.AddFunc("@every 1m", func() {})
+	c.Start()
+	defer c.Stop()
+	h := NewHandler(c)
+
+	w := do(h, "GET", "/entries")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var body map[string][]map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	entries := body["entries"]
+	assert.Len(t, entries, 2)
+	assert.Equal(t, float64(1), entries[0]["id"])
+	assert.Equal(t, "report", entries[0]["name"])
+	assert.Equal(t, []interface{}{"daily"}, entries[0]["tags"])
+	assert.Equal(t, "@every 1h", entries[0]["spec"])
+	assert.Equal(t, "2012-07-09T15:45:00Z", entries[0]["next"])
+	assert.Equal(t, nil, entries[0]["prev"])
+	assert.Equal(t, []interface{}{}, entries[1]["tags"])
+	stats := entries[0]["stats"].(map[string]interface{})
+	assert.Equal(t, float64(0), stats["runs"])
+	assert.Equal(t, nil, stats["lastStart"])
+	for _, key := range []string{"skipped", "replaced", "consecutiveFailures", "lastEnd", "lastDurationMs"} {
+		_, ok := stats[key]
+		assert.True(t, ok, key)
+	}
+}
+
+func TestMutations(t *testing.T) {
+	c := cron.New(clockwork.NewRealClock())
+	ran := make(chan struct{}, 1)
+	id, _ := c.AddFunc("@every 1h", func() { ran <- struct{}{} })
+	h := NewHandler(c, WithAuthorizer(allowAll))
+
+	w := do(h, "POST", "/entries/1/run")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("expected the entry to run")
+	}
+
+	w = do(h, "POST", "/entries/1/pause")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var e Entry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &e))
+	assert.True(t, e.Paused)
+	assert.True(t, c.Entry(id).Paused)
+
+	w = do(h, "POST", "/entries/1/resume")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, c.Entry(id).Paused)
+
+	w = do(h, "DELETE", "/entries/1")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Len(t, c.Entries(), 0)
+
+	// Entries are found by string ID too.
+	id, _ = c.AddFunc("@every 1h", func() {}, cron.WithID("report"))
+	w = do(h, "POST", "/entries/report/pause")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, c.Entry(id).Paused)
+	assert.Contains(t, w.Body.String(), `"stringId":"report"`)
+}
+
+func TestUnknownEntry(t *testing.T) {
+	h := NewHandler(cron.New(clockwork.NewRealClock()), WithAuthorizer(allowAll))
+	for _, req := range [][2]string{
+		{"POST", "/entries/7/run"},
+		{"POST", "/entries/7/pause"},
+		{"POST", "/entries/7/resume"},
+		{"DELETE", "/entries/7"},
+	} {
+		w := do(h, req[0], req[1])
+		assert.Equal(t, http.StatusNotFound, w.Code, req[1])
+		assert.Contains(t, w.Body.String(), `"error"`)
+	}
+	assert.Equal(t, http.StatusNotFound, do(h, "POST", "/entries/x/run").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(h, "GET", "/entries/7/run").Code)
+}
+
+func TestAuthorizer(t *testing.T) {
+	c := cron.New(clockwork.NewRealClock())
+	c.AddFunc("@every 1h", func() {})
+	deny := func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer secret" }
+	for _, h := range []http.Handler{NewHandler(c), NewHandler(c, WithAuthorizer(deny))} {
+		assert.Equal(t, http.StatusForbidden, do(h, "POST", "/entries/1/pause").Code)
+		assert.Equal(t, http.StatusForbidden, do(h, "DELETE", "/entries/1").Code)
+		assert.Equal(t, http.StatusOK, do(h, "GET", "/entries").Code)
+	}
+	assert.False(t, c.Entry(1).Paused)
+	assert.Len(t, c.Entries(), 1)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/entries/1/pause", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	NewHandler(c, WithAuthorizer(deny)).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHealthz(t *testing.T) {
+	c := cron.New(clockwork.NewRealClock())
+	h := NewHandler(c)
+	w := do(h, "GET", "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "{\"running\":false}\n", w.Body.String())
+
+	c.Start()
+	defer c.Stop()
+	w = do(h, "GET", "/healthz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"running\":true}\n", w.Body.String())
+}
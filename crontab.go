@@ -0,0 +1,409 @@
+package cron
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"time"
+)
+
+// CrontabLine is a job line of a crontab document.
+type CrontabLine struct {
+	// The number of the line in the document, from 1.
+	Line int
+
+	// The schedule of the line, either 5 fields or a descriptor.
+	Spec string
+
+	// The rest of the line after the schedule, such as the command to run.
+	Command string
+
+	// The time zone set by the last CRON_TZ or TZ line before this one, or nil.
+	Location *time.Location
+
+	// The environment lines before this one, as "NAME=value".
+	Env []string
+
+	// The name set by a "# name: ..." comment before this line, or empty.
+	Name string
+}
+
+// CrontabError is an error in a line of a crontab document.
+type CrontabError struct {
+	Line, Column int
+	Err          error
+}
+
+func (e *CrontabError) Error() string {
+	return fmt.Sprintf("Line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+// CrontabErrors are the errors in the lines of a crontab document.
+type CrontabErrors []*CrontabError
+
+func (e CrontabErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// crontabParser parses the schedules of crontab lines.
+var crontabParser = NewParser(Minute | Hour | Dom | Month | Dow | Descriptor)
+
+// ParseCrontab reads the job lines of a crontab document. Blank lines and
+// lines starting with # are ignored, and environment lines such as
+// "CRON_TZ=Europe/Paris" apply to the job lines after them: CRON_TZ and TZ set
+// their time zone. A job line is a schedule of 5 fields or a descriptor, such
+// as @daily or "@every 5m", followed by its command.
+//
+// A line with an invalid schedule or time zone is left out, and its error is
+// returned, along with the errors in the other lines, as CrontabErrors after
+// reading the whole document.
+//
+// A comment such as "# name: backup" names the next job line, as with
+// WithName.
+func ParseCrontab(r io.Reader) ([]CrontabLine, error) {
+	var (
+		lines []CrontabLine
+		errs  CrontabErrors
+		loc   *time.Location
+		env   []string
+		name  string
+	)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		fields := splitCrontabLine(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.HasPrefix(fields[0].text, "#") {
+			comment := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "#"))
+			if strings.HasPrefix(comment, "name:") {
+				name = strings.TrimSpace(strings.TrimPrefix(comment, "name:"))
+			}
+			continue
+		}
+
+		if name, value, ok := envLine(scanner.Text()); ok {
+			if name == "CRON_TZ" || name == "TZ" {
+				l, err := time.LoadLocation(value)
+				if err != nil {
+					errs = append(errs, &CrontabError{n, strings.Index(scanner.Text(), "=") + 2, err})
+					continue
+				}
+				loc = l
+			}
+			env = append(env[:len(env):len(env)], name+"="+value)
+			continue
+		}
+
+		count := 5
+		if strings.HasPrefix(fields[0].text, "@") {
+			count = 1
+			if fields[0].text == "@every" || fields[0].text == "@at" || fields[0].text == "@after" {
+				count = 2
+			}
+		}
+		if len(fields) < count {
+			errs = append(errs, &CrontabError{n, fields[0].column, fmt.Errorf("Expected %d fields before the command, found %d", count, len(fields))})
+			name = ""
+			continue
+		}
+		spec := fields[0].text
+		for _, f := range fields[1:count] {
+			spec += " " + f.text
+		}
+		if _, err := crontabParser.Parse(spec); err != nil {
+			column := fields[0].column
+			if fieldErr, ok := err.(*ParseError); ok {
+				column = fields[fieldErr.Index].column
+			}
+			errs = append(errs, &CrontabError{n, column, err})
+			name = ""
+			continue
+		}
+		var command string
+		if len(fields) > count {
+			command = strings.TrimSpace(scanner.Text()[fields[count].column-1:])
+		}
+		lines = append(lines, CrontabLine{Line: n, Spec: spec, Command: command, Location: loc, Env: env, Name: name})
+		name = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return lines, err
+	}
+	if len(errs) > 0 {
+		return lines, errs
+	}
+	return lines, nil
+}
+
+// AddCrontab adds an entry for each job line of a crontab document, as read by
+// ParseCrontab, running the job returned by factory for the line. Lines for
+// which factory returns nil are left out. The entries for the valid lines are
+// added even if other lines have errors, which are returned as CrontabErrors.
+func (c *Cron) AddCrontab(r io.Reader, factory func(line CrontabLine) Job) ([]EntryID, error) {
+	lines, err := ParseCrontab(r)
+	errs, _ := err.(CrontabErrors)
+	if err != nil && errs == nil {
+		return nil, err
+	}
+	var ids []EntryID
+	for _, line := range lines {
+		schedule, err := c.parseWith(crontabParser, line.Spec, line.Command)
+		if err != nil {
+			errs = append(errs, &CrontabError{line.Line, 1, err})
+			continue
+		}
+		job := factory(line)
+		if job == nil {
+			errs = append(errs, &CrontabError{line.Line, 1, fmt.Errorf("No job for %q", line.Command)})
+			continue
+		}
+		id, err := c.schedule(schedule, job, crontabOptions(line))
+		if err != nil {
+			errs = append(errs, &CrontabError{line.Line, 1, err})
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(errs) > 0 {
+		return ids, errs
+	}
+	return ids, nil
+}
+
+// crontabOptions returns the options of the entry of a crontab line.
+func crontabOptions(line CrontabLine) []EntryOption {
+	opts := []EntryOption{withSpec(line.Spec)}
+	if line.Location != nil {
+		opts = append(opts, WithLocationFor(line.Location))
+	}
+	if line.Name != "" {
+		opts = append(opts, WithName(line.Name))
+	}
+	return opts
+}
+
+// ReloadOption is an option of Reload.
+type ReloadOption int
+
+const (
+	// ReloadAtomic leaves the entries unchanged if a line has an error.
+	ReloadAtomic ReloadOption = 1 << iota
+)
+
+// Reload makes the entries loaded from crontab documents with Reload match the
+// job lines of the given one, as read by ParseCrontab, and returns how many
+// entries were added, removed and updated. It is safe to call while the Cron
+// is running.
+//
+// A line is identified by its name, set with a "# name: ..." comment, or else
+// by a hash of its command. The entry of an unchanged line is left as is; that
+// of a changed line gets the new schedule, location and job in place, keeping
+// its ID and stats. The entries of the lines that are gone are removed, and
+// entries are added for new lines, with the job returned by factory.
+//
+// The errors in lines, including those returned by factory, are returned as
+// CrontabErrors after applying the valid lines; the entry of a line whose job
+// cannot be made is left unchanged. With ReloadAtomic, nothing is applied if
+// a line has an error.
+func (c *Cron) Reload(r io.Reader, factory func(line CrontabLine) (Job, error), opts ...ReloadOption) (added, removed, updated int, err error) {
+	var options ReloadOption
+	for _, opt := range opts {
+		options |= opt
+	}
+	lines, err := ParseCrontab(r)
+	errs, _ := err.(CrontabErrors)
+	if err != nil && errs == nil {
+		return 0, 0, 0, err
+	}
+
+	// Reloads are serialized, so the entries only change in between if they
+	// are removed or updated by other means.
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	current := make(map[string]CrontabLine)
+	c.apply(func() {
+		for _, e := range c.entries {
+			if e.crontabKey != "" {
+				current[e.crontabKey] = e.crontabLine
+			}
+		}
+	})
+
+	type change struct {
+		key      string
+		line     CrontabLine
+		schedule Schedule
+		job      Job
+	}
+	var changes []change
+	keys := make(map[string]bool)
+	for _, line := range lines {
+		key := crontabKey(line, keys)
+		keys[key] = true
+		if old, ok := current[key]; ok && sameCrontabLine(old, line) {
+			continue
+		}
+		schedule, err := c.parseWith(crontabParser, line.Spec, line.Command)
+		if err != nil {
+			errs = append(errs, &CrontabError{line.Line, 1, err})
+			continue
+		}
+		job, err := factory(line)
+		if err == nil && job == nil {
+			err = fmt.Errorf("No job for %q", line.Command)
+		}
+		if err != nil {
+			errs = append(errs, &CrontabError{line.Line, 1, err})
+			continue
+		}
+		changes = append(changes, change{key, line, schedule, job})
+	}
+	if len(errs) > 0 && options&ReloadAtomic > 0 {
+		return 0, 0, 0, errs
+	}
+
+	var additions []change
+	c.apply(func() {
+		byKey := make(map[string]*Entry)
+		for _, e := range append([]*Entry(nil), c.entries...) {
+			switch {
+			case e.crontabKey == "":
+			case !keys[e.crontabKey]:
+				c.cancelEntry(e.ID)
+				removed++
+			default:
+				byKey[e.crontabKey] = e
+			}
+		}
+		for _, ch := range changes {
+			e := byKey[ch.key]
+			if e == nil {
+				additions = append(additions, ch)
+				continue
+			}
+			e.Schedule = ch.schedule
+			e.Spec = ch.line.Spec
+			e.Location = ch.line.Location
+			e.cronLocation = e.Location == nil
+			if e.cronLocation {
+				e.Location = c.location
+			}
+			e.Job = ch.job
+			e.WrappedJob = Chain(c.chain...)(Chain(e.chain...)(ch.job))
+			e.crontabLine = ch.line
+			if c.running {
+				e.Next = e.next(c.now())
+				heap.Fix(&c.entries, e.index)
+			}
+			updated++
+		}
+	})
+	for _, ch := range additions {
+		opts := append(crontabOptions(ch.line), withCrontab(ch.key, ch.line))
+		if _, err := c.schedule(ch.schedule, ch.job, opts); err != nil {
+			errs = append(errs, &CrontabError{ch.line.Line, 1, err})
+			continue
+		}
+		added++
+	}
+	if len(errs) > 0 {
+		return added, removed, updated, errs
+	}
+	return added, removed, updated, nil
+}
+
+// withCrontab records the crontab line an entry is loaded from with Reload.
+func withCrontab(key string, line CrontabLine) EntryOption {
+	return func(e *Entry) {
+		e.crontabKey = key
+		e.crontabLine = line
+	}
+}
+
+// crontabKey returns the key identifying a crontab line: its name, or else a
+// hash of its command, followed by its rank among the lines with the same
+// command if it is not the first one. seen holds the keys of the lines before.
+func crontabKey(line CrontabLine, seen map[string]bool) string {
+	if line.Name != "" {
+		return "name:" + line.Name
+	}
+	h := fnv.New64a()
+	h.Write([]byte(line.Command))
+	key := fmt.Sprintf("command:%x", h.Sum64())
+	for n := 2; seen[key]; n++ {
+		key = fmt.Sprintf("command:%x#%d", h.Sum64(), n)
+	}
+	return key
+}
+
+// sameCrontabLine reports whether two lines with the same key give the same
+// entry.
+func sameCrontabLine(a, b CrontabLine) bool {
+	if a.Spec != b.Spec || a.Command != b.Command || len(a.Env) != len(b.Env) {
+		return false
+	}
+	if (a.Location == nil) != (b.Location == nil) || a.Location != nil && a.Location.String() != b.Location.String() {
+		return false
+	}
+	for i := range a.Env {
+		if a.Env[i] != b.Env[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// crontabField is a whitespace separated field of a crontab line, with the
+// column it starts at, from 1.
+type crontabField struct {
+	text   string
+	column int
+}
+
+func splitCrontabLine(line string) []crontabField {
+	var fields []crontabField
+	start := -1
+	for i, r := range line + " " {
+		switch {
+		case r == ' ' || r == '\t':
+			if start >= 0 {
+				fields = append(fields, crontabField{line[start:i], start + 1})
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+	}
+	return fields
+}
+
+// envLine returns the name and value of an environment line, such as
+// "TZ=UTC" or `CRON_TZ = "Europe/Paris"`.
+func envLine(line string) (name, value string, ok bool) {
+	eq := strings.Index(line, "=")
+	if eq < 0 {
+		return "", "", false
+	}
+	name = strings.TrimSpace(line[:eq])
+	for i, r := range name {
+		if !(r == '_' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || i > 0 && '0' <= r && r <= '9') {
+			return "", "", false
+		}
+	}
+	if name == "" {
+		return "", "", false
+	}
+	value = strings.TrimSpace(line[eq+1:])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return name, value, true
+}
@@ -0,0 +1,175 @@
+package cron
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCrontab = `# Nightly maintenance
+SHELL=/bin/sh
+MAILTO="ops@example.com"
+
+30 2 * * *	/usr/local/bin/backup --full
+*/15 9-17 * * MON-FRI  /usr/local/bin/poll  -v
+
+# Reports follow the Paris office hours.
+CRON_TZ=Europe/Paris
+0 8 1 * * /usr/local/bin/report monthly
+@daily /usr/local/bin/rotate-logs
+@every 1h30m /usr/local/bin/sync
+0 25 * * * /usr/local/bin/broken
+TZ=Nowhere/Special
+@hourly
+`
+
+func TestParseCrontab(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	lines, err := ParseCrontab(strings.NewReader(testCrontab))
+
+	env := []string{"SHELL=/bin/sh", "MAILTO=ops@example.com"}
+	parisEnv := append(env[:2:2], "CRON_TZ=Europe/Paris")
+	assert.Equal(t, []CrontabLine{
+		{Line: 5, Spec: "30 2 * * *", Command: "/usr/local/bin/backup --full", Env: env},
+		{Line: 6, Spec: "*/15 9-17 * * MON-FRI", Command: "/usr/local/bin/poll  -v", Env: env},
+		{Line: 10, Spec: "0 8 1 * *", Command: "/usr/local/bin/report monthly", Location: paris, Env: parisEnv},
+		{Line: 11, Spec: "@daily", Command: "/usr/local/bin/rotate-logs", Location: paris, Env: parisEnv},
+		{Line: 12, Spec: "@every 1h30m", Command: "/usr/local/bin/sync", Location: paris, Env: parisEnv},
+		{Line: 15, Spec: "@hourly", Location: paris, Env: parisEnv},
+	}, lines)
+
+	errs, ok := err.(CrontabErrors)
+	assert.True(t, ok, "expected CrontabErrors")
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 13, errs[0].Line)
+	assert.Equal(t, 3, errs[0].Column)
+	assert.Contains(t, errs[0].Error(), "Line 13, column 3: ")
+	assert.Contains(t, errs[0].Error(), "End of range (25) above maximum (23)")
+	assert.Equal(t, 14, errs[1].Line)
+	assert.Equal(t, 4, errs[1].Column)
+}
+
+func TestParseCrontabErrors(t *testing.T) {
+	tests := []struct {
+		line         string
+		column       int
+		errSubstring string
+	}{
+		{"* * * *", 1, "Expected 5 fields"},
+		{"  @every", 3, "Expected 2 fields"},
+		{"@every 5x cmd", 1, "Failed to parse duration"},
+		{"0 0 * * FUNDAY cmd", 9, "Failed to parse int"},
+		{"@sometimes cmd", 1, "Unrecognized descriptor"},
+	}
+	for _, test := range tests {
+		_, err := ParseCrontab(strings.NewReader("ok=1\n" + test.line))
+		errs, _ := err.(CrontabErrors)
+		if assert.Len(t, errs, 1, test.line) {
+			assert.Equal(t, 2, errs[0].Line, test.line)
+			assert.Equal(t, test.column, errs[0].Column, test.line)
+			assert.Contains(t, errs[0].Error(), test.errSubstring, test.line)
+		}
+	}
+}
+
+func TestAddCrontab(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	cron := New(clockwork.NewFakeClock())
+	var commands []string
+	ids, err := cron.AddCrontab(strings.NewReader(testCrontab), func(line CrontabLine) Job {
+		if line.Command == "" {
+			return nil
+		}
+		commands = append(commands, line.Command)
+		return FuncJob(func() {})
+	})
+	assert.Len(t, ids, 5)
+	assert.Len(t, err.(CrontabErrors), 3)
+	assert.Len(t, commands, 5)
+
+	entries := cron.Entries()
+	assert.Equal(t, "30 2 * * *", entries[0].Spec)
+	assert.Equal(t, "0 8 1 * *", entries[2].Spec)
+	assert.Equal(t, paris, entries[2].Location)
+	sched := entries[0].Schedule.(*SpecSchedule)
+	assert.Equal(t, uint64(1<<30), sched.Minute)
+	assert.Equal(t, uint64(1<<2), sched.Hour)
+}
+
+func TestReload(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	cron.Start()
+	defer cron.Stop()
+	jobs := make(map[string]int)
+	factory := func(line CrontabLine) (Job, error) {
+		if strings.Contains(line.Command, "missing") {
+			return nil, errors.New("no such command")
+		}
+		jobs[line.Command]++
+		return FuncJob(func() {}), nil
+	}
+
+	added, removed, updated, err := cron.Reload(strings.NewReader(`
+# name: backup
+30 2 * * * /usr/local/bin/backup
+*/15 * * * * /usr/local/bin/poll
+@daily /usr/local/bin/rotate-logs
+`), factory)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 0, 0}, []int{added, removed, updated})
+	backup, _ := cron.EntryByName("backup")
+	ids := make(map[string]EntryID)
+	for _, e := range cron.Entries() {
+		ids[e.Spec] = e.ID
+	}
+
+	// The named line and the line with the same command keep their entries.
+	added, removed, updated, err = cron.Reload(strings.NewReader(`
+# name: backup
+0 3 * * * /usr/local/bin/backup --full
+@daily /usr/local/bin/rotate-logs
+@hourly /usr/local/bin/sync
+@hourly /usr/local/bin/missing
+`), factory)
+	assert.Len(t, err.(CrontabErrors), 1)
+	assert.Equal(t, []int{1, 1, 1}, []int{added, removed, updated})
+	entries := cron.Entries()
+	assert.Len(t, entries, 3)
+	e, _ := cron.EntryByName("backup")
+	assert.Equal(t, backup.ID, e.ID)
+	assert.Equal(t, "0 3 * * *", e.Spec)
+	assert.Equal(t, ids["@daily"], cron.Entry(ids["@daily"]).ID)
+	assert.Equal(t, EntryID(0), cron.Entry(ids["*/15 * * * *"]).ID)
+	assert.Equal(t, 1, jobs["/usr/local/bin/rotate-logs"])
+	assert.Equal(t, 1, jobs["/usr/local/bin/sync"])
+
+	// The spec of a line without a name is updated in place too.
+	added, removed, updated, err = cron.Reload(strings.NewReader(`
+# name: backup
+0 3 * * * /usr/local/bin/backup --full
+@weekly /usr/local/bin/rotate-logs
+@hourly /usr/local/bin/sync
+`), factory)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 0, 1}, []int{added, removed, updated})
+	assert.Equal(t, "@weekly", cron.Entry(ids["@daily"]).Spec)
+
+	// Nothing is applied with errors and ReloadAtomic.
+	added, removed, updated, err = cron.Reload(strings.NewReader(`
+@hourly /usr/local/bin/sync
+0 25 * * * /usr/local/bin/broken
+`), factory, ReloadAtomic)
+	assert.Len(t, err.(CrontabErrors), 1)
+	assert.Equal(t, []int{0, 0, 0}, []int{added, removed, updated})
+	assert.Len(t, cron.Entries(), 3)
+}
@@ -0,0 +1,333 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describe returns an English description of a spec, parsed as with Parse, or
+// with a Parser created with the given options if there are any. For example
+// "0 30 4 */2 * MON-FRI" is described as "At 04:30, on every 2nd day of the
+// month, Monday through Friday". The spec may start with a CRON_TZ= or TZ=
+// time zone, which ends the description, as in "At 09:00, in Europe/Paris".
+//
+// The description is assembled from a fragment for the time of day, the days
+// and the months, each built from the values of its fields.
+func Describe(spec string, opts ...ParseOption) (string, error) {
+	var zone string
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return "", fmt.Errorf("Missing spec after time zone: %s", spec)
+		}
+		zone = spec[strings.Index(spec, "=")+1 : i]
+		if _, err := time.LoadLocation(zone); err != nil {
+			return "", err
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+	schedule, err := parserFor(opts).Parse(spec)
+	if err != nil {
+		return "", err
+	}
+	desc := describeSchedule(schedule)
+	if zone != "" {
+		desc += ", in " + zone
+	}
+	return desc, nil
+}
+
+func describeSchedule(schedule Schedule) string {
+	switch s := schedule.(type) {
+	case *SpecSchedule:
+		return describeSpec(s)
+	case *YearSchedule:
+		var spans []fieldSpan
+		for _, year := range s.Years {
+			if n := len(spans); n > 0 && spans[n-1].end+1 == uint(year) {
+				spans[n-1].end++
+			} else {
+				spans = append(spans, fieldSpan{uint(year), uint(year), 1})
+			}
+		}
+		return describeSpec(s.Schedule) + ", in " + describeValues(spans, strconv.Itoa)
+	case ConstantDelaySchedule:
+		return "Every " + s.Delay.String()
+	case PreciseDelaySchedule:
+		return "Every " + s.Delay.String()
+	case AfterCompletionSchedule:
+		return s.Delay.String() + " after each run completes"
+	case BackoffSchedule:
+		return "Every " + s.Initial.String() + ", growing by a factor of " +
+			strconv.FormatFloat(s.Factor, 'g', -1, 64) + " up to " + s.Max.String()
+	case OneShotSchedule:
+		return "Once, at " + s.At.Format(time.RFC3339)
+	case RebootSchedule:
+		return "Once, when the Cron starts"
+	}
+	return fmt.Sprint(schedule)
+}
+
+// describeSpec returns the description of a spec schedule, with a capital.
+func describeSpec(s *SpecSchedule) string {
+	var fragments []string
+	for _, fragment := range []string{describeTime(s), describeDays(s), describeMonths(s)} {
+		if fragment != "" {
+			fragments = append(fragments, fragment)
+		}
+	}
+	desc := strings.Join(fragments, ", ")
+	return strings.ToUpper(desc[:1]) + desc[1:]
+}
+
+// describeTime returns the fragment for the second, minute and hour fields,
+// such as "at 04:30" or "every 5 minutes, between 09:00 and 17:59".
+func describeTime(s *SpecSchedule) string {
+	var (
+		secSpans  = fieldSpans(s.Second, seconds)
+		minSpans  = fieldSpans(s.Minute, minutes)
+		hourSpans = fieldSpans(s.Hour, hours)
+	)
+	if singleValue(secSpans) && singleValue(minSpans) && singleValues(hourSpans) {
+		var times []string
+		for _, h := range hourSpans {
+			times = append(times, clockString(h.start, minSpans[0].start, secSpans[0].start))
+		}
+		return "at " + joinWords(times)
+	}
+	onTheHour := isValue(secSpans, 0) && isValue(minSpans, 0)
+	step := len(hourSpans) == 1 && hourSpans[0].step > 1
+
+	var fragments []string
+	switch {
+	case onTheHour && step:
+	case onTheHour:
+		fragments = append(fragments, "every hour")
+	default:
+		if !isValue(secSpans, 0) {
+			fragments = append(fragments, describeUnit(secSpans, seconds, "second"))
+		}
+		// Every minute goes without saying after the seconds.
+		if !isFull(minSpans, minutes) || isValue(secSpans, 0) {
+			fragments = append(fragments, describeUnit(minSpans, minutes, "minute"))
+		}
+	}
+	switch {
+	case isFull(hourSpans, hours):
+	case step:
+		fragments = append(fragments, describeUnit(hourSpans, hours, "hour"))
+	default:
+		var between []string
+		for _, h := range hourSpans {
+			between = append(between, "between "+clockString(h.start, 0, 0)+" and "+clockString(h.end, 59, 0))
+		}
+		fragments = append(fragments, joinWords(between))
+	}
+	return strings.Join(fragments, ", ")
+}
+
+// describeDays returns the fragment for the day of month and day of week
+// fields, such as "on day 1 of the month" or "Monday through Friday".
+func describeDays(s *SpecSchedule) string {
+	var (
+		domSpans = fieldSpans(s.Dom&^weekdayBits, dom)
+		dowSpans = fieldSpans(s.Dow&^nthBits, dow)
+		domFrag  string
+		dowFrag  string
+	)
+	switch {
+	case s.LastDom && s.LastDomOffset == 0:
+		domFrag = "on the last day of the month"
+	case s.LastDom:
+		word := " days"
+		if s.LastDomOffset == 1 {
+			word = " day"
+		}
+		domFrag = strconv.Itoa(int(s.LastDomOffset)) + word + " before the last day of the month"
+	case s.Dom&weekdayBits > 0:
+		var days []string
+		for day := dom.min; day <= dom.max; day++ {
+			if s.Dom&(1<<(weekdayShift+day)) > 0 {
+				days = append(days, strconv.Itoa(int(day)))
+			}
+		}
+		domFrag = "on the weekday nearest day " + joinWords(days) + " of the month"
+	case s.Dom&starBit > 0 && isFull(domSpans, dom):
+	case len(domSpans) == 1 && domSpans[0].step > 1 && domSpans[0].start == dom.min:
+		domFrag = "on every " + ordinal(domSpans[0].step) + " day of the month"
+	default:
+		word := "days "
+		if singleValue(domSpans) {
+			word = "day "
+		}
+		domFrag = "on " + word + describeValues(domSpans, strconv.Itoa) + " of the month"
+	}
+	switch {
+	case s.Dow&nthBits > 0:
+		var nths []string
+		for bit := uint(nthShift); bit < nthShift+35; bit++ {
+			if s.Dow&(1<<bit) > 0 {
+				n := bit - nthShift
+				nths = append(nths, ordinal(n/7+1)+" "+time.Weekday(n%7).String())
+			}
+		}
+		dowFrag = "on the " + joinWords(nths) + " of the month"
+	case s.Dow&starBit > 0 && isFull(dowSpans, dow):
+	case len(dowSpans) == 1 && dowSpans[0].start != dowSpans[0].end && dowSpans[0].step == 1:
+		dowFrag = describeValues(dowSpans, func(v int) string { return time.Weekday(v).String() })
+	default:
+		dowFrag = "on " + describeValues(dowSpans, func(v int) string { return time.Weekday(v).String() })
+	}
+
+	switch {
+	case domFrag == "":
+		return dowFrag
+	case dowFrag == "":
+		return domFrag
+	case s.Dom&starBit == 0 && s.Dow&(starBit|strictBit) == 0:
+		return domFrag + " or " + dowFrag
+	}
+	return domFrag + ", " + dowFrag
+}
+
+// describeMonths returns the fragment for the month field, such as "in
+// January through March".
+func describeMonths(s *SpecSchedule) string {
+	spans := fieldSpans(s.Month, months)
+	switch {
+	case isFull(spans, months):
+		return ""
+	case len(spans) == 1 && spans[0].step > 1 && spans[0].start == months.min:
+		return "every " + strconv.Itoa(int(spans[0].step)) + " months"
+	}
+	return "in " + describeValues(spans, func(v int) string { return time.Month(v).String() })
+}
+
+// describeUnit describes the values of a time field, such as "every 5
+// minutes", "at minute 30" or "at seconds 0 through 10 and 30".
+func describeUnit(spans []fieldSpan, r bounds, unit string) string {
+	if len(spans) == 1 && spans[0].step > 1 {
+		desc := "every " + strconv.Itoa(int(spans[0].step)) + " " + unit + "s"
+		if spans[0].start != r.min {
+			desc += " from " + unit + " " + strconv.Itoa(int(spans[0].start))
+		}
+		return desc
+	}
+	if isFull(spans, r) {
+		return "every " + unit
+	}
+	if !singleValue(spans) {
+		unit += "s"
+	}
+	return "at " + unit + " " + describeValues(spans, strconv.Itoa)
+}
+
+// fieldSpan is a range of values of a field, with a step.
+type fieldSpan struct {
+	start, end, step uint
+}
+
+// fieldSpans returns the values of a field's bits as the ranges of consecutive
+// values, or as a single span with a step if they are evenly spaced until the
+// end of the field.
+func fieldSpans(bits uint64, r bounds) []fieldSpan {
+	var values []uint
+	for v := r.min; v <= r.max; v++ {
+		if bits&(1<<v) > 0 {
+			values = append(values, v)
+		}
+	}
+	if n := len(values); n >= 3 && values[1]-values[0] > 1 && values[n-1]+values[1]-values[0] > r.max {
+		step, even := values[1]-values[0], true
+		for i := 2; i < n; i++ {
+			even = even && values[i]-values[i-1] == step
+		}
+		if even {
+			return []fieldSpan{{values[0], values[n-1], step}}
+		}
+	}
+	var spans []fieldSpan
+	for _, v := range values {
+		if n := len(spans); n > 0 && spans[n-1].end+1 == v {
+			spans[n-1].end = v
+		} else {
+			spans = append(spans, fieldSpan{v, v, 1})
+		}
+	}
+	return spans
+}
+
+// describeValues lists the spans, such as "1 through 5, 10 and 20", with
+// name giving the word for each value.
+func describeValues(spans []fieldSpan, name func(int) string) string {
+	var words []string
+	for _, span := range spans {
+		switch {
+		case span.step > 1:
+			for v := span.start; v <= span.end; v += span.step {
+				words = append(words, name(int(v)))
+			}
+		case span.start == span.end:
+			words = append(words, name(int(span.start)))
+		default:
+			words = append(words, name(int(span.start))+" through "+name(int(span.end)))
+		}
+	}
+	return joinWords(words)
+}
+
+// isFull returns true if the spans cover the whole field.
+func isFull(spans []fieldSpan, r bounds) bool {
+	return len(spans) == 1 && spans[0].step == 1 && spans[0].start == r.min && spans[0].end == r.max
+}
+
+func singleValue(spans []fieldSpan) bool {
+	return len(spans) == 1 && spans[0].start == spans[0].end
+}
+
+func singleValues(spans []fieldSpan) bool {
+	for _, span := range spans {
+		if span.start != span.end {
+			return false
+		}
+	}
+	return len(spans) > 0
+}
+
+func isValue(spans []fieldSpan, v uint) bool {
+	return singleValue(spans) && spans[0].start == v
+}
+
+// clockString returns a time of day, such as "04:30", with the seconds if
+// there are any, such as "04:30:15".
+func clockString(hour, min, sec uint) string {
+	if sec != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", hour, min, sec)
+	}
+	return fmt.Sprintf("%02d:%02d", hour, min)
+}
+
+// joinWords joins words as in "a, b and c".
+func joinWords(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// ordinal returns n as an English ordinal, such as "2nd".
+func ordinal(n uint) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(int(n)) + suffix
+}
@@ -0,0 +1,93 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		spec     string
+		opts     []ParseOption
+		expected string
+	}{
+		{"0 30 4 */2 * MON-FRI", nil, "At 04:30, on every 2nd day of the month, Monday through Friday"},
+
+		// Descriptors
+		{"@hourly", nil, "Every hour"},
+		{"@daily", nil, "At 00:00"},
+		{"@weekly", nil, "At 00:00, on Sunday"},
+		{"@monthly", nil, "At 00:00, on day 1 of the month"},
+		{"@yearly", nil, "At 00:00, on day 1 of the month, in January"},
+		{"@every 90m", nil, "Every 1h30m0s"},
+		{"@reboot", nil, "Once, when the Cron starts"},
+		{"@at 2025-07-01T09:00:00+02:00", nil, "Once, at 2025-07-01T09:00:00+02:00"},
+
+		// Time of day
+		{"* * * * * *", nil, "Every second"},
+		{"0 * * * * *", nil, "Every minute"},
+		{"*/10 * * * * *", nil, "Every 10 seconds"},
+		{"30 * * * * *", nil, "At second 30"},
+		{"0 */5 * * * *", nil, "Every 5 minutes"},
+		{"0 5/15 * * * *", nil, "Every 15 minutes from minute 5"},
+		{"0 30 * * * *", nil, "At minute 30"},
+		{"0 0,30 * * * *", nil, "At minutes 0 and 30"},
+		{"0 0-10,30 * * * *", nil, "At minutes 0 through 10 and 30"},
+		{"0 0 */2 * * *", nil, "Every 2 hours"},
+		{"0 0 9-17 * * *", nil, "Every hour, between 09:00 and 17:59"},
+		{"0 0 9-11,14-17 * * *", nil, "Every hour, between 09:00 and 11:59 and between 14:00 and 17:59"},
+		{"0 */15 9-17 * * MON-FRI", nil, "Every 15 minutes, between 09:00 and 17:59, Monday through Friday"},
+		{"0 0 9,17 * * *", nil, "At 09:00 and 17:00"},
+		{"0 30 9,12,17 * * *", nil, "At 09:30, 12:30 and 17:30"},
+		{"15 30 4 * * *", nil, "At 04:30:15"},
+		{"30 4 * * *", []ParseOption{Minute, Hour, Dom, Month, Dow}, "At 04:30"},
+
+		// Days and months
+		{"0 0 0 1,15 * *", nil, "At 00:00, on days 1 and 15 of the month"},
+		{"0 0 0 1-7 * *", nil, "At 00:00, on days 1 through 7 of the month"},
+		{"0 0 0 13 * FRI", nil, "At 00:00, on day 13 of the month or on Friday"},
+		{"0 0 0 13 * FRI", []ParseOption{Second, Minute, Hour, Dom, Month, Dow, StrictDow}, "At 00:00, on day 13 of the month, on Friday"},
+		{"0 0 0 * * SAT,SUN", nil, "At 00:00, on Sunday and Saturday"},
+		{"0 0 0 * * 1,3,5", nil, "At 00:00, on Monday, Wednesday and Friday"},
+		{"0 0 12 15W * ?", nil, "At 12:00, on the weekday nearest day 15 of the month"},
+		{"0 0 23 L * ?", nil, "At 23:00, on the last day of the month"},
+		{"0 0 23 L-1 * ?", nil, "At 23:00, 1 day before the last day of the month"},
+		{"0 0 23 L-2 * MON", nil, "At 23:00, 2 days before the last day of the month or on Monday"},
+		{"0 0 9 ? * TUE#2", nil, "At 09:00, on the 2nd Tuesday of the month"},
+		{"0 0 0 1 */3 *", nil, "At 00:00, on day 1 of the month, every 3 months"},
+		{"0 0 0 1 JAN-MAR,DEC *", nil, "At 00:00, on day 1 of the month, in January through March and December"},
+		{"0 0 12 1 1 ? 2026-2028,2030", []ParseOption{Second, Minute, Hour, Dom, Month, Dow, Year},
+			"At 12:00, on day 1 of the month, in January, in 2026 through 2028 and 2030"},
+
+		// Time zones
+		{"CRON_TZ=Europe/Paris 0 0 9 * * *", nil, "At 09:00, in Europe/Paris"},
+		{"TZ=UTC @daily", nil, "At 00:00, in UTC"},
+	}
+
+	for _, test := range tests {
+		actual, err := Describe(test.spec, test.opts...)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.spec, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("%s: (expected) %q != %q (actual)", test.spec, test.expected, actual)
+		}
+	}
+}
+
+func TestDescribeErrors(t *testing.T) {
+	tests := []struct {
+		spec, err string
+	}{
+		{"0 60 * * * *", "above maximum"},
+		{"CRON_TZ=Nowhere/Special 0 0 9 * * *", "unknown time zone"},
+		{"CRON_TZ=UTC", "Missing spec"},
+		{"@sometimes", "Unrecognized descriptor"},
+	}
+	for _, test := range tests {
+		if _, err := Describe(test.spec); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s => expected %v, got %v", test.spec, test.err, err)
+		}
+	}
+}
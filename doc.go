@@ -33,10 +33,10 @@ A cron expression represents a set of times, using 6 space-separated fields.
 	Hours        | Yes        | 0-23            | * / , -
 	Day of month | Yes        | 1-31            | * / , - ?
 	Month        | Yes        | 1-12 or JAN-DEC | * / , -
-	Day of week  | Yes        | 0-6 or SUN-SAT  | * / , - ?
+	Day of week  | Yes        | 0-7 or SUN-SAT  | * / , - ?
 
 Note: Month and Day-of-week field values are case insensitive.  "SUN", "Sun",
-and "sun" are equally accepted.
+and "sun" are equally accepted.  Both 0 and 7 stand for Sunday.
 
 Special Characters
 
@@ -63,13 +63,64 @@ the 5th field (day of week) would mean Mondays, Wednesdays and Fridays.
 Hyphen ( - )
 
 Hyphens are used to define ranges. For example, 9-17 would indicate every
-hour between 9am and 5pm inclusive.
+hour between 9am and 5pm inclusive.  A range whose beginning is after its end
+wraps around the end of the field: FRI-MON stands for Friday to Monday, and
+22-2/2 in the hours field for 10pm, midnight and 2am.
 
 Question mark ( ? )
 
 Question mark may be used instead of '*' for leaving either day-of-month or
 day-of-week blank.
 
+W
+
+W after a single day of month stands for the weekday nearest to that day, in
+the same month. For example "15W" runs on the 15th, or on Friday the 14th if
+the 15th is a Saturday, or on Monday the 16th if it is a Sunday. "1W" runs on
+Monday the 3rd if the 1st is a Saturday, rather than in the previous month.
+
+L
+
+L alone in the day of month field stands for the last day of the month, and
+L-n for n days before it. For example "0 0 23 L * *" runs on January 31st,
+February 28th or 29th and April 30th, and "0 0 23 L-2 * *" on January 29th and
+February 26th or 27th. L cannot be combined with other days of month.
+
+Hash ( # )
+
+Hash between a single day of week and a number from 1 to 5 stands for that
+occurrence of the day of week in the month. For example "TUE#2" runs on the
+second Tuesday of the month, and "FRI#5" only in the months with five Fridays.
+
+Hash ( H )
+
+H stands for a value picked from a hash of a key, which spreads the entries
+having the same spec over the range of a field while keeping each of them at
+the same times across restarts. For example "0 H 3 * * *" runs at some minute
+between 03:00 and 03:59, "0 H(0-29) 3 * * *" at some minute in the first half
+hour, and "0 H/15 * * * *" every 15 minutes from some minute of the first
+quarter hour. H in the day of month field picks a day from 1 to 28. The key is
+the name of the entry, or its key or spec if it has no name, prefixed with the
+key set with WithHashKey; Parser.WithHashKey sets it when parsing directly. The
+String of the schedule shows the picked values.
+
+Day of month and day of week
+
+If both day-of-month and day-of-week are restricted (neither is '*' or '?'),
+the expression matches days satisfying either field, as in Vixie cron. For
+example "0 0 0 13 * 5" runs on every 13th and on every Friday. Parsers created
+with the StrictDow option require both fields to match instead, so the same
+expression only runs on Friday the 13th. A day of week field starting with &,
+as in "0 0 0 13 * &5", requires it with any parser; String writes such fields
+that way, so that the spec parses back to the same schedule.
+
+Year
+
+Parsers created with the Year option accept an optional last field for the
+year, from 1970 to 2099, as in Quartz. For example "0 0 12 1 1 ? 2030-2040/5"
+runs at noon on New Year's Day every five years from 2030. The schedule is
+then a YearSchedule.
+
 Predefined schedules
 
 You may use one of several pre-defined schedules in place of a cron expression.
@@ -81,6 +132,20 @@ You may use one of several pre-defined schedules in place of a cron expression.
 	@weekly                | Run once a week, midnight between Sat/Sun  | 0 0 0 * * 0
 	@daily (or @midnight)  | Run once a day, midnight                   | 0 0 0 * * *
 	@hourly                | Run once an hour, beginning of hour        | 0 0 * * * *
+	@reboot                | Run once each time the Cron starts         |
+
+An @reboot entry also runs once when it is added to a running Cron, and its
+Next time is always zero.
+
+More descriptors can be registered on a Parser with RegisterDescriptor, such
+as "@end-of-month", and used by a Cron created with WithParser.
+
+One-shots
+
+"@at" followed by an RFC 3339 time, such as "@at 2025-07-01T09:00:00+02:00",
+runs once at that time, like a OneShotSchedule returned by At. The entry is
+removed once the time has passed. A time that has already passed when the
+entry is added is not an error, but the entry never runs.
 
 Intervals
 
@@ -99,13 +164,67 @@ Note: The interval does not take the job runtime into account.  For example,
 if a job takes 3 minutes to run, and it is scheduled to run every 5 minutes,
 it will have only 2 minutes of idle time between each run.
 
+Intervals are rounded to the second, unless the Cron is created with
+WithSubsecondPrecision (or the spec is parsed with SubsecondPrecision), in
+which case "@every 250ms" activates four times a second.
+
+"@every-aligned <duration>" activates at the multiples of the duration on the
+wall clock since midnight, in the location of the entry, rather than since the
+entry was added: "@every-aligned 15m" runs at 10:15, 10:30, 10:45 and so on,
+whenever the Cron is started. A duration that does not divide a day starts
+again from each midnight.
+
+A range of durations separated by a tilde, such as "@every 5m~10m", waits for
+a delay drawn at random from that range after each activation, like the
+RandomDelaySchedule returned by EveryRange.
+
+"@after <duration>" waits for the duration after each run completes, like the
+AfterCompletionSchedule returned by AfterCompletion: a job taking 3 minutes
+on "@after 5m" starts every 8 minutes, and its runs never overlap.
+
+Backoff returns a BackoffSchedule whose delay grows after each activation up
+to a maximum, such as from 10 seconds, doubling up to 10 minutes, for polling
+an idle system. Its Reset brings the delay back to the initial one, and
+Cron.ResetBackoff also reschedules the entry for it.
+
+Windows
+
+Between restricts a schedule to the activations falling in a Window of wall
+clock times on some days of the week, such as from 09:00 to 17:00 on weekdays.
+An interval clipped to a window starts again from the start of the window.
+
+Exclude removes the activations of a schedule excluded by a Calendar, such as
+a DateCalendar of holidays, which can be updated while the Cron is running.
+
+Union combines schedules into one activated at the activations of any of them,
+such as every hour on weekdays and at 08:00 on Saturdays, and Intersect into
+one activated only when both of two schedules are, such as on the 1st of the
+month if it is a Monday. Either can be used with Schedule like any other
+schedule, and wrapped with Between or Exclude.
+
 Time zones
 
 All interpretation and scheduling is done in the machine's local time zone (as
 provided by the Go time package (http://www.golang.org/pkg/time).
 
 Be aware that jobs scheduled during daylight-savings leap-ahead transitions will
-not be run!
+not be run, and that jobs scheduled during leap-back transitions will be run
+twice, unless set otherwise with WithDSTPolicy!
+
+Contexts
+
+A job implementing JobWithContext, such as a func added with AddFuncCtx, is
+run with a context that is done once the Cron is stopped or the entry is
+removed, or at the deadline set with WithTimeout. EntryFromContext returns the
+entry of the run from that context, for logging.
+
+Missed runs
+
+If Cron wakes up after several activations of an entry have passed, for example
+because the machine was suspended, the entry is not run for them by default,
+and runs again from its next activation. WithMissedRunPolicy makes it run once
+for all of them (RunOnce), or once for each of them (RunAll), up to 100 runs
+unless set with WithMissedRunLimit.
 
 Thread safety
 
@@ -115,15 +234,21 @@ care must be taken to ensure proper synchronization.
 All cron methods are designed to be correctly synchronized as long as the caller
 ensures that invocations have a clear happens-before ordering between them.
 
+Jobs, job wrappers, hooks and error handlers may add, remove, update, pause
+and list the entries of the running Cron: no run is started while the entries
+are locked, and the changes are taken into account by the next wake at the
+latest.
+
 Implementation
 
-Cron entries are stored in an array, sorted by their next activation time.  Cron
-sleeps until the next job is due to be run.
+Cron entries are stored in a min-heap, ordered by their next activation time.
+Cron sleeps until the next job is due to be run.
 
 Upon waking:
- - it runs each entry that is active on that second
+ - it runs each entry that is active on that second, by decreasing priority
+   then in the order the entries were added
  - it calculates the next run times for the jobs that were run
- - it re-sorts the array of entries by next activation time.
+ - it moves these entries down the heap to their new place.
  - it goes to sleep until the soonest job.
 */
 package cron
@@ -0,0 +1,134 @@
+package cron
+
+import "time"
+
+// DSTPolicy is how the activations of a spec schedule falling in a daylight
+// saving time transition are handled. Policies can be combined with |.
+type DSTPolicy int
+
+const (
+	// SkipNonexistent skips the activations at wall clock times that do not
+	// exist because the clocks were set forward, and runs the activations at
+	// wall clock times that happen twice because the clocks were set back
+	// each time. This is the default.
+	SkipNonexistent DSTPolicy = 0
+
+	// RunAtNextValid runs the activations at wall clock times that do not
+	// exist shifted by the length of the transition: with a spring forward from
+	// 02:00 to 03:00, a 02:30 activation runs at 03:30.
+	RunAtNextValid DSTPolicy = 1 << iota
+
+	// FireOnceOnRepeat runs the activations at wall clock times that happen
+	// twice only the first time.
+	FireOnceOnRepeat
+)
+
+// nextWithDST returns the next activation of the schedule after t, handling
+// the activations in daylight saving time transitions according to policy.
+// Only spec schedules are affected, as they are the ones following the wall
+// clock.
+func nextWithDST(s Schedule, t time.Time, policy DSTPolicy) time.Time {
+	if _, ok := s.(*SpecSchedule); !ok || policy == SkipNonexistent {
+		return s.Next(t)
+	}
+	for {
+		next := s.Next(t)
+		if policy&RunAtNextValid > 0 {
+			if shifted := nextInGap(s, t, next); !shifted.IsZero() {
+				next = shifted
+			}
+		}
+		if next.IsZero() || policy&FireOnceOnRepeat == 0 {
+			return next
+		}
+		end := repeatEnd(next)
+		if end.IsZero() {
+			return next
+		}
+		// Skip the rest of the repeated wall clock times.
+		t = end.Add(-time.Second)
+	}
+}
+
+// nextInGap returns the first activation of the schedule after t, and before
+// next unless it is zero, at a wall clock time skipped by a spring forward, as
+// a time shifted by the length of the transition. It returns the zero time if
+// there is none.
+func nextInGap(s Schedule, t, next time.Time) time.Time {
+	to := next
+	if to.IsZero() {
+		to = t.AddDate(5, 0, 0)
+	}
+	for _, tr := range zoneTransitions(t, to) {
+		if tr.after <= tr.before {
+			continue
+		}
+		// The skipped wall clock times exist in a zone with the offset from
+		// before the transition.
+		from := tr.at.Add(-time.Second)
+		if from.Before(t) {
+			from = t
+		}
+		gap := s.Next(from.In(time.FixedZone("", tr.before)))
+		end := tr.at.Add(time.Duration(tr.after-tr.before) * time.Second)
+		if !gap.IsZero() && gap.Before(end) && (next.IsZero() || gap.Before(next)) {
+			return gap.In(t.Location())
+		}
+	}
+	return time.Time{}
+}
+
+// repeatEnd returns the end of the repeated wall clock times t is in, if t is
+// the second time its wall clock time happens because of a fall back, or the
+// zero time otherwise.
+func repeatEnd(t time.Time) time.Time {
+	trs := zoneTransitions(t.Add(-24*time.Hour), t)
+	if len(trs) == 0 {
+		return time.Time{}
+	}
+	tr := trs[len(trs)-1]
+	if tr.after >= tr.before {
+		return time.Time{}
+	}
+	end := tr.at.Add(time.Duration(tr.before-tr.after) * time.Second)
+	if !t.Before(end) {
+		return time.Time{}
+	}
+	return end
+}
+
+// zoneTransition is a change of the offset of a location.
+type zoneTransition struct {
+	at            time.Time // The first instant with the new offset.
+	before, after int       // The offsets, in seconds east of UTC.
+}
+
+// zoneTransitions returns the transitions of the location of from, in
+// (from, to]. Transitions are assumed to be more than a day apart.
+func zoneTransitions(from, to time.Time) []zoneTransition {
+	var trs []zoneTransition
+	_, offset := from.Zone()
+	for lo := from; lo.Before(to); {
+		hi := lo.Add(24 * time.Hour)
+		if hi.After(to) {
+			hi = to
+		}
+		if _, next := hi.Zone(); next != offset {
+			// Search for the transition, which happens on a whole second.
+			l, h := lo, hi
+			for h.Sub(l) > time.Second {
+				m := l.Add(h.Sub(l) / 2)
+				if _, o := m.Zone(); o == offset {
+					l = m
+				} else {
+					h = m
+				}
+			}
+			at := l.Truncate(time.Second).Add(time.Second)
+			trs = append(trs, zoneTransition{at, offset, next})
+			offset = next
+		}
+		lo = hi
+	}
+	return trs
+}
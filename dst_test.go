@@ -0,0 +1,81 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextWithDST(t *testing.T) {
+	tests := []struct {
+		zone     string
+		spec     string
+		from     string
+		policy   DSTPolicy
+		expected string
+	}{
+		// US spring forward: 02:00 EST -> 03:00 EDT on Sun Mar 11 2012.
+		{"America/New_York", "0 30 2 * * ?", "2012-03-11T00:00:00-05:00", SkipNonexistent, "2012-03-12T02:30:00-04:00"},
+		{"America/New_York", "0 30 2 * * ?", "2012-03-11T00:00:00-05:00", RunAtNextValid, "2012-03-11T03:30:00-04:00"},
+		{"America/New_York", "0 30 2 * * ?", "2012-03-11T03:30:00-04:00", RunAtNextValid, "2012-03-12T02:30:00-04:00"},
+		{"America/New_York", "0 0,30 * * * ?", "2012-03-11T01:30:00-05:00", RunAtNextValid, "2012-03-11T03:00:00-04:00"},
+		{"America/New_York", "0 0,30 * * * ?", "2012-03-11T03:00:00-04:00", RunAtNextValid, "2012-03-11T03:30:00-04:00"},
+		{"America/New_York", "0 30 3 * * ?", "2012-03-11T00:00:00-05:00", RunAtNextValid, "2012-03-11T03:30:00-04:00"},
+
+		// US fall back: 02:00 EDT -> 01:00 EST on Sun Nov 4 2012.
+		{"America/New_York", "0 30 1 * * ?", "2012-11-04T00:00:00-04:00", SkipNonexistent, "2012-11-04T01:30:00-04:00"},
+		{"America/New_York", "0 30 1 * * ?", "2012-11-04T01:30:00-04:00", SkipNonexistent, "2012-11-04T01:30:00-05:00"},
+		{"America/New_York", "0 30 1 * * ?", "2012-11-04T00:00:00-04:00", FireOnceOnRepeat, "2012-11-04T01:30:00-04:00"},
+		{"America/New_York", "0 30 1 * * ?", "2012-11-04T01:30:00-04:00", FireOnceOnRepeat, "2012-11-05T01:30:00-05:00"},
+		{"America/New_York", "0 0,30 * * * ?", "2012-11-04T01:30:00-04:00", FireOnceOnRepeat, "2012-11-04T02:00:00-05:00"},
+		{"America/New_York", "0 0,30 * * * ?", "2012-11-04T01:30:00-04:00", RunAtNextValid | FireOnceOnRepeat, "2012-11-04T02:00:00-05:00"},
+
+		// EU spring forward: 02:00 CET -> 03:00 CEST on Sun Mar 25 2012.
+		{"Europe/Berlin", "0 30 2 * * ?", "2012-03-25T00:00:00+01:00", SkipNonexistent, "2012-03-26T02:30:00+02:00"},
+		{"Europe/Berlin", "0 30 2 * * ?", "2012-03-25T00:00:00+01:00", RunAtNextValid, "2012-03-25T03:30:00+02:00"},
+		{"Europe/London", "0 30 1 * * ?", "2012-03-25T00:00:00+00:00", RunAtNextValid, "2012-03-25T02:30:00+01:00"},
+
+		// EU fall back: 03:00 CEST -> 02:00 CET on Sun Oct 28 2012.
+		{"Europe/Berlin", "0 30 2 * * ?", "2012-10-28T02:30:00+02:00", SkipNonexistent, "2012-10-28T02:30:00+01:00"},
+		{"Europe/Berlin", "0 30 2 * * ?", "2012-10-28T02:30:00+02:00", FireOnceOnRepeat, "2012-10-29T02:30:00+01:00"},
+		{"Europe/London", "0 30 1 * * ?", "2012-10-28T01:30:00+01:00", FireOnceOnRepeat, "2012-10-29T01:30:00+00:00"},
+
+		// Southern hemisphere: 02:00 AEST -> 03:00 AEDT on Sun Oct 7 2012, and
+		// 03:00 AEDT -> 02:00 AEST on Sun Apr 1 2012.
+		{"Australia/Sydney", "0 30 2 * * ?", "2012-10-07T00:00:00+10:00", RunAtNextValid, "2012-10-07T03:30:00+11:00"},
+		{"Australia/Sydney", "0 30 2 * * ?", "2012-04-01T02:30:00+11:00", SkipNonexistent, "2012-04-01T02:30:00+10:00"},
+		{"Australia/Sydney", "0 30 2 * * ?", "2012-04-01T02:30:00+11:00", FireOnceOnRepeat, "2012-04-02T02:30:00+10:00"},
+
+		// Days without transitions are unaffected.
+		{"America/New_York", "0 30 2 * * ?", "2012-06-01T00:00:00-04:00", RunAtNextValid | FireOnceOnRepeat, "2012-06-01T02:30:00-04:00"},
+	}
+
+	for _, c := range tests {
+		loc, err := time.LoadLocation(c.zone)
+		if err != nil {
+			t.Skipf("time zone %s not available: %v", c.zone, err)
+		}
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, _ := time.Parse(time.RFC3339, c.from)
+		expected, _ := time.Parse(time.RFC3339, c.expected)
+		actual := nextWithDST(sched, from.In(loc), c.policy)
+		if !actual.Equal(expected) {
+			t.Errorf("%s, %q from %s with policy %d: (expected) %v != %v (actual)",
+				c.zone, c.spec, c.from, c.policy, expected.In(loc), actual)
+		}
+	}
+}
+
+func TestNextWithDSTConstantDelay(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone America/New_York not available")
+	}
+	from := time.Date(2012, time.November, 4, 5, 45, 0, 0, time.UTC).In(loc)
+	expected := from.Add(30 * time.Minute)
+	if actual := nextWithDST(Every(30*time.Minute), from, FireOnceOnRepeat); !actual.Equal(expected) {
+		t.Errorf("(expected) %v != %v (actual)", expected, actual)
+	}
+}
@@ -0,0 +1,169 @@
+package cron
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of an Event.
+type EventType int
+
+const (
+	// EntryAdded is sent when an entry is added.
+	EntryAdded EventType = iota + 1
+
+	// EntryRemoved is sent when an entry is removed, including after its
+	// last run.
+	EntryRemoved
+
+	// JobScheduled is sent when an activation of an entry is due, or a run is
+	// requested with RunNow, before the run is queued or its locks acquired.
+	JobScheduled
+
+	// JobStarted is sent right before each run of a job.
+	JobStarted
+
+	// JobCompleted is sent after each run of a job.
+	JobCompleted
+
+	// JobSkipped is sent when an activation of an entry is not run.
+	JobSkipped
+
+	// SchedulerStarted and SchedulerStopped are sent when the Cron is started
+	// and stopped.
+	SchedulerStarted
+	SchedulerStopped
+
+	// EntryDisabled is sent when an entry is paused after failing as many
+	// times in a row as set with WithDisableAfterFailures, with the error of
+	// the last run.
+	EntryDisabled
+)
+
+var eventTypeNames = map[EventType]string{
+	EntryAdded:       "EntryAdded",
+	EntryRemoved:     "EntryRemoved",
+	JobScheduled:     "JobScheduled",
+	JobStarted:       "JobStarted",
+	JobCompleted:     "JobCompleted",
+	JobSkipped:       "JobSkipped",
+	SchedulerStarted: "SchedulerStarted",
+	SchedulerStopped: "SchedulerStopped",
+	EntryDisabled:    "EntryDisabled",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return "EventType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Event is something that happened to a Cron or one of its entries, as sent
+// to the channels returned by Subscribe.
+type Event struct {
+	Type EventType
+
+	// The entry the event is about, its string ID and its name. The ID is 0
+	// for the events of the scheduler.
+	EntryID  EntryID
+	StringID string
+	Name     string
+
+	// The time the event happened.
+	Time time.Time
+
+	// The activation time of the run, for the events of the runs.
+	Activation time.Time
+
+	// How long the run took and the error it failed with, for JobCompleted.
+	Duration time.Duration
+	Err      error
+
+	// The number of events dropped right before this one because the
+	// subscriber was too slow to receive them.
+	Dropped uint64
+}
+
+// eventBus sends the events of a Cron to its subscribers.
+type eventBus struct {
+	mu   sync.RWMutex
+	subs map[*subscriber]bool
+}
+
+type subscriber struct {
+	mu      sync.Mutex
+	ch      chan Event
+	dropped uint64 // Number of events dropped since the last one sent.
+}
+
+// Subscribe returns a channel receiving the events of the Cron from now on,
+// and the func to call to stop receiving them, which closes the channel. At
+// most buffer events are kept for a subscriber not receiving them: the oldest
+// ones are dropped to make room for the new ones, and counted in the Dropped
+// field of the next event sent. Sending events never blocks the Cron.
+func (c *Cron) Subscribe(buffer int) (<-chan Event, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	sub := &subscriber{ch: make(chan Event, buffer)}
+	c.events.mu.Lock()
+	if c.events.subs == nil {
+		c.events.subs = make(map[*subscriber]bool)
+	}
+	c.events.subs[sub] = true
+	c.events.mu.Unlock()
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			c.events.mu.Lock()
+			defer c.events.mu.Unlock()
+			delete(c.events.subs, sub)
+			close(sub.ch)
+		})
+	}
+}
+
+// publish sends the event to the subscribers, dropping their oldest events if
+// their buffer is full.
+func (b *eventBus) publish(ev Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		sub.send(ev)
+	}
+}
+
+func (s *subscriber) send(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		ev.Dropped = s.dropped
+		select {
+		case s.ch <- ev:
+			s.dropped = 0
+			return
+		default:
+		}
+		select {
+		case old := <-s.ch:
+			s.dropped += old.Dropped + 1
+		default:
+		}
+	}
+}
+
+// event publishes an event about the given entry.
+func (c *Cron) event(typ EventType, e *Entry, ev Event) {
+	ev.Type = typ
+	if e != nil {
+		ev.EntryID = e.ID
+		ev.StringID = e.StringID
+		ev.Name = e.Name
+	}
+	if ev.Time.IsZero() {
+		ev.Time = c.clock.Now()
+	}
+	c.events.publish(ev)
+}
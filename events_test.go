@@ -0,0 +1,120 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+// receive returns the next event of the channel, failing the test if there is
+// none within a second.
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(OneSecond):
+		t.Fatal("expected an event")
+		return Event{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithErrorHandler(func(EntryID, error) {}))
+	events, unsubscribe := cron.Subscribe(10)
+	defer unsubscribe()
+	others, unsubscribeOthers := cron.Subscribe(10)
+	defer unsubscribeOthers()
+
+	id, _ := cron.AddFuncE("@every 1m", func() error { return errors.New("failed") }, WithName("job"))
+	cron.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	expected := []EventType{EntryAdded, SchedulerStarted, JobScheduled, JobStarted, JobCompleted}
+	var got []Event
+	for range expected {
+		got = append(got, receive(t, events))
+	}
+	cron.Remove(id)
+	cron.Stop()
+	got = append(got, receive(t, events), receive(t, events))
+	expected = append(expected, EntryRemoved, SchedulerStopped)
+
+	for i, ev := range got {
+		assert.Equal(t, expected[i], ev.Type)
+		assert.Equal(t, uint64(0), ev.Dropped)
+	}
+	activation := clock.Now()
+	for _, ev := range got[2:5] {
+		assert.Equal(t, id, ev.EntryID)
+		assert.Equal(t, "job", ev.Name)
+		assert.Equal(t, "e-1", ev.StringID)
+		assert.Equal(t, activation, ev.Activation)
+	}
+	assert.Equal(t, "failed", got[4].Err.Error())
+	assert.Equal(t, EntryID(0), got[1].EntryID)
+
+	// Each subscriber gets every event.
+	for _, typ := range expected {
+		assert.Equal(t, typ, receive(t, others).Type)
+	}
+}
+
+func TestSubscribeSkipped(t *testing.T) {
+	cron := New(clockwork.NewFakeClock(), WithEntryLocker(denyLocker{}))
+	events, unsubscribe := cron.Subscribe(10)
+	defer unsubscribe()
+	id, _ := cron.AddFunc("@every 1m", func() {})
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	for _, typ := range []EventType{EntryAdded, JobScheduled, JobSkipped} {
+		assert.Equal(t, typ, receive(t, events).Type)
+	}
+}
+
+func TestSubscribeSlow(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	events, unsubscribe := cron.Subscribe(2)
+	defer unsubscribe()
+	var ids []EntryID
+	for i := 0; i < 5; i++ {
+		id, _ := cron.AddFunc("@every 1m", func() {})
+		ids = append(ids, id)
+	}
+
+	// The oldest events were dropped, and counted.
+	first, second := receive(t, events), receive(t, events)
+	assert.Equal(t, ids[3], first.EntryID)
+	assert.Equal(t, ids[4], second.EntryID)
+	assert.Equal(t, uint64(3), first.Dropped+second.Dropped)
+	cron.Remove(ids[0])
+	assert.Equal(t, uint64(0), receive(t, events).Dropped)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	cron := New(clockwork.NewFakeClock())
+	events, unsubscribe := cron.Subscribe(10)
+	cron.AddFunc("@every 1m", func() {})
+	unsubscribe()
+	unsubscribe()
+	cron.AddFunc("@every 1m", func() {})
+
+	// The event sent before is still received, then the channel is closed.
+	assert.Equal(t, EntryAdded, receive(t, events).Type)
+	_, ok := <-events
+	assert.False(t, ok, "expected the channel to be closed")
+	assert.Equal(t, 0, len(cron.events.subs))
+	assert.Equal(t, goroutines, runtime.NumGoroutine())
+}
+
+func TestEventTypeString(t *testing.T) {
+	assert.Equal(t, "JobCompleted", JobCompleted.String())
+	assert.Equal(t, "EventType(42)", EventType(42).String())
+}
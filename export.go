@@ -0,0 +1,229 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EntrySpec is the serializable definition of an entry: the spec it runs on,
+// the key of its job, its tags and whether it is paused. It does not carry the
+// job itself.
+type EntrySpec struct {
+	Key    string   `json:"key"`
+	Spec   string   `json:"spec"`
+	Tags   []string `json:"tags,omitempty"`
+	Paused bool     `json:"paused,omitempty"`
+}
+
+// Export returns the definitions of the cron entries, in the order they were
+// added. Entries added with a Schedule rather than a spec can't be represented
+// and are left out.
+func (c *Cron) Export() []EntrySpec {
+	entries := c.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	var specs []EntrySpec
+	for _, e := range entries {
+		if e.Spec == "" {
+			continue
+		}
+		specs = append(specs, EntrySpec{Key: e.Key, Spec: e.Spec, Tags: e.Tags, Paused: e.Paused})
+	}
+	return specs
+}
+
+// ImportFunc adds an entry for each of the given definitions, as returned by
+// Export. resolve maps the key of each definition to the func to run.
+//
+// Every definition is validated before anything is added: if a spec fails to
+// parse or a key can't be resolved, an error is returned and no entry is added.
+func (c *Cron) ImportFunc(specs []EntrySpec, resolve func(key string) func()) ([]EntryID, error) {
+	schedules := make([]Schedule, len(specs))
+	cmds := make([]func(), len(specs))
+	for i, s := range specs {
+		schedule, err := c.parse(s.Spec, s.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid spec for key %q: %s", s.Key, err)
+		}
+		cmd := resolve(s.Key)
+		if cmd == nil {
+			return nil, fmt.Errorf("No func for key %q", s.Key)
+		}
+		schedules[i], cmds[i] = schedule, cmd
+	}
+	ids := make([]EntryID, len(specs))
+	for i, s := range specs {
+		ids[i] = c.Schedule(schedules[i], FuncJob(cmds[i]), withSpec(s.Spec), WithKey(s.Key), WithTags(s.Tags...))
+		if s.Paused {
+			c.Pause(ids[i])
+		}
+	}
+	return ids, nil
+}
+
+// SnapshotEntry is an entry in the JSON document of Snapshot.
+type SnapshotEntry struct {
+	// The key of the job, passed to the resolver of Restore: the key of the
+	// entry, set with WithKey, or else its name.
+	JobKey string `json:"jobKey"`
+
+	// The string ID of the entry, if set with WithID.
+	ID string `json:"id,omitempty"`
+
+	Key  string   `json:"key,omitempty"`
+	Name string   `json:"name,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+
+	// The spec of the entry. For entries added with a Schedule, it is the
+	// spec of the schedule if it has one, such as "@every 1m0s" for
+	// Every(time.Minute).
+	Spec string `json:"spec,omitempty"`
+
+	// Whether the schedule of the entry has no spec, and the type of the
+	// schedule. These entries are left out by Restore.
+	Unserializable bool   `json:"unserializable,omitempty"`
+	Schedule       string `json:"schedule,omitempty"`
+
+	Paused bool      `json:"paused,omitempty"`
+	Next   time.Time `json:"next"`
+	Prev   time.Time `json:"prev"`
+}
+
+// snapshot is the JSON document of Snapshot.
+type snapshot struct {
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+// Snapshot returns a JSON document describing the entries, in the order they
+// were added, to be given to Restore, such as by the next process after a
+// deploy. The jobs themselves are not part of it, only their keys.
+func (c *Cron) Snapshot() ([]byte, error) {
+	entries := c.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	snap := snapshot{Entries: []SnapshotEntry{}}
+	for _, e := range entries {
+		s := SnapshotEntry{
+			JobKey: e.Key,
+			Key:    e.Key,
+			Name:   e.Name,
+			Tags:   e.Tags,
+			Spec:   e.Spec,
+			Paused: e.Paused,
+			Next:   e.Next,
+			Prev:   e.Prev,
+		}
+		if s.JobKey == "" {
+			s.JobKey = e.Name
+		}
+		if e.customID {
+			s.ID = e.StringID
+		}
+		if s.Spec == "" {
+			s.Spec = c.scheduleSpec(e.Schedule)
+		}
+		if s.Spec == "" {
+			s.Unserializable = true
+			s.Schedule = fmt.Sprintf("%T", e.Schedule)
+		}
+		snap.Entries = append(snap.Entries, s)
+	}
+	return json.Marshal(snap)
+}
+
+// scheduleSpec returns the spec of the schedule, if it has a String method
+// returning a spec that the parser of the Cron parses back to an equal
+// schedule, or else an empty string.
+func (c *Cron) scheduleSpec(schedule Schedule) string {
+	str, ok := schedule.(fmt.Stringer)
+	if !ok {
+		return ""
+	}
+	eq, ok := schedule.(interface{ Equal(Schedule) bool })
+	if !ok {
+		return ""
+	}
+	spec := str.String()
+	if parsed, err := c.parser.Parse(spec); err != nil || !eq.Equal(parsed) {
+		return ""
+	}
+	return spec
+}
+
+// RestoreFailure is an entry that Restore failed to restore, and why.
+type RestoreFailure struct {
+	Entry SnapshotEntry
+	Err   error
+}
+
+// RestoreError is the error of Restore when some of the entries could not be
+// restored.
+type RestoreError struct {
+	Failures []RestoreFailure
+}
+
+func (e *RestoreError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = fmt.Sprintf("%q: %s", f.Entry.JobKey, f.Err)
+	}
+	return fmt.Sprintf("cron: failed to restore %d entries: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Restore adds an entry for each of the entries described by data, as returned
+// by Snapshot, with their specs, names, tags, paused state and previous run
+// times. resolve returns the job of each entry from its job key. The next run
+// times are computed again.
+//
+// The entries whose specs fail to parse, whose jobs can't be resolved, or that
+// were flagged as unserializable are left out, and reported together in a
+// *RestoreError; the other ones are restored, and their IDs returned.
+func (c *Cron) Restore(data []byte, resolve func(jobKey string) (Job, error)) ([]EntryID, error) {
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	var ids []EntryID
+	var failed []RestoreFailure
+	fail := func(s SnapshotEntry, err error) {
+		failed = append(failed, RestoreFailure{s, err})
+	}
+	for _, s := range snap.Entries {
+		if s.Unserializable {
+			fail(s, fmt.Errorf("Unserializable schedule %s", s.Schedule))
+			continue
+		}
+		key := s.Name
+		if key == "" {
+			key = s.Key
+		}
+		schedule, err := c.parse(s.Spec, key)
+		if err != nil {
+			fail(s, fmt.Errorf("Invalid spec: %s", err))
+			continue
+		}
+		job, err := resolve(s.JobKey)
+		if err == nil && job == nil {
+			err = errors.New("No job")
+		}
+		if err != nil {
+			fail(s, err)
+			continue
+		}
+		id, err := c.schedule(schedule, job, []EntryOption{withSpec(s.Spec), WithKey(s.Key), WithName(s.Name), WithTags(s.Tags...), WithID(s.ID), withPrev(s.Prev)})
+		if err != nil {
+			fail(s, err)
+			continue
+		}
+		if s.Paused {
+			c.Pause(id)
+		}
+		ids = append(ids, id)
+	}
+	if len(failed) > 0 {
+		return ids, &RestoreError{Failures: failed}
+	}
+	return ids, nil
+}
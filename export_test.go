@@ -0,0 +1,109 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	cron.AddFunc("0 30 * * * *", func() {}, WithKey("half-hour"))
+	cron.AddFunc("@daily", func() {}, WithKey("daily"), WithTags("report"))
+	cron.Schedule(Every(time.Minute), FuncJob(func() {}))
+	cron.Pause(1)
+
+	data, err := json.Marshal(cron.Export())
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"key":"half-hour","spec":"0 30 * * * *","paused":true},{"key":"daily","spec":"@daily","tags":["report"]}]`, string(data))
+
+	var specs []EntrySpec
+	assert.NoError(t, json.Unmarshal(data, &specs))
+	restored := New(clockwork.NewFakeClock())
+	resolved := map[string]bool{}
+	ids, err := restored.ImportFunc(specs, func(key string) func() {
+		resolved[key] = true
+		return func() {}
+	})
+	assert.NoError(t, err)
+	assert.Len(t, ids, 2)
+	assert.Equal(t, map[string]bool{"half-hour": true, "daily": true}, resolved)
+	assert.Equal(t, cron.Export(), restored.Export())
+}
+
+func TestImportFuncErrors(t *testing.T) {
+	cron := New(clockwork.NewFakeClock())
+	_, err := cron.ImportFunc([]EntrySpec{{Key: "a", Spec: "@daily"}, {Key: "b", Spec: "bogus"}},
+		func(string) func() { return func() {} })
+	assert.Error(t, err)
+	_, err = cron.ImportFunc([]EntrySpec{{Key: "a", Spec: "@daily"}},
+		func(string) func() { return nil })
+	assert.Error(t, err)
+	assert.Len(t, cron.Entries(), 0)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	report, _ := cron.AddFunc("0 30 * * * *", func() {}, WithName("report"), WithTags("daily", "mail"))
+	cron.AddFunc("@daily", func() {}, WithKey("cleanup"))
+	cron.Schedule(Every(5*time.Minute), FuncJob(func() {}), WithName("poll"))
+	cron.Schedule(new(ZeroSchedule), FuncJob(func() {}), WithName("custom"))
+	cron.AddFunc("H * * * *", func() {}, WithName("hashed"))
+	cron.AddFunc("@hourly", func() {}, WithName("unknown"))
+	assert.NoError(t, cron.RunNow(report))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	cron.Pause(report)
+
+	data, err := cron.Snapshot()
+	assert.NoError(t, err)
+	var snap struct{ Entries []SnapshotEntry }
+	assert.NoError(t, json.Unmarshal(data, &snap))
+	assert.Equal(t, 6, len(snap.Entries))
+	assert.Equal(t, SnapshotEntry{JobKey: "report", Name: "report", Tags: []string{"daily", "mail"},
+		Spec: "0 30 * * * *", Paused: true, Prev: clock.Now()}, snap.Entries[0])
+	assert.Equal(t, "cleanup", snap.Entries[1].JobKey)
+	assert.Equal(t, "@every 5m0s", snap.Entries[2].Spec)
+	assert.True(t, snap.Entries[3].Unserializable, "expected the custom schedule to be flagged")
+	assert.Equal(t, "*cron.ZeroSchedule", snap.Entries[3].Schedule)
+
+	restored := New(clockwork.NewFakeClock())
+	ids, err := restored.Restore(data, func(key string) (Job, error) {
+		if key == "unknown" {
+			return nil, errors.New("no such job")
+		}
+		return FuncJob(func() {}), nil
+	})
+	assert.Equal(t, 4, len(ids))
+	restoreErr, ok := err.(*RestoreError)
+	assert.True(t, ok, "expected a RestoreError")
+	assert.Equal(t, 2, len(restoreErr.Failures))
+	assert.Equal(t, "custom", restoreErr.Failures[0].Entry.JobKey)
+	assert.Equal(t, "unknown", restoreErr.Failures[1].Entry.JobKey)
+	assert.Contains(t, err.Error(), `"unknown": no such job`)
+
+	for i, id := range ids {
+		a, b := cron.Entry([]EntryID{1, 2, 3, 5}[i]), restored.Entry(id)
+		assert.Equal(t, a.Name, b.Name)
+		assert.Equal(t, a.Key, b.Key)
+		assert.Equal(t, a.Tags, b.Tags)
+		assert.Equal(t, a.Paused, b.Paused)
+		assert.Equal(t, a.Prev, b.Prev)
+		assert.True(t, a.Schedule.(interface{ Equal(Schedule) bool }).Equal(b.Schedule), "expected the schedules to be equal")
+	}
+
+	// Restoring again fails on the names already taken, but not for the
+	// unnamed entries.
+	ids, err = restored.Restore(data, func(string) (Job, error) { return FuncJob(func() {}), nil })
+	assert.Equal(t, 2, len(ids))
+	assert.Equal(t, 4, len(err.(*RestoreError).Failures))
+	assert.Equal(t, ErrDuplicateName, err.(*RestoreError).Failures[0].Err)
+
+	_, err = restored.Restore([]byte("bogus"), nil)
+	assert.Error(t, err)
+}
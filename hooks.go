@@ -0,0 +1,80 @@
+package cron
+
+import (
+	"sync"
+	"time"
+)
+
+// jobHooks holds the callbacks registered through OnJobStart, OnJobComplete
+// and OnJobSkipped.
+type jobHooks struct {
+	mu       sync.RWMutex
+	start    []func(EntryID, time.Time)
+	complete []func(EntryID, time.Time, time.Duration, error)
+	skipped  []func(EntryID, time.Time)
+}
+
+// OnJobStart registers a callback invoked right before each run of a job, with
+// the entry ID and the time the run starts. Callbacks run synchronously on the
+// job's goroutine, in registration order.
+func (c *Cron) OnJobStart(fn func(id EntryID, t time.Time)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.start = append(c.hooks.start, fn)
+}
+
+// OnJobComplete registers a callback invoked after each run of a job, with the
+// entry ID, the time the run completed and how long it took. err is non-nil
+// if the job panicked. Callbacks run synchronously on the job's goroutine, in
+// registration order.
+func (c *Cron) OnJobComplete(fn func(id EntryID, t time.Time, dur time.Duration, err error)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.complete = append(c.hooks.complete, fn)
+}
+
+// OnJobSkipped registers a callback invoked when an activation of an entry is
+// not run, e.g. because its entry lock could not be acquired. It receives the
+// entry ID and the activation time. Callbacks run in registration order.
+func (c *Cron) OnJobSkipped(fn func(id EntryID, t time.Time)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.skipped = append(c.hooks.skipped, fn)
+}
+
+func (c *Cron) jobStarted(e Entry, fireTime, t time.Time) {
+	if c.metrics != nil {
+		c.metrics.SchedulingDelay(e, t.Sub(fireTime))
+		c.metrics.JobStarted(e)
+	}
+	c.event(JobStarted, &e, Event{Time: t, Activation: fireTime})
+	c.hooks.mu.RLock()
+	defer c.hooks.mu.RUnlock()
+	for _, fn := range c.hooks.start {
+		fn(e.ID, t)
+	}
+}
+
+func (c *Cron) jobCompleted(e Entry, fireTime, t time.Time, dur time.Duration, err error) {
+	if c.metrics != nil {
+		c.metrics.JobCompleted(e, dur, err)
+	}
+	c.event(JobCompleted, &e, Event{Time: t, Activation: fireTime, Duration: dur, Err: err})
+	c.hooks.mu.RLock()
+	defer c.hooks.mu.RUnlock()
+	for _, fn := range c.hooks.complete {
+		fn(e.ID, t, dur, err)
+	}
+}
+
+func (c *Cron) jobSkipped(e Entry, t time.Time, reason string) {
+	if c.metrics != nil {
+		c.metrics.JobSkipped(e, reason)
+	}
+	c.event(JobSkipped, &e, Event{Activation: t})
+	c.hooks.mu.RLock()
+	defer c.hooks.mu.RUnlock()
+	for _, fn := range c.hooks.skipped {
+		fn(e.ID, t)
+	}
+}
@@ -0,0 +1,69 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobHooks(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock)
+	events := make(chan string, 10)
+	cron.OnJobStart(func(id EntryID, _ time.Time) { events <- "start1" })
+	cron.OnJobStart(func(id EntryID, _ time.Time) { events <- "start2" })
+	cron.OnJobComplete(func(id EntryID, _ time.Time, _ time.Duration, err error) {
+		assert.Error(t, err)
+		events <- "complete"
+	})
+	id, _ := cron.AddFunc("* * * * * ?", func() {
+		events <- "run"
+		panic("YOLO")
+	})
+	assert.Equal(t, EntryID(1), id)
+	cron.Start()
+	defer cron.Stop()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	for _, expected := range []string{"start1", "start2", "run", "complete"} {
+		select {
+		case actual := <-events:
+			assert.Equal(t, expected, actual)
+		case <-time.After(OneSecond):
+			t.Fatalf("expected %s event", expected)
+		}
+	}
+}
+
+type denyLocker struct{ err error }
+
+func (l denyLocker) Acquire(context.Context, EntryID, time.Time) (func(), bool, error) {
+	return nil, false, l.err
+}
+
+func TestJobHooksSkipped(t *testing.T) {
+	for _, locker := range []denyLocker{{}, {errors.New("unavailable")}} {
+		clock := clockwork.NewFakeClock()
+		cron := New(clock, WithEntryLocker(locker))
+		skipped := make(chan time.Time, 1)
+		cron.OnJobStart(func(EntryID, time.Time) { t.Error("expected job not to start") })
+		cron.OnJobSkipped(func(_ EntryID, fireTime time.Time) { skipped <- fireTime })
+		cron.AddFunc("* * * * * ?", func() {})
+		cron.Start()
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+
+		select {
+		case fireTime := <-skipped:
+			assert.Equal(t, clock.Now(), fireTime)
+		case <-time.After(OneSecond):
+			t.Fatal("expected skipped event")
+		}
+		cron.Stop()
+	}
+}
@@ -0,0 +1,121 @@
+package cron
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// entryJSON is the JSON shape of an entry. Times are in RFC 3339, in the
+// location of the entry, or null if zero.
+type entryJSON struct {
+	ID       EntryID   `json:"id"`
+	StringID string    `json:"stringId"`
+	Name     string    `json:"name"`
+	Key      string    `json:"key"`
+	Tags     []string  `json:"tags"`
+	Spec     string    `json:"spec"`
+	Schedule string    `json:"schedule"`
+	Location string    `json:"location"`
+	Next     *string   `json:"next"`
+	Prev     *string   `json:"prev"`
+	Paused   bool      `json:"paused"`
+	Disabled bool      `json:"disabled"`
+	Active   bool      `json:"active"`
+	Running  int       `json:"running"`
+	Stats    statsJSON `json:"stats"`
+}
+
+type statsJSON struct {
+	Runs                uint64  `json:"runs"`
+	Skipped             uint64  `json:"skipped"`
+	Replaced            uint64  `json:"replaced"`
+	ConsecutiveFailures int     `json:"consecutiveFailures"`
+	LastStart           *string `json:"lastStart"`
+	LastEnd             *string `json:"lastEnd"`
+	LastDurationMs      int64   `json:"lastDurationMs"`
+	LastQueuedMs        int64   `json:"lastQueuedMs"`
+}
+
+type cronJSON struct {
+	Running    bool        `json:"running"`
+	Location   string      `json:"location"`
+	EntryCount int         `json:"entryCount"`
+	Entries    []entryJSON `json:"entries"`
+}
+
+// MarshalJSON encodes the entry for dashboards, as an object with its id,
+// string ID, name, key, tags, spec, a description of its schedule, location,
+// next and prev times, paused, disabled and active flags, number of runs in
+// progress and stats. The job is left out.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newEntryJSON(e))
+}
+
+// MarshalJSON encodes a snapshot of the Cron for dashboards, as an object with
+// its running flag, location, number of entries and entries, sorted by ID.
+func (c *Cron) MarshalJSON() ([]byte, error) {
+	var state cronJSON
+	c.apply(func() {
+		entries := copyEntries(c.entries)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+		state = cronJSON{
+			Running:    c.running,
+			Location:   c.location.String(),
+			EntryCount: len(entries),
+			Entries:    make([]entryJSON, len(entries)),
+		}
+		for i, e := range entries {
+			state.Entries[i] = newEntryJSON(e)
+		}
+	})
+	return json.Marshal(state)
+}
+
+func newEntryJSON(e Entry) entryJSON {
+	loc := e.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	format := func(t time.Time) *string {
+		if t.IsZero() {
+			return nil
+		}
+		s := t.In(loc).Format(time.RFC3339)
+		return &s
+	}
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	schedule := ""
+	if e.Schedule != nil {
+		schedule = describeSchedule(e.Schedule)
+	}
+	return entryJSON{
+		ID:       e.ID,
+		StringID: e.StringID,
+		Name:     e.Name,
+		Key:      e.Key,
+		Tags:     tags,
+		Spec:     e.Spec,
+		Schedule: schedule,
+		Location: loc.String(),
+		Next:     format(e.Next),
+		Prev:     format(e.Prev),
+		Paused:   e.Paused,
+		Disabled: e.Disabled,
+		Active:   e.Active,
+		Running:  e.Running,
+		Stats: statsJSON{
+			Runs:                e.Stats.Runs,
+			Skipped:             e.Stats.Skipped,
+			Replaced:            e.Stats.Replaced,
+			ConsecutiveFailures: e.Stats.ConsecutiveFailures,
+			LastStart:           format(e.Stats.LastStart),
+			LastEnd:             format(e.Stats.LastEnd),
+			LastDurationMs:      e.Stats.LastDuration.Milliseconds(),
+			LastQueuedMs:        e.Stats.LastQueued.Milliseconds(),
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryMarshalJSON(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.July, 9, 18, 45, 0, 0, time.UTC))
+	cron := NewWithLocation(clock, ny)
+	id, _ := cron.AddFunc("0 30 * * * *", func() {}, WithName("report"), WithTags("daily", "mail"))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+
+	data, err := json.Marshal(cron.Entry(id))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"stringId":"e-1","name":"report","key":"","tags":["daily","mail"],"spec":"0 30 * * * *",`+
+		`"schedule":"At minute 30","location":"America/New_York","next":null,`+
+		`"prev":"2012-07-09T14:45:00-04:00","paused":false,"disabled":false,"active":false,"running":0,`+
+		`"stats":{"runs":1,"skipped":0,"replaced":0,"consecutiveFailures":0,`+
+		`"lastStart":"2012-07-09T14:45:00-04:00","lastEnd":"2012-07-09T14:45:00-04:00",`+
+		`"lastDurationMs":0,"lastQueuedMs":0}}`, string(data))
+}
+
+func TestCronMarshalJSON(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2012, time.July, 9, 14, 45, 0, 0, time.UTC))
+	cron := New(clock)
+	cron.Schedule(Every(5*time.Minute), FuncJob(func() {}), WithKey("poll"), WithID("poll"))
+	paused, _ := cron.AddFunc("@daily", func() {})
+	cron.Pause(paused)
+	cron.Start()
+	defer cron.Stop()
+
+	data, err := json.Marshal(cron)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"running":true,"location":"UTC","entryCount":2,"entries":[`+
+		`{"id":1,"stringId":"poll","name":"","key":"poll","tags":[],"spec":"","schedule":"Every 5m0s","location":"UTC",`+
+		`"next":"2012-07-09T14:50:00Z","prev":null,"paused":false,"disabled":false,"active":false,"running":0,`+
+		`"stats":{"runs":0,"skipped":0,"replaced":0,"consecutiveFailures":0,"lastStart":null,"lastEnd":null,`+
+		`"lastDurationMs":0,"lastQueuedMs":0}},`+
+		`{"id":2,"stringId":"e-2","name":"","key":"","tags":[],"spec":"@daily","schedule":"At 00:00","location":"UTC",`+
+		`"next":"2012-07-10T00:00:00Z","prev":null,"paused":true,"disabled":false,"active":false,"running":0,`+
+		`"stats":{"runs":0,"skipped":0,"replaced":0,"consecutiveFailures":0,"lastStart":null,"lastEnd":null,`+
+		`"lastDurationMs":0,"lastQueuedMs":0}}]}`, string(data))
+}
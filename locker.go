@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+)
+
+// EntryLocker coordinates runs of the same entry across several Cron
+// instances, e.g. replicas of a service backed by a shared lock store.
+//
+// Acquire is called before every run with the entry ID and the activation time
+// being run. The run is skipped unless acquired is true, and release is called
+// once it completes. Entries are identified by their EntryID, so instances
+// sharing a locker must register their entries in the same order.
+type EntryLocker interface {
+	Acquire(ctx context.Context, id EntryID, fireTime time.Time) (release func(), acquired bool, err error)
+}
+
+// MemoryEntryLocker is an EntryLocker for Cron instances within a single
+// process. Each activation of an entry is granted at most once.
+type MemoryEntryLocker struct {
+	mu      sync.Mutex
+	held    map[EntryID]bool
+	claimed map[EntryID]time.Time
+}
+
+// NewMemoryEntryLocker returns an empty MemoryEntryLocker.
+func NewMemoryEntryLocker() *MemoryEntryLocker {
+	return &MemoryEntryLocker{
+		held:    make(map[EntryID]bool),
+		claimed: make(map[EntryID]time.Time),
+	}
+}
+
+// Acquire grants the lock unless it is held, or the activation at fireTime (or
+// a later one) has already been granted.
+func (l *MemoryEntryLocker) Acquire(ctx context.Context, id EntryID, fireTime time.Time) (func(), bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[id] || !fireTime.After(l.claimed[id]) {
+		return nil, false, nil
+	}
+	l.held[id] = true
+	l.claimed[id] = fireTime
+	return func() {
+		l.mu.Lock()
+		delete(l.held, id)
+		l.mu.Unlock()
+	}, true, nil
+}
+
+// DistributedLocker is a lock store shared by several Cron instances, such as
+// one backed by Redis, set with WithDistributedLock.
+//
+// Acquire is called before every run with a key naming the activation being
+// run, and the time the lock is to be held at most. The run is skipped unless
+// ok is true, and release is called once it completes.
+type DistributedLocker interface {
+	Acquire(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error)
+}
+
+// distributedLock is the DistributedLocker of a Cron, with how its keys are
+// made.
+type distributedLock struct {
+	locker DistributedLocker
+	key    func(Entry) string
+	ttl    time.Duration
+}
+
+// activationKey returns the key of the lock of the activation of the entry at
+// fireTime: the key of the entry, then the activation time in nanoseconds, so
+// that activations less than a second apart have their own keys.
+func (l *distributedLock) activationKey(e Entry, fireTime time.Time) string {
+	key := ""
+	if l.key != nil {
+		key = l.key(e)
+	} else if e.Name != "" {
+		key = e.Name
+	} else if e.Key != "" {
+		key = e.Key
+	} else if e.customID {
+		key = e.StringID
+	} else {
+		key = strconv.FormatInt(int64(e.ID), 10)
+	}
+	return key + "/" + strconv.FormatInt(fireTime.UnixNano(), 10)
+}
+
+// MemoryDistributedLocker is a DistributedLocker for Cron instances within a
+// single process. A key stays claimed until its ttl expires, even once
+// released, so that an activation is run once even by the instances trying
+// after it completed.
+type MemoryDistributedLocker struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	expires map[string]time.Time
+}
+
+// NewMemoryDistributedLocker returns an empty MemoryDistributedLocker, whose
+// keys expire according to the given clock.
+func NewMemoryDistributedLocker(clock clockwork.Clock) *MemoryDistributedLocker {
+	return &MemoryDistributedLocker{
+		now:     clock.Now,
+		expires: make(map[string]time.Time),
+	}
+}
+
+// Acquire claims the key for ttl unless it is already claimed.
+func (l *MemoryDistributedLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for k, expires := range l.expires {
+		if !expires.After(now) {
+			delete(l.expires, k)
+		}
+	}
+	if _, ok := l.expires[key]; ok {
+		return nil, false, nil
+	}
+	l.expires[key] = now.Add(ttl)
+	return func() {}, true, nil
+}
@@ -0,0 +1,179 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryEntryLocker(t *testing.T) {
+	locker := NewMemoryEntryLocker()
+	fire := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	release, acquired, err := locker.Acquire(context.Background(), 1, fire)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Held, then already claimed once released.
+	_, acquired, _ = locker.Acquire(context.Background(), 1, fire)
+	assert.False(t, acquired)
+	release()
+	_, acquired, _ = locker.Acquire(context.Background(), 1, fire)
+	assert.False(t, acquired)
+
+	// Other entries and later activations are independent.
+	_, acquired, _ = locker.Acquire(context.Background(), 2, fire)
+	assert.True(t, acquired)
+	_, acquired, _ = locker.Acquire(context.Background(), 1, fire.Add(time.Second))
+	assert.True(t, acquired)
+}
+
+func TestEntryLockerSharedBetweenInstances(t *testing.T) {
+	locker := NewMemoryEntryLocker()
+	ran := make(chan struct{}, 2)
+	clock := clockwork.NewFakeClock()
+	var crons []*Cron
+	for i := 0; i < 2; i++ {
+		c := New(clock, WithEntryLocker(locker))
+		c.AddFunc("* * * * * ?", func() { ran <- struct{}{} })
+		crons = append(crons, c)
+	}
+	for _, c := range crons {
+		c.Start()
+		defer c.Stop()
+	}
+	clock.BlockUntil(2)
+	clock.Advance(time.Second)
+
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected one instance to run the job")
+	}
+	select {
+	case <-ran:
+		t.Fatal("expected only one instance to run the job")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemoryDistributedLocker(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	locker := NewMemoryDistributedLocker(clock)
+
+	release, ok, err := locker.Acquire(context.Background(), "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Claimed until the ttl expires, even once released.
+	release()
+	_, ok, _ = locker.Acquire(context.Background(), "a", time.Minute)
+	assert.False(t, ok)
+	_, ok, _ = locker.Acquire(context.Background(), "b", time.Minute)
+	assert.True(t, ok)
+	clock.Advance(time.Minute)
+	_, ok, _ = locker.Acquire(context.Background(), "a", time.Minute)
+	assert.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = locker.Acquire(ctx, "c", time.Minute)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestDistributedLockSharedBetweenInstances(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	locker := NewMemoryDistributedLocker(clock)
+	var runs int32
+	var crons []*Cron
+	for i := 0; i < 2; i++ {
+		c := New(clock, WithDistributedLock(locker, nil, 30*time.Second), WithErrorHandler(func(EntryID, error) {}))
+		c.AddFunc("* * * * * ?", func() { atomic.AddInt32(&runs, 1) }, WithName("job"))
+		crons = append(crons, c)
+	}
+	for _, c := range crons {
+		c.Start()
+	}
+	const n = 5
+	for i := 0; i < n; i++ {
+		clock.BlockUntil(2)
+		clock.Advance(time.Second)
+	}
+	clock.BlockUntil(2)
+	for _, c := range crons {
+		assert.NoError(t, c.Shutdown(context.Background()))
+	}
+	assert.Equal(t, int32(n), atomic.LoadInt32(&runs))
+	e0, e1 := crons[0].Entries()[0], crons[1].Entries()[0]
+	assert.Equal(t, uint64(n), e0.Stats.Runs+e1.Stats.Runs)
+	assert.Equal(t, uint64(n), e0.Stats.Skipped+e1.Stats.Skipped)
+}
+
+func TestDistributedLockSubSecondActivations(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithDistributedLock(NewMemoryDistributedLocker(clock), nil, time.Minute))
+	var runs int32
+	id := cron.Schedule(Every(time.Hour), FuncJob(func() { atomic.AddInt32(&runs, 1) }), WithName("job"))
+	assert.NoError(t, cron.RunNow(id))
+	cron.jobWaiter.Wait()
+	clock.Advance(100 * time.Millisecond)
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	assert.Equal(t, uint64(0), cron.Entry(id).Stats.Skipped)
+}
+
+func TestDistributedLockStringID(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	locker := NewMemoryDistributedLocker(clock)
+	var runs int32
+	var crons []*Cron
+	for i := 0; i < 2; i++ {
+		c := New(clock, WithDistributedLock(locker, nil, time.Minute))
+		// The int IDs of the entries differ between the instances.
+		for j := 0; j < i; j++ {
+			c.AddFunc("@daily", func() {})
+		}
+		c.Schedule(Every(time.Hour), FuncJob(func() { atomic.AddInt32(&runs, 1) }), WithID("x"))
+		crons = append(crons, c)
+	}
+	for _, c := range crons {
+		e, _ := c.EntryByStringID("x")
+		assert.NoError(t, c.RunNow(e.ID))
+	}
+	for _, c := range crons {
+		assert.NoError(t, c.Shutdown(context.Background()))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+}
+
+type failingDistributedLocker struct{}
+
+func (failingDistributedLocker) Acquire(context.Context, string, time.Duration) (func(), bool, error) {
+	return nil, false, errors.New("store down")
+}
+
+func TestDistributedLockError(t *testing.T) {
+	errs := make(chan error, 1)
+	var keys []string
+	cron := New(clockwork.NewFakeClock(),
+		WithDistributedLock(failingDistributedLocker{}, func(e Entry) string {
+			keys = append(keys, e.Key)
+			return "custom-" + e.Key
+		}, time.Minute),
+		WithErrorHandler(func(id EntryID, err error) { errs <- err }))
+	ran := false
+	id := cron.Schedule(Every(time.Hour), FuncJob(func() { ran = true }), WithKey("k"))
+	assert.NoError(t, cron.RunNow(id))
+	err := <-errs
+	assert.Contains(t, err.Error(), "store down")
+	assert.Contains(t, err.Error(), "custom-k/")
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.False(t, ran)
+	assert.Equal(t, []string{"k"}, keys)
+}
@@ -0,0 +1,63 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// logEntry logs msg at the given level, with the identity of the entry if not
+// nil, then the given attributes. It logs to the logger set with WithLogger, or
+// else to ErrorLog with the attributes as key=value pairs after the message.
+// Debug messages are only logged with the verbose flag of WithLogger.
+func (c *Cron) logEntry(level slog.Level, e *Entry, msg string, attrs ...slog.Attr) {
+	if level < slog.LevelInfo && !c.verbose {
+		return
+	}
+	if e != nil {
+		attrs = append(entryAttrs(e), attrs...)
+	}
+	if c.logger != nil {
+		c.logger.LogAttrs(context.Background(), level, msg, attrs...)
+		return
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, attr := range attrs {
+		b.WriteString(" ")
+		b.WriteString(attr.Key)
+		b.WriteString("=")
+		b.WriteString(formatValue(attr.Value))
+	}
+	logf(c.ErrorLog, "%s", b.String())
+}
+
+// entryAttrs returns the attributes identifying the entry: its ID, and its
+// string ID, name and spec if set.
+func entryAttrs(e *Entry) []slog.Attr {
+	attrs := []slog.Attr{slog.Int64("entry", int64(e.ID))}
+	if e.customID {
+		attrs = append(attrs, slog.String("id", e.StringID))
+	}
+	if e.Name != "" {
+		attrs = append(attrs, slog.String("name", e.Name))
+	}
+	if e.Spec != "" {
+		attrs = append(attrs, slog.String("spec", e.Spec))
+	}
+	return attrs
+}
+
+// formatValue formats an attribute value for ErrorLog, quoting values with
+// spaces.
+func formatValue(v slog.Value) string {
+	if v.Kind() == slog.KindTime {
+		return v.Time().Format(time.RFC3339)
+	}
+	if s := v.String(); strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return v.String()
+}
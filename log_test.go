@@ -0,0 +1,139 @@
+package cron
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordHandler is a slog.Handler keeping the records it handles.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
+
+// find returns the level and the attributes of the first record with the given
+// message, and whether there is one.
+func (h *recordHandler) find(msg string) (slog.Level, map[string]slog.Value, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := make(map[string]slog.Value)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value
+			return true
+		})
+		return r.Level, attrs, true
+	}
+	return 0, nil, false
+}
+
+func TestLogger(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	h := &recordHandler{}
+	cron := New(clock, WithLogger(slog.New(h), true))
+	id, _ := cron.AddFunc("@every 1m", func() {}, WithName("job"))
+	cron.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+
+	level, attrs, ok := h.find("cron: schedule")
+	assert.True(t, ok, "expected a schedule record")
+	assert.Equal(t, slog.LevelDebug, level)
+	assert.Equal(t, int64(id), attrs["entry"].Int64())
+	assert.Equal(t, "job", attrs["name"].String())
+	assert.Equal(t, "@every 1m", attrs["spec"].String())
+	assert.Equal(t, slog.KindTime, attrs["next"].Kind())
+
+	_, attrs, ok = h.find("cron: wake")
+	assert.True(t, ok, "expected a wake record")
+	assert.Equal(t, clock.Now(), attrs["now"].Time())
+
+	level, attrs, ok = h.find("cron: run")
+	assert.True(t, ok, "expected a run record")
+	assert.Equal(t, slog.LevelDebug, level)
+	assert.Equal(t, int64(id), attrs["entry"].Int64())
+	assert.Equal(t, clock.Now(), attrs["activation"].Time())
+	assert.Equal(t, slog.KindTime, attrs["start"].Kind())
+}
+
+func TestLoggerNotVerbose(t *testing.T) {
+	h := &recordHandler{}
+	cron := New(clockwork.NewFakeClock(), WithLogger(slog.New(h), false))
+	id, _ := cron.AddFunc("@every 1m", func() {})
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, 0, len(h.records))
+}
+
+func TestLoggerSkippedAndPanic(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	h := &recordHandler{}
+	locker := NewMemoryDistributedLocker(clock)
+	other := New(clock, WithDistributedLock(locker, nil, time.Minute))
+	cron := New(clock, WithLogger(slog.New(h), false), WithDistributedLock(locker, nil, time.Minute))
+	other.AddFunc("@every 1m", func() {}, WithName("job"))
+	id, _ := cron.AddFunc("@every 1m", func() {}, WithName("job"))
+	assert.NoError(t, other.RunNow(1))
+	assert.NoError(t, other.Shutdown(context.Background()))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+
+	level, attrs, ok := h.find("cron: skipped run: lock held by another instance")
+	assert.True(t, ok, "expected a skip record")
+	assert.Equal(t, slog.LevelInfo, level)
+	assert.Equal(t, "lock", attrs["reason"].String())
+	assert.Equal(t, "job", attrs["name"].String())
+	assert.Equal(t, clock.Now(), attrs["activation"].Time())
+
+	id, _ = cron.AddFunc("@every 1h", func() { panic("boom") }, WithName("panicking"))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	level, attrs, ok = h.find("cron: panic running job")
+	assert.True(t, ok, "expected a panic record")
+	assert.Equal(t, slog.LevelError, level)
+	assert.Equal(t, "panicking", attrs["name"].String())
+	assert.Equal(t, "boom", attrs["panic"].String())
+	assert.Contains(t, attrs["stack"].String(), "goroutine")
+}
+
+func TestErrorLogAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	cron := New(clockwork.NewFakeClock())
+	cron.ErrorLog = log.New(&buf, "", 0)
+	id, _ := cron.AddFuncE("@every 1m", func() error { return ErrTimeout }, WithName("job"))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, `cron: run failed entry=1 name=job spec="@every 1m" error="Run timed out"`+"\n", buf.String())
+
+	buf.Reset()
+	id, _ = cron.AddFuncE("@every 1m", func() error { return ErrTimeout }, WithID("sync"))
+	assert.NoError(t, cron.RunNow(id))
+	cron.jobWaiter.Wait()
+	assert.Equal(t, `cron: run failed entry=2 id=sync spec="@every 1m" error="Run timed out"`+"\n", buf.String())
+}
@@ -0,0 +1,165 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Metrics receives the events of the runs of the entries of a Cron, set with
+// WithMetrics, such as to expose them to a monitoring system. Its methods are
+// called on the goroutines of the runs, so they must be safe for concurrent
+// use and should not block.
+type Metrics interface {
+	// JobStarted is called right before each run of a job.
+	JobStarted(entry Entry)
+
+	// JobCompleted is called after each run of a job, with how long it took
+	// and the error it failed with, if any; a panicking job fails with a
+	// *PanicError. A run skipped by a wrapper, such as SkipIfStillRunning,
+	// fails with ErrSkipped, and JobSkipped is called after it.
+	JobCompleted(entry Entry, d time.Duration, err error)
+
+	// JobSkipped is called when an activation of an entry is not run, with
+	// the reason: "still running", "queue full", "delayed", "rate limited",
+	// "lock" or "dependency", or "skipped" for other jobs failing with
+	// ErrSkipped.
+	JobSkipped(entry Entry, reason string)
+
+	// SchedulingDelay is called right before each run of a job, with the
+	// delay between the activation time and the start of the run.
+	SchedulingDelay(entry Entry, delay time.Duration)
+}
+
+// NopMetrics is a Metrics that does nothing.
+type NopMetrics struct{}
+
+func (NopMetrics) JobStarted(Entry)                         {}
+func (NopMetrics) JobCompleted(Entry, time.Duration, error) {}
+func (NopMetrics) JobSkipped(Entry, string)                 {}
+func (NopMetrics) SchedulingDelay(Entry, time.Duration)     {}
+
+// EntryMetrics holds the counts of MemoryMetrics for an entry.
+type EntryMetrics struct {
+	// The number of runs started, and of the ones that succeeded, failed
+	// and panicked once done. Panicked runs are counted as failed too, and
+	// runs skipped by a wrapper only as started and skipped.
+	Started, Succeeded, Failed, Panicked uint64
+
+	// The number of runs in progress.
+	Running int64
+
+	// The number of skipped activations, by reason.
+	Skipped map[string]uint64
+
+	// The total duration of the runs done, and the longest scheduling delay.
+	Duration, MaxDelay time.Duration
+}
+
+// MemoryMetrics is a Metrics counting the events of each entry in memory, such
+// as for tests. It implements expvar.Var, so that it can be exposed with
+// expvar.Publish.
+type MemoryMetrics struct {
+	mu      sync.Mutex
+	entries map[EntryID]*EntryMetrics
+}
+
+// NewMemoryMetrics returns a MemoryMetrics with no counts.
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{entries: make(map[EntryID]*EntryMetrics)}
+}
+
+// entry returns the counts of the given entry. m.mu must be held.
+func (m *MemoryMetrics) entry(id EntryID) *EntryMetrics {
+	e := m.entries[id]
+	if e == nil {
+		e = &EntryMetrics{Skipped: make(map[string]uint64)}
+		m.entries[id] = e
+	}
+	return e
+}
+
+func (m *MemoryMetrics) JobStarted(entry Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entry(entry.ID)
+	e.Started++
+	e.Running++
+}
+
+func (m *MemoryMetrics) JobCompleted(entry Entry, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entry(entry.ID)
+	e.Running--
+	e.Duration += d
+	var panicked *PanicError
+	switch {
+	case errors.Is(err, ErrSkipped):
+	case err == nil:
+		e.Succeeded++
+	case errors.As(err, &panicked):
+		e.Panicked++
+		e.Failed++
+	default:
+		e.Failed++
+	}
+}
+
+func (m *MemoryMetrics) JobSkipped(entry Entry, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entry(entry.ID).Skipped[reason]++
+}
+
+func (m *MemoryMetrics) SchedulingDelay(entry Entry, delay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.entry(entry.ID); delay > e.MaxDelay {
+		e.MaxDelay = delay
+	}
+}
+
+// Entry returns a copy of the counts of the given entry.
+func (m *MemoryMetrics) Entry(id EntryID) EntryMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := *m.entry(id)
+	skipped := make(map[string]uint64, len(e.Skipped))
+	for reason, n := range e.Skipped {
+		skipped[reason] = n
+	}
+	e.Skipped = skipped
+	return e
+}
+
+// String returns the counts of all the entries as a JSON object keyed by entry
+// ID.
+func (m *MemoryMetrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make(map[string]*EntryMetrics, len(m.entries))
+	for id, e := range m.entries {
+		entries[strconv.FormatInt(int64(id), 10)] = e
+	}
+	b, _ := json.Marshal(entries)
+	return string(b)
+}
+
+// skipError is the error of a run skipped by a wrapper, for the given reason.
+type skipError string
+
+func (e skipError) Error() string { return ErrSkipped.Error() + ": " + string(e) }
+
+func (e skipError) Is(target error) bool { return target == ErrSkipped }
+
+// skipReason returns the reason of a run failing with ErrSkipped.
+func skipReason(err error) string {
+	var skip skipError
+	if errors.As(err, &skip) {
+		return string(skip)
+	}
+	return "skipped"
+}
@@ -0,0 +1,92 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Metrics = NopMetrics{}
+
+func TestMetrics(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	metrics := NewMemoryMetrics()
+	cron := New(clock, WithMetrics(metrics), WithErrorHandler(func(EntryID, error) {}))
+	ok, _ := cron.AddFunc("@every 1m", func() {})
+	failing, _ := cron.AddFuncE("@every 1h", func() error { return errors.New("failed") })
+	panicking, _ := cron.AddFunc("@every 1h", func() { panic("boom") })
+
+	// Scheduled runs.
+	cron.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, uint64(2), metrics.Entry(ok).Started)
+	assert.Equal(t, uint64(2), metrics.Entry(ok).Succeeded)
+	assert.Equal(t, int64(0), metrics.Entry(ok).Running)
+
+	// RunNow, failures and panics.
+	assert.NoError(t, cron.RunNow(ok))
+	assert.NoError(t, cron.RunNow(failing))
+	assert.NoError(t, cron.RunNow(panicking))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, uint64(3), metrics.Entry(ok).Succeeded)
+	assert.Equal(t, EntryMetrics{Started: 1, Failed: 1, Skipped: map[string]uint64{}}, metrics.Entry(failing))
+	assert.Equal(t, EntryMetrics{Started: 1, Failed: 1, Panicked: 1, Skipped: map[string]uint64{}}, metrics.Entry(panicking))
+
+	var counts map[string]EntryMetrics
+	assert.NoError(t, json.Unmarshal([]byte(metrics.String()), &counts))
+	assert.Equal(t, uint64(1), counts["3"].Panicked)
+}
+
+func TestMetricsSkipped(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	cron := New(clockwork.NewFakeClock(), WithMetrics(metrics))
+	started, release := make(chan struct{}), make(chan struct{})
+	id := cron.Schedule(Every(time.Hour), FuncJob(func() {
+		close(started)
+		<-release
+	}), WithChain(SkipIfStillRunning(nil)))
+	assert.NoError(t, cron.RunNow(id))
+	<-started
+	assert.NoError(t, cron.RunNow(id))
+	for metrics.Entry(id).Skipped["still running"] == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, uint64(2), metrics.Entry(id).Started)
+	assert.Equal(t, uint64(1), metrics.Entry(id).Succeeded)
+	assert.Equal(t, int64(0), metrics.Entry(id).Running)
+
+	// Wrappers failing with ErrSkipped directly.
+	id = cron.Schedule(Every(time.Hour), FuncJob(func() {}), WithChain(func(Job) Job {
+		return errorJobAdapter{FuncJobE(func() error { return ErrSkipped })}
+	}))
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, uint64(1), metrics.Entry(id).Skipped["skipped"])
+}
+
+func TestMetricsSchedulingDelay(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	metrics := NewMemoryMetrics()
+	cron := New(clock, WithMetrics(metrics), WithMaxConcurrentJobs(1))
+	release := make(chan struct{})
+	blocking := cron.Schedule(Every(time.Hour), FuncJob(func() { <-release }))
+	queued := cron.Schedule(Every(time.Hour), FuncJob(func() {}))
+	assert.NoError(t, cron.RunNow(blocking))
+	assert.NoError(t, cron.RunNow(queued))
+	clock.Advance(5 * time.Second)
+	close(release)
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, 5*time.Second, metrics.Entry(queued).MaxDelay)
+}
@@ -0,0 +1,36 @@
+package cron
+
+import "time"
+
+// OneShotSchedule is activated once, at a given time. An entry with this
+// schedule is removed once its time has passed, after running unless it is
+// paused. An entry added with a time that has already passed never runs, and
+// stays in the entries with a zero Next time.
+type OneShotSchedule struct {
+	At time.Time
+}
+
+// At returns a Schedule that activates once, at t.
+func At(t time.Time) OneShotSchedule {
+	return OneShotSchedule{At: t}
+}
+
+// Next returns the time of the schedule if it is after t, or the zero time.
+func (schedule OneShotSchedule) Next(t time.Time) time.Time {
+	if schedule.At.After(t) {
+		return schedule.At
+	}
+	return time.Time{}
+}
+
+// Equal reports whether other is a OneShotSchedule at the same instant.
+func (schedule OneShotSchedule) Equal(other Schedule) bool {
+	o, ok := other.(OneShotSchedule)
+	return ok && o.At.Equal(schedule.At)
+}
+
+// String returns the descriptor for the schedule, e.g.
+// "@at 2025-07-01T09:00:00+02:00".
+func (schedule OneShotSchedule) String() string {
+	return "@at " + schedule.At.Format(time.RFC3339)
+}
@@ -0,0 +1,465 @@
+package cron
+
+import (
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// Option represents a modification to the default behavior of a Cron.
+type Option func(*Cron)
+
+// WithEntryLocker makes the Cron acquire a lock from the given locker before
+// each run of an entry; the run is skipped if the lock is not acquired.
+func WithEntryLocker(locker EntryLocker) Option {
+	return func(c *Cron) {
+		c.locker = locker
+	}
+}
+
+// WithDistributedLock makes the Cron acquire a lock from the given locker before
+// each run of an entry, for at most ttl, so that only one of the instances
+// sharing the locker runs each activation. The key of the lock is the one
+// returned by keyFunc for the entry, or if nil the name of the entry, else its
+// key, else the string ID set with WithID, else its ID, followed by "/" and the
+// activation time as a Unix time in nanoseconds.
+// The instances must agree on the keys of their entries.
+//
+// A run is skipped and logged if the lock is held by another instance. A
+// failure to acquire the lock skips the run too, and is passed to the error
+// handler.
+func WithDistributedLock(locker DistributedLocker, keyFunc func(Entry) string, ttl time.Duration) Option {
+	return func(c *Cron) {
+		c.distLock = &distributedLock{locker: locker, key: keyFunc, ttl: ttl}
+	}
+}
+
+// WithMetrics makes the Cron report the events of the runs of its entries to
+// m. The default is to report them to nothing.
+func WithMetrics(m Metrics) Option {
+	return func(c *Cron) {
+		c.metrics = m
+	}
+}
+
+// WithLogger makes the Cron log to the given structured logger rather than to
+// ErrorLog, with the entries and the times of the runs as attributes: "entry"
+// for the entry ID, "name" and "spec" if set, and "activation" for the
+// activation time of a run. With verbose, it also logs at the debug level each
+// time it wakes up, schedules the next activation of an entry, and starts a
+// run.
+//
+// The wrappers of this package log to a *log.Logger, which can log to a
+// structured logger with slog.NewLogLogger.
+func WithLogger(logger *slog.Logger, verbose bool) Option {
+	return func(c *Cron) {
+		c.logger = logger
+		c.verbose = verbose
+	}
+}
+
+// WithStateStore makes the Cron save the activation time of each completed run
+// of its named entries to the store, and load them when it starts, so that the
+// activations missed while it was not running, such as during a restart, are
+// handled according to the missed run policy: skipped by default, or run once
+// right away with RunOnce, or each in turn with RunAll. Entries without a name
+// are not saved. The failures of the store are passed to the error handler.
+func WithStateStore(store StateStore) Option {
+	return func(c *Cron) {
+		c.store = store
+	}
+}
+
+// WithErrorHandler sets the func called with the entry ID and the error each
+// time a run fails, such as with the error of an ErrorJob; a panicking job
+// fails with a *PanicError. The func is called on the goroutine of the run, so
+// a slow handler does not delay the other entries. By default failures are
+// logged to ErrorLog.
+func WithErrorHandler(handler func(id EntryID, err error)) Option {
+	return func(c *Cron) {
+		c.onError = handler
+	}
+}
+
+// MissedRunPolicy is how a Cron handles the activations of an entry that were
+// missed because it woke up late, such as after the machine was suspended.
+type MissedRunPolicy int
+
+const (
+	// SkipMissed skips all the missed activations of an entry, which runs
+	// again from its next activation. This is the default.
+	SkipMissed MissedRunPolicy = iota
+
+	// RunOnce runs an entry once for all its missed activations.
+	RunOnce
+
+	// RunAll runs an entry for each of its missed activations, in order, up
+	// to the limit set with WithMissedRunLimit.
+	RunAll
+)
+
+// ConcurrencyPolicy is how a run of an entry is started while another run of
+// the entry is in progress.
+type ConcurrencyPolicy int
+
+const (
+	// AllowConcurrent starts the run alongside the one in progress. This is
+	// the default.
+	AllowConcurrent ConcurrencyPolicy = iota
+
+	// ForbidConcurrent skips the run, as with SkipIfStillRunning.
+	ForbidConcurrent
+
+	// ReplaceConcurrent cancels the context of the run in progress, and
+	// starts the new run once it is done, or after the grace period set with
+	// WithReplaceGracePeriod. Only jobs implementing JobWithContext observe
+	// the cancellation.
+	ReplaceConcurrent
+)
+
+// WithMissedRunPolicy sets how the Cron handles missed activations.
+func WithMissedRunPolicy(policy MissedRunPolicy) Option {
+	return func(c *Cron) {
+		c.missed = policy
+	}
+}
+
+// defaultMissedRunLimit is the number of runs of an entry for its missed
+// activations with the RunAll policy, unless set with WithMissedRunLimit.
+const defaultMissedRunLimit = 100
+
+// WithMissedRunLimit limits the number of runs of an entry for its missed
+// activations with the RunAll policy. The default is 100, and 0 or less means
+// no limit.
+func WithMissedRunLimit(n int) Option {
+	return func(c *Cron) {
+		c.missedMax = n
+	}
+}
+
+// WithDSTPolicy sets how the activations of the entries falling in daylight
+// saving time transitions are handled, unless set per entry with
+// WithDSTPolicyFor.
+func WithDSTPolicy(policy DSTPolicy) Option {
+	return func(c *Cron) {
+		c.dst = policy
+	}
+}
+
+// WithMaxConcurrentJobs limits the number of jobs running at the same time,
+// across all entries, to n. The runs over the limit are queued, and started in
+// order as running jobs complete. An n of 0 or less means no limit.
+func WithMaxConcurrentJobs(n int) Option {
+	return func(c *Cron) {
+		c.maxJobs = n
+	}
+}
+
+// WithJobQueueLimit limits the number of runs queued by WithMaxConcurrentJobs
+// to n, and sets what happens to the runs over that limit. The default is no
+// limit.
+func WithJobQueueLimit(n int, overflow OverflowPolicy) Option {
+	return func(c *Cron) {
+		c.queueMax = n
+		c.overflow = overflow
+	}
+}
+
+// WithSubsecondPrecision makes the Cron keep sub-second delays in the @every
+// descriptors of the specs it parses, such as "@every 250ms", instead of
+// rounding them to the second.
+func WithSubsecondPrecision() Option {
+	return func(c *Cron) {
+		c.parser.options |= SubsecondPrecision
+	}
+}
+
+// WithSecondsOptional makes the parser of the specs of the entries accept
+// both standard specs of 5 fields and specs of 6 fields starting with the
+// seconds, as with SecondOptional. The day of week field is then required.
+func WithSecondsOptional() Option {
+	return func(c *Cron) {
+		c.parser.options = c.parser.options&^DowOptional | SecondOptional | Second | Dow
+		c.parser.optionals = 0
+	}
+}
+
+// WithParser sets the parser of the specs of the entries, such as one with
+// descriptors registered with RegisterDescriptor. The default is the parser of
+// Parse.
+func WithParser(p Parser) Option {
+	return func(c *Cron) {
+		if p.hashKey == "" {
+			p.hashKey = c.parser.hashKey
+		}
+		c.parser = p
+	}
+}
+
+// WithHashKey sets a key, such as the name of the service, from which the
+// values of the H expressions of the specs are picked, along with the name of
+// each entry, or its key or spec if it has no name. Services running the same
+// specs with different keys run them at different times.
+func WithHashKey(key string) Option {
+	return func(c *Cron) {
+		c.parser = c.parser.WithHashKey(key)
+	}
+}
+
+// WithJitterSeed seeds the random delays of the entries added with WithJitter,
+// so that they are the same from one run to another, such as in tests.
+func WithJitterSeed(seed int64) Option {
+	return func(c *Cron) {
+		c.rand = rand.New(rand.NewSource(seed))
+		c.seed = seed
+	}
+}
+
+// WithJobWrappers wraps the jobs of all the entries with the given wrappers,
+// the first one being the outermost, around the wrappers of each entry set
+// with WithChain.
+func WithJobWrappers(wrappers ...JobWrapper) Option {
+	return func(c *Cron) {
+		c.chain = append(c.chain, wrappers...)
+	}
+}
+
+// WithRecoverHandler wraps the jobs of all the entries with RecoverWith, around
+// the other wrappers, so that handler is called with the entry ID, the value
+// and the stack of each panic of a job.
+func WithRecoverHandler(handler func(id EntryID, recovered interface{}, stack []byte), repanic bool) Option {
+	return func(c *Cron) {
+		c.chain = append([]JobWrapper{RecoverWith(handler, repanic)}, c.chain...)
+	}
+}
+
+// EntryOption represents a modification to an entry added to a Cron.
+type EntryOption func(*Entry)
+
+// WithKey sets a stable key identifying the job of the entry. Export and
+// ImportFunc use it to map an exported entry back to its command.
+func WithKey(key string) EntryOption {
+	return func(e *Entry) {
+		e.Key = key
+	}
+}
+
+// WithName names the entry, so that it can be looked up with EntryByName and
+// removed with RemoveByName. Adding an entry named after an existing entry
+// fails with ErrDuplicateName.
+func WithName(name string) EntryOption {
+	return func(e *Entry) {
+		e.Name = name
+	}
+}
+
+// WithID sets the string ID of the entry, so that it can be referred to by a
+// stable ID with EntryByStringID and RemoveByStringID, whatever the order the
+// entries are added in. Adding an entry with the string ID of an existing entry
+// fails with ErrDuplicateID, and one of the form of the generated IDs, "e-"
+// followed by digits, with ErrReservedID. An empty ID is ignored.
+func WithID(id string) EntryOption {
+	return func(e *Entry) {
+		if id != "" {
+			e.StringID = id
+			e.customID = true
+		}
+	}
+}
+
+// WithTags tags the entry, so that it can be listed with EntriesByTag and
+// removed with RemoveByTag along with the other entries having the same tag.
+func WithTags(tags ...string) EntryOption {
+	return func(e *Entry) {
+		e.Tags = append(e.Tags, tags...)
+	}
+}
+
+// WithLocationFor makes the schedule of the entry be evaluated in the given
+// time zone rather than in the location of the Cron.
+func WithLocationFor(loc *time.Location) EntryOption {
+	return func(e *Entry) {
+		e.Location = loc
+	}
+}
+
+// WithPriority sets the priority of the entry. Entries due at the same time
+// are started by decreasing priority, and in the order they were added for
+// equal priorities. The default priority is 0.
+func WithPriority(priority int) EntryOption {
+	return func(e *Entry) {
+		e.Priority = priority
+	}
+}
+
+// WithDSTPolicyFor sets how the activations of the entry falling in daylight
+// saving time transitions are handled, instead of the policy of the Cron.
+func WithDSTPolicyFor(policy DSTPolicy) EntryOption {
+	return func(e *Entry) {
+		e.dst = policy
+	}
+}
+
+// WithRunOnStart makes the entry run once right away each time the Cron starts,
+// or when it is added to a running Cron, besides its scheduled runs. This run
+// does not count towards WithMaxRuns.
+func WithRunOnStart() EntryOption {
+	return func(e *Entry) {
+		e.runOnStart = true
+	}
+}
+
+// WithJitter delays each activation of the entry by a random duration less than
+// max, drawn again for each activation, to spread the runs of entries having
+// the same schedule. An activation is never delayed past the next one. Next
+// shows the delayed time.
+func WithJitter(max time.Duration) EntryOption {
+	return func(e *Entry) {
+		e.jitter = max
+	}
+}
+
+// WithChain wraps the job of the entry with the given wrappers, the first one
+// being the outermost, inside the wrappers of the Cron set with
+// WithJobWrappers. Each entry gets its own wrapped job, so wrappers such as
+// SkipIfStillRunning do not share their state across entries.
+func WithChain(wrappers ...JobWrapper) EntryOption {
+	return func(e *Entry) {
+		e.chain = append(e.chain, wrappers...)
+	}
+}
+
+// WithConcurrencyPolicy sets how a run of the entry is started while another
+// one is in progress. Skipped and replaced runs are counted in the stats of
+// the entry.
+func WithConcurrencyPolicy(policy ConcurrencyPolicy) EntryOption {
+	return func(e *Entry) {
+		e.ConcurrencyPolicy = policy
+	}
+}
+
+// WithReplaceGracePeriod limits how long a run of the entry waits for the run
+// it replaces with ReplaceConcurrent to be done before starting anyway. The
+// default is to wait as long as it takes.
+func WithReplaceGracePeriod(d time.Duration) EntryOption {
+	return func(e *Entry) {
+		e.replaceGrace = d
+	}
+}
+
+// WithRunAfter makes each run of the entry, including the ones started with
+// RunNow, wait for a run of the given entry started at or after its activation
+// time to be done, as with a job depending on the output of another one. If
+// onlyOnSuccess is set, the run is skipped if that run failed or was skipped.
+// The run is skipped too if the entry it waits for is removed, or after the
+// wait set with WithRunAfterMaxWait.
+//
+// Adding the entry returns ErrEntryNotFound if the given entry does not exist,
+// and ErrDependencyCycle if it would depend on itself.
+func WithRunAfter(dependsOn EntryID, onlyOnSuccess bool) EntryOption {
+	return func(e *Entry) {
+		e.dependsOn = dependsOn
+		e.onlyOnSuccess = onlyOnSuccess
+	}
+}
+
+// WithRunAfterMaxWait limits how long a run of the entry waits for the entry
+// set with WithRunAfter, after which it is skipped. The default is to wait
+// until the Cron is stopped; a waiting run takes a slot of
+// WithMaxConcurrentJobs.
+func WithRunAfterMaxWait(d time.Duration) EntryOption {
+	return func(e *Entry) {
+		e.dependWait = d
+	}
+}
+
+// WithStartAt suppresses the activations of the entry before t. Its first
+// activation is the first one of its schedule at or after t; delays, such as
+// with Every, count from t.
+func WithStartAt(t time.Time) EntryOption {
+	return func(e *Entry) {
+		e.StartAt = t
+	}
+}
+
+// WithEndAt suppresses the activations of the entry after t: its next time is
+// zero once its last activation at or before t is past. Adding an entry
+// ending before it starts fails with ErrInvalidWindow.
+func WithEndAt(t time.Time) EntryOption {
+	return func(e *Entry) {
+		e.EndAt = t
+	}
+}
+
+// WithRemoveAfterEnd removes the entry once its last activation before the
+// end set with WithEndAt is started, rather than keeping it with a zero next
+// time.
+func WithRemoveAfterEnd() EntryOption {
+	return func(e *Entry) {
+		e.removeAfterEnd = true
+	}
+}
+
+// WithMaxRuns limits the entry to n scheduled runs, after which it is removed.
+// A run counts as soon as it is started, so that no run is started past the
+// limit, but is given back if it is then skipped, such as by
+// SkipIfStillRunning or ForbidConcurrent. The entry is removed once its last
+// run is known not to be skipped. Runs started with RunNow do not count, nor
+// activations skipped because the entry is paused. An n of 0 or less means no
+// limit.
+func WithMaxRuns(n int) EntryOption {
+	return func(e *Entry) {
+		e.RemainingRuns = 0
+		e.maxRuns = n > 0
+		if n > 0 {
+			e.RemainingRuns = n
+		}
+	}
+}
+
+// WithOnComplete sets the func called, in its own goroutine, once the entry is
+// removed after the last of the runs allowed by WithMaxRuns, or after the run
+// of its OneShotSchedule.
+func WithOnComplete(fn func(id EntryID)) EntryOption {
+	return func(e *Entry) {
+		e.onComplete = fn
+	}
+}
+
+// WithDisableAfterFailures pauses the entry once its runs have failed n times
+// in a row, with an error or a panic, and then calls onDisable, if not nil,
+// with the error of the last run. The entry is then Disabled until Resume,
+// which counts its failures from zero again. A successful run resets the
+// count.
+func WithDisableAfterFailures(n int, onDisable func(id EntryID, lastErr error)) EntryOption {
+	return func(e *Entry) {
+		e.disableAfter = n
+		e.onDisable = onDisable
+	}
+}
+
+// WithHistory makes the Cron keep the last n runs of the entry, including the
+// skipped ones, to be returned by History. An n of 0 or less disables the
+// history.
+func WithHistory(n int) EntryOption {
+	return func(e *Entry) {
+		e.state.history = nil
+		if n > 0 {
+			e.state.history = make([]Execution, n)
+		}
+	}
+}
+
+// withPrev sets the time an entry was last run.
+func withPrev(t time.Time) EntryOption {
+	return func(e *Entry) {
+		e.Prev = t
+	}
+}
+
+// withSpec records the spec an entry's schedule was parsed from.
+func withSpec(spec string) EntryOption {
+	return func(e *Entry) {
+		e.Spec = spec
+	}
+}
@@ -0,0 +1,127 @@
+// Package otelcron traces the runs of the jobs of a cron.Cron with
+// OpenTelemetry, so that the spans of the services called by a job join a
+// trace rooted at its run. It is a package of its own so that package cron
+// does not depend on OpenTelemetry.
+//
+// Each run gets a span named after the entry: its name, else its spec. The
+// span ends once the run is done, with an error status if the job failed or
+// panicked. Jobs implementing cron.JobWithContext get the context of the span
+// in the context they are run with:
+//
+//	c := cron.New(clock, otelcron.WithTracing())
+//	c.AddFuncCtx("@hourly", func(ctx context.Context) {
+//		// Requests made with ctx are part of the trace of the run.
+//	})
+package otelcron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The name of the tracer of the spans.
+const tracerName = "github.com/robfig/cron/otelcron"
+
+// The name of the spans of the runs of entries with neither a name nor a
+// spec, and of the runs outside of a Cron.
+const defaultSpanName = "cron.job"
+
+// The attributes of the spans.
+const (
+	EntryIDKey       = attribute.Key("cron.entry.id")
+	EntryStringIDKey = attribute.Key("cron.entry.string_id")
+	EntryNameKey     = attribute.Key("cron.entry.name")
+	EntrySpecKey     = attribute.Key("cron.entry.spec")
+	ActivationKey    = attribute.Key("cron.activation")     // The activation time, in RFC 3339.
+	StartDelayKey    = attribute.Key("cron.start_delay_ms") // From the activation time to the start.
+	SkippedKey       = attribute.Key("cron.skipped")        // Whether a wrapper skipped the run.
+)
+
+// Option represents a modification to how the runs are traced.
+type Option func(*config)
+
+type config struct {
+	provider trace.TracerProvider
+}
+
+// WithTracerProvider sets the provider of the tracer of the spans. The default
+// is the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// JobWrapper returns a cron.JobWrapper tracing each run of the wrapped job in a
+// span. Outside of a Cron, the span has only a default name.
+func JobWrapper(opts ...Option) cron.JobWrapper {
+	c := config{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	tracer := c.provider.Tracer(tracerName)
+	return cron.WrapperFunc(func(ctx context.Context, run func(context.Context) error) (err error) {
+		name := defaultSpanName
+		var attrs []attribute.KeyValue
+		start := time.Now()
+		if e, ok := cron.EntryFromContext(ctx); ok {
+			name = spanName(e)
+			attrs = append(attrs, EntryIDKey.Int64(int64(e.ID)), EntryStringIDKey.String(e.StringID))
+			if e.Name != "" {
+				attrs = append(attrs, EntryNameKey.String(e.Name))
+			}
+			if e.Spec != "" {
+				attrs = append(attrs, EntrySpecKey.String(e.Spec))
+			}
+		}
+		if t, ok := cron.ActivationFromContext(ctx); ok {
+			attrs = append(attrs,
+				ActivationKey.String(t.Format(time.RFC3339Nano)),
+				StartDelayKey.Int64(start.Sub(t).Milliseconds()))
+		}
+		ctx, span := tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+		defer func() {
+			if r := recover(); r != nil {
+				span.RecordError(fmt.Errorf("panic: %v", r), trace.WithStackTrace(true))
+				span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", r))
+				span.End()
+				panic(r)
+			}
+			switch {
+			case errors.Is(err, cron.ErrSkipped):
+				span.SetAttributes(SkippedKey.Bool(true))
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+		return run(ctx)
+	})
+}
+
+// WithTracing returns a cron.Option wrapping the jobs of all the entries of the
+// Cron with JobWrapper, as with cron.WithJobWrappers. Put it first so that the
+// spans cover the other wrappers.
+func WithTracing(opts ...Option) cron.Option {
+	return cron.WithJobWrappers(JobWrapper(opts...))
+}
+
+// spanName returns the name of the spans of the runs of the entry.
+func spanName(e cron.Entry) string {
+	switch {
+	case e.Name != "":
+		return e.Name
+	case e.Spec != "":
+		return e.Spec
+	}
+	return defaultSpanName
+}
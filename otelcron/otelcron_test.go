@@ -0,0 +1,127 @@
+package otelcron
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/robfig/cron"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracer() (*tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	exporter := tracetest.NewInMemoryExporter()
+	return exporter, sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+}
+
+// attr returns the value of the attribute of the span with the given key.
+func attr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestSpans(t *testing.T) {
+	exporter, provider := newTracer()
+	clock := clockwork.NewFakeClock()
+	c := cron.New(clock, WithTracing(WithTracerProvider(provider)), cron.WithErrorHandler(func(cron.EntryID, error) {}))
+	named, _ := c.AddFunc("@every 1h", func() {}, cron.WithName("cleanup"))
+	spec, _ := c.AddFuncE("@every 2h", func() error { return errors.New("failed") })
+	c.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	clock.BlockUntil(1)
+	assert.NoError(t, c.Shutdown(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 3, len(spans))
+	byName := map[string][]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	assert.Equal(t, 2, len(byName["cleanup"]))
+	// The runs of both hours may end in any order.
+	sort.Slice(byName["cleanup"], func(i, j int) bool {
+		return attr(byName["cleanup"][i], ActivationKey).AsString() < attr(byName["cleanup"][j], ActivationKey).AsString()
+	})
+	assert.Equal(t, 1, len(byName["@every 2h"]))
+
+	span := byName["cleanup"][0]
+	assert.Equal(t, int64(named), attr(span, EntryIDKey).AsInt64())
+	assert.Equal(t, "e-"+strconv.Itoa(int(named)), attr(span, EntryStringIDKey).AsString())
+	assert.Equal(t, "cleanup", attr(span, EntryNameKey).AsString())
+	assert.Equal(t, "@every 1h", attr(span, EntrySpecKey).AsString())
+	assert.Equal(t, clock.Now().Add(-time.Hour).Format(time.RFC3339Nano), attr(span, ActivationKey).AsString())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+
+	span = byName["@every 2h"][0]
+	assert.Equal(t, int64(spec), attr(span, EntryIDKey).AsInt64())
+	assert.Equal(t, codes.Error, span.Status.Code)
+	assert.Equal(t, "failed", span.Status.Description)
+	assert.Equal(t, 1, len(span.Events))
+}
+
+func TestPanicSpan(t *testing.T) {
+	exporter, provider := newTracer()
+	c := cron.New(clockwork.NewFakeClock(), WithTracing(WithTracerProvider(provider)), cron.WithErrorHandler(func(cron.EntryID, error) {}))
+	id, _ := c.AddFunc("@every 1h", func() { panic("boom") }, cron.WithHistory(1))
+	assert.NoError(t, c.RunNow(id))
+	assert.NoError(t, c.Shutdown(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "panic: boom", spans[0].Status.Description)
+	assert.True(t, spans[0].EndTime.After(spans[0].StartTime), "expected the span to be ended")
+
+	// The panic still fails the run.
+	var panicked *cron.PanicError
+	assert.True(t, errors.As(c.History(id)[0].Err, &panicked), "expected a PanicError")
+}
+
+func TestChildSpan(t *testing.T) {
+	exporter, provider := newTracer()
+	tracer := provider.Tracer("downstream")
+	c := cron.New(clockwork.NewFakeClock(), WithTracing(WithTracerProvider(provider)))
+	id, _ := c.AddFuncCtx("@every 1h", func(ctx context.Context) {
+		_, span := tracer.Start(ctx, "request")
+		span.End()
+	}, cron.WithName("sync"))
+	assert.NoError(t, c.RunNow(id))
+	assert.NoError(t, c.Shutdown(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 2, len(spans))
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "request", child.Name)
+	assert.Equal(t, "sync", parent.Name)
+	assert.Equal(t, parent.SpanContext.TraceID(), child.SpanContext.TraceID())
+	assert.Equal(t, parent.SpanContext.SpanID(), child.Parent.SpanID())
+	assert.False(t, parent.Parent.IsValid(), "expected the span of the run to be a root span")
+}
+
+func TestSkippedSpan(t *testing.T) {
+	exporter, provider := newTracer()
+	job := cron.Chain(JobWrapper(WithTracerProvider(provider)), cron.RateLimit(1, time.Hour, cron.RateLimitPolicy{}))(cron.FuncJob(func() {}))
+	job.Run()
+	job.Run()
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, defaultSpanName, spans[1].Name)
+	assert.True(t, attr(spans[1], SkippedKey).AsBool(), "expected the run to be skipped")
+	assert.Equal(t, codes.Unset, spans[1].Status.Code)
+}
@@ -2,9 +2,11 @@ package cron
 
 import (
 	"fmt"
+	"hash/fnv"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,14 +17,18 @@ import (
 type ParseOption int
 
 const (
-	Second      ParseOption = 1 << iota // Seconds field, default 0
-	Minute                              // Minutes field, default 0
-	Hour                                // Hours field, default 0
-	Dom                                 // Day of month field, default *
-	Month                               // Month field, default *
-	Dow                                 // Day of week field, default *
-	DowOptional                         // Optional day of week field, default *
-	Descriptor                          // Allow descriptors such as @monthly, @weekly, etc.
+	Second             ParseOption = 1 << iota // Seconds field, default 0
+	Minute                                     // Minutes field, default 0
+	Hour                                       // Hours field, default 0
+	Dom                                        // Day of month field, default *
+	Month                                      // Month field, default *
+	Dow                                        // Day of week field, default *
+	DowOptional                                // Optional day of week field, default *
+	Descriptor                                 // Allow descriptors such as @monthly, @weekly, etc.
+	StrictDow                                  // Require both day of month and day of week to match
+	SubsecondPrecision                         // Keep sub-second delays in @every descriptors
+	Year                                       // Optional trailing year field, default *
+	SecondOptional                             // Optional leading seconds field, default 0
 )
 
 var places = []ParseOption{
@@ -45,8 +51,10 @@ var defaults = []string{
 
 // A custom Parser that can be configured.
 type Parser struct {
-	options   ParseOption
-	optionals int
+	options     ParseOption
+	optionals   int
+	hashKey     string
+	descriptors *descriptors
 }
 
 // Creates a custom Parser with custom options.
@@ -63,13 +71,54 @@ type Parser struct {
 //  subsParser := NewParser(Dom | Month | DowOptional)
 //  sched, err := specParser.Parse("15 */3")
 //
+//  // Standard parser also accepting a leading seconds field
+//  mixedParser := NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow)
+//  sched, err := mixedParser.Parse("*/5 * * * *")
+//  sched, err := mixedParser.Parse("30 */5 * * * *")
+//
+// The day of week field is required with SecondOptional, as a spec with one
+// field less could otherwise miss either field: DowOptional is ignored.
 func NewParser(options ParseOption) Parser {
 	optionals := 0
+	if options&SecondOptional > 0 {
+		options = options&^DowOptional | Second
+	}
 	if options&DowOptional > 0 {
 		options |= Dow
 		optionals++
 	}
-	return Parser{options, optionals}
+	return Parser{options: options, optionals: optionals, descriptors: &descriptors{}}
+}
+
+// RegisterDescriptor makes the parser, and its copies, parse the specs "@name"
+// and "@name arg" with fn, which is given the text after the first space, or
+// "" if there is none. The parser must accept descriptors. It returns an error
+// if the name is taken by a predefined descriptor, such as "hourly" or "every",
+// or by a descriptor registered before. It is safe to call concurrently with
+// Parse.
+//
+//  p := NewParser(Minute | Hour | Dom | Month | Dow | Descriptor)
+//  err := p.RegisterDescriptor("end-of-month", endOfMonth)
+//  sched, err := p.Parse("@end-of-month 18:00")
+func (p Parser) RegisterDescriptor(name string, fn func(arg string) (Schedule, error)) error {
+	if p.descriptors == nil {
+		return fmt.Errorf("Parser not created with NewParser")
+	}
+	if name == "" || strings.ContainsAny(name, " \t@") {
+		return fmt.Errorf("Invalid descriptor name: %q", name)
+	}
+	if predefinedDescriptors[name] {
+		return fmt.Errorf("Descriptor @%s is predefined", name)
+	}
+	return p.descriptors.register(name, fn)
+}
+
+// WithHashKey returns a copy of the parser picking the values of the H
+// expressions of the specs it parses from the given key, rather than from
+// each spec.
+func (p Parser) WithHashKey(key string) Parser {
+	p.hashKey = key
+	return p
 }
 
 // Parse returns a new crontab schedule representing the given spec.
@@ -80,7 +129,14 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		return nil, fmt.Errorf("Empty spec string")
 	}
 	if spec[0] == '@' && p.options&Descriptor > 0 {
-		return parseDescriptor(spec)
+		name, arg := spec[1:], ""
+		if i := strings.Index(name, " "); i >= 0 {
+			name, arg = name[:i], name[i+1:]
+		}
+		if fn := p.descriptors.lookup(name); fn != nil {
+			return fn(arg)
+		}
+		return parseDescriptor(spec, p.options)
 	}
 
 	// Figure out how many fields we need
@@ -91,10 +147,30 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		}
 	}
 	min := max - p.optionals
+	if p.options&Year > 0 {
+		max++
+	}
 
 	// Split fields on whitespace
 	fields := strings.Fields(spec)
 
+	// Without the optional seconds field, the fields are shifted by one. Specs
+	// with all the fields but the year start with the seconds.
+	shift := 0
+	if p.options&SecondOptional > 0 {
+		if count := len(fields); count < min-1 || count > max {
+			counts := fmt.Sprintf("%d or %d", min-1, min)
+			if max > min {
+				counts = fmt.Sprintf("%d, %d or %d", min-1, min, max)
+			}
+			return nil, fmt.Errorf("Expected %s fields, with an optional leading seconds field, found %d: %s", counts, count, spec)
+		}
+		if len(fields) == min-1 {
+			fields = append([]string{defaults[0]}, fields...)
+			shift = 1
+		}
+	}
+
 	// Validate number of fields
 	if count := len(fields); count < min || count > max {
 		if min == max {
@@ -103,39 +179,183 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		return nil, fmt.Errorf("Expected %d to %d fields, found %d: %s", min, max, count, spec)
 	}
 
+	// Take the year field off, if there is one
+	var years []int
+	specFields := fields
+	yearError := func(err error) error {
+		return &ParseError{Field: "year", Index: max - 1 - shift, Token: specFields[max-1], Err: err}
+	}
+	if p.options&Year > 0 && len(fields) == max {
+		var err error
+		if years, err = getYears(fields[max-1]); err != nil {
+			return nil, yearError(err)
+		}
+		fields = fields[:max-1]
+	}
+
 	// Fill in missing fields
 	fields = expandFields(fields, p.options)
+	raw := append([]string(nil), fields...)
+	fieldError := func(place int, err error) error {
+		return &ParseError{Field: fieldNames[place], Index: p.fieldIndex(place) - shift, Token: raw[place], Err: err}
+	}
+
+	// A day of week field starting with & must match along with the day of
+	// month, as with StrictDow
+	strict := p.options&StrictDow > 0
+	if strings.HasPrefix(fields[5], "&") {
+		fields[5], strict = fields[5][1:], true
+		if fields[5] == "" {
+			return nil, fieldError(5, fmt.Errorf("Missing day of week after &"))
+		}
+	}
 
+	// Resolve the H expressions
+	key := p.hashKey
+	if key == "" {
+		key = spec
+	}
 	var err error
-	field := func(field string, r bounds) uint64 {
+	for i, r := range hashBounds {
+		if fields[i], err = resolveHash(fields[i], r, key, i); err != nil {
+			return nil, fieldError(i, err)
+		}
+	}
+
+	parse := func(place int, get func(string) (uint64, error)) uint64 {
 		if err != nil {
 			return 0
 		}
-		var bits uint64
-		bits, err = getField(field, r)
+		bits, fieldErr := get(fields[place])
+		if fieldErr != nil {
+			err = fieldError(place, fieldErr)
+		}
 		return bits
 	}
+	field := func(place int, r bounds) uint64 {
+		return parse(place, func(field string) (uint64, error) { return getField(field, r) })
+	}
 
 	var (
-		second     = field(fields[0], seconds)
-		minute     = field(fields[1], minutes)
-		hour       = field(fields[2], hours)
-		dayofmonth = field(fields[3], dom)
-		month      = field(fields[4], months)
-		dayofweek  = field(fields[5], dow)
+		lastDom       bool
+		lastDomOffset uint
+	)
+	var (
+		second     = field(0, seconds)
+		minute     = field(1, minutes)
+		hour       = field(2, hours)
+		dayofmonth = parse(3, func(field string) (uint64, error) {
+			if !strings.ContainsAny(field, "Ll") {
+				return getDayOfMonth(field)
+			}
+			offset, err := getLastDayOfMonth(field)
+			lastDom, lastDomOffset = true, offset
+			return 0, err
+		})
+		month      = field(4, months)
+		dayofweek  = parse(5, getDayOfWeek)
 	)
 	if err != nil {
 		return nil, err
 	}
+	if strict {
+		dayofweek |= strictBit
+	}
 
-	return &SpecSchedule{
+	schedule := &SpecSchedule{
 		Second: second,
 		Minute: minute,
 		Hour:   hour,
 		Dom:    dayofmonth,
 		Month:  month,
 		Dow:    dayofweek,
-	}, nil
+
+		LastDom:       lastDom,
+		LastDomOffset: lastDomOffset,
+	}
+	if err := checkDays(schedule); err != nil {
+		return nil, fieldError(3, err)
+	}
+	if years != nil {
+		yearSchedule := &YearSchedule{Schedule: schedule, Years: years}
+		first := time.Date(years[0], time.January, 1, 0, 0, 0, 0, time.UTC).Add(-time.Second)
+		if yearSchedule.Next(first).IsZero() {
+			return nil, yearError(fmt.Errorf("The other fields never match in these years"))
+		}
+		return yearSchedule, nil
+	}
+	return schedule, nil
+}
+
+// Validate returns the error parsing the given spec, as with NextN, or nil if
+// it is valid. It is cheaper than keeping the schedule.
+func Validate(spec string, opts ...ParseOption) error {
+	_, err := parserFor(opts).Parse(spec)
+	return err
+}
+
+// ParseError is an error in a field of a spec.
+type ParseError struct {
+	// The name of the field, such as "day of month".
+	Field string
+
+	// The position of the field in the spec, from 0.
+	Index int
+
+	// The text of the field in the spec.
+	Token string
+
+	// The cause of the error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Invalid %s field %q (field %d): %s", e.Field, e.Token, e.Index+1, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// The names of the fields, for each place.
+var fieldNames = []string{"second", "minute", "hour", "day of month", "month", "day of week"}
+
+// fieldIndex returns the position in the specs of the field at the given
+// place.
+func (p Parser) fieldIndex(place int) int {
+	index := 0
+	for _, option := range places[:place] {
+		if p.options&option > 0 {
+			index++
+		}
+	}
+	return index
+}
+
+// checkDays returns an error if the day of month field of a schedule, when it
+// has to match, has no day in its months. February is taken to have 29 days.
+func checkDays(s *SpecSchedule) error {
+	if s.Dom&starBit > 0 || s.Dow&(starBit|strictBit) == 0 {
+		return nil
+	}
+	var names []string
+	for m := months.min; m <= months.max; m++ {
+		if s.Month&(1<<m) == 0 {
+			continue
+		}
+		last := uint(time.Date(2000, time.Month(m)+1, 0, 0, 0, 0, 0, time.UTC).Day())
+		if s.LastDom && s.LastDomOffset < last {
+			return nil
+		}
+		for d := dom.min; d <= last; d++ {
+			if s.Dom&(1<<d|1<<(weekdayShift+d)) > 0 {
+				return nil
+			}
+		}
+		names = append(names, time.Month(m).String())
+	}
+	return fmt.Errorf("No such day in %s, so the spec never matches", joinWords(names))
 }
 
 func expandFields(fields []string, options ParseOption) []string {
@@ -167,8 +387,14 @@ var standardParser = NewParser(
 // It accepts
 //   - Standard crontab specs, e.g. "* * * * ?"
 //   - Descriptors, e.g. "@midnight", "@every 1h30m"
-func ParseStandard(standardSpec string) (Schedule, error) {
-	return standardParser.Parse(standardSpec)
+//
+// The given options are added to those of the standard parser, such as
+// SecondOptional to also accept specs with a leading seconds field.
+func ParseStandard(standardSpec string, opts ...ParseOption) (Schedule, error) {
+	if len(opts) == 0 {
+		return standardParser.Parse(standardSpec)
+	}
+	return parserFor(append(opts, Minute|Hour|Dom|Month|Dow|Descriptor)).Parse(standardSpec)
 }
 
 var defaultParser = NewParser(
@@ -185,6 +411,89 @@ func Parse(spec string) (Schedule, error) {
 	return defaultParser.Parse(spec)
 }
 
+// NextN returns the next n activation times of the given spec, from now. It
+// returns fewer times if the schedule stops being satisfiable. The spec is
+// parsed as with Parse, or with a Parser created with the given options if
+// there are any.
+func NextN(spec string, n int, opts ...ParseOption) ([]time.Time, error) {
+	schedule, err := parserFor(opts).Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return nextN(schedule.Next, time.Now(), n), nil
+}
+
+// parserFor returns the default parser if there are no options, or a parser
+// created with the options.
+func parserFor(opts []ParseOption) Parser {
+	if len(opts) == 0 {
+		return defaultParser
+	}
+	var options ParseOption
+	for _, opt := range opts {
+		options |= opt
+	}
+	return NewParser(options)
+}
+
+// The ranges H expressions pick from, for each field. The day of month stops at
+// 28 so that the schedule is activated every month.
+var hashBounds = []bounds{seconds, minutes, hours, {dom.min, 28, nil}, months, dow}
+
+// resolveHash replaces the H expressions of a field with the values they pick
+// in the given range, from a hash of the key and the position of the field:
+//   "H" [ "(" number "-" number ")" ] [ "/" number ]
+// "H" picks a value in the range, and "H/step" a value in the first step of the
+// range and every step after it. The same key always picks the same values.
+func resolveHash(field string, r bounds, key string, place int) (string, error) {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		if item != "H" && !strings.HasPrefix(item, "H(") && !strings.HasPrefix(item, "H/") {
+			continue
+		}
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		h.Write([]byte{byte(place)})
+		hash := uint(h.Sum32())
+
+		low, high, rest := r.min, r.max, item[1:]
+		if strings.HasPrefix(rest, "(") {
+			end := strings.Index(rest, ")")
+			if end < 0 || strings.Contains(rest[:end], "/") {
+				return "", fmt.Errorf("Invalid range of H: %s", item)
+			}
+			var err error
+			if low, high, _, _, err = parseRange(rest[1:end], r); err != nil {
+				return "", err
+			}
+			if low > high {
+				return "", fmt.Errorf("Beginning of range (%d) beyond end of range (%d): %s", low, high, item)
+			}
+			rest = rest[end+1:]
+		}
+		if rest == "" {
+			items[i] = strconv.Itoa(int(low + hash%(high-low+1)))
+			continue
+		}
+		if !strings.HasPrefix(rest, "/") {
+			return "", fmt.Errorf("Invalid H expression: %s", item)
+		}
+		step, err := mustParseInt(rest[1:])
+		if err != nil {
+			return "", err
+		}
+		if step == 0 {
+			return "", fmt.Errorf("Step of range should be a positive number: %s", item)
+		}
+		offset := step
+		if offset > high-low+1 {
+			offset = high - low + 1
+		}
+		items[i] = fmt.Sprintf("%d-%d/%d", low+hash%offset, high, step)
+	}
+	return strings.Join(items, ","), nil
+}
+
 // getField returns an Int with the bits set representing all of the times that
 // the field represents or error parsing field value.  A "field" is a comma-separated
 // list of "ranges".
@@ -203,25 +512,204 @@ func getField(field string, r bounds) (uint64, error) {
 
 // getRange returns the bits indicated by the given expression:
 //   number | number "-" number [ "/" number ]
-// or error parsing range.
+// or error parsing range. A range whose beginning is after its end, such as
+// "FRI-MON", wraps around the end of the field.
 func getRange(expr string, r bounds) (uint64, error) {
+	start, end, step, star, err := parseRange(expr, r)
+	if err != nil {
+		return 0, err
+	}
+	var extra uint64
+	if star {
+		extra = starBit
+	}
+	return rangeBits(start, end, step, r) | extra, nil
+}
+
+// rangeBits sets the bits from start to end with the given step, continuing
+// from the minimum of the field past its maximum if start is after end: in
+// hours, 22-2/2 is 22, 0 and 2.
+func rangeBits(start, end, step uint, r bounds) uint64 {
+	if start <= end {
+		return getBits(start, end, step)
+	}
+	var bits uint64
+	size := r.max - r.min + 1
+	for i := start; i <= end+size; i += step {
+		bits |= 1 << (r.min + (i-r.min)%size)
+	}
+	return bits
+}
+
+// getDayOfMonth returns the bits of a day of month field, which may also be a
+// W expression.
+func getDayOfMonth(field string) (uint64, error) {
+	if strings.HasSuffix(strings.ToUpper(field), "W") {
+		return getNearestWeekday(field)
+	}
+	return getField(field, dom)
+}
+
+// getLastDayOfMonth returns the offset of a day of month field activated on a
+// day counted back from the last day of the month:
+//   "L" [ "-" number ]
+// or error parsing the field.
+func getLastDayOfMonth(field string) (uint, error) {
+	if strings.ContainsAny(field, ",*?/") {
+		return 0, fmt.Errorf("L cannot be combined with other days of month: %s", field)
+	}
+	if strings.EqualFold(field, "L") {
+		return 0, nil
+	}
+	if len(field) < 2 || !strings.EqualFold(field[:2], "L-") {
+		return 0, fmt.Errorf("L must be alone or followed by an offset, as in L-2: %s", field)
+	}
+	n, err := mustParseInt(field[2:])
+	if err != nil {
+		return 0, err
+	}
+	if n > dom.max-dom.min {
+		return 0, fmt.Errorf("Offset from the last day of the month (%d) must be from 0 to %d: %s", n, dom.max-dom.min, field)
+	}
+	return n, nil
+}
+
+// getDayOfWeek returns the bits of a day of week field, which may also be a #
+// expression.
+func getDayOfWeek(field string) (uint64, error) {
+	if strings.Contains(field, "#") {
+		return getNthWeekday(field)
+	}
+	var bits uint64
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		bit, err := getDayOfWeekRange(expr)
+		if err != nil {
+			return bits, err
+		}
+		bits |= bit
+	}
+	return bits, nil
+}
+
+// getDayOfWeekRange returns the bits of a range of days of week, where 7 is
+// also Sunday: 7 and 7-1 begin on Sunday, and 1-7 ends on it, while N/step
+// still ends on Saturday.
+func getDayOfWeekRange(expr string) (uint64, error) {
+	start, end, step, star, err := parseRange(expr, bounds{dow.min, 7, dow.names})
+	if err != nil {
+		return 0, err
+	}
+	if star {
+		return getBits(dow.min, dow.max, step) | starBit, nil
+	}
+	if start == 7 {
+		start = 0
+		if end == 7 {
+			end = 0
+		}
+	}
+	if end == 7 {
+		if start == 0 || !strings.Contains(expr, "-") {
+			end = dow.max
+		} else {
+			end = 0
+		}
+	}
+	return rangeBits(start, end, step, dow), nil
+}
+
+// getNearestWeekday returns the bits of a day of month field activated on the
+// weekday nearest to a single day:
+//   number "W"
+// or error parsing the field.
+func getNearestWeekday(field string) (uint64, error) {
+	if strings.ContainsAny(field, ",-/*?") {
+		return 0, fmt.Errorf("W must follow a single day of month: %s", field)
+	}
+	day, err := mustParseInt(field[:len(field)-1])
+	if err != nil {
+		return 0, err
+	}
+	if day < dom.min || day > dom.max {
+		return 0, fmt.Errorf("Day of month (%d) must be from %d to %d: %s", day, dom.min, dom.max, field)
+	}
+	return 1 << (weekdayShift + day), nil
+}
+
+// getNthWeekday returns the bits of a day of week field activated on the nth
+// occurrence of a single weekday in the month:
+//   (number | name) "#" number
+// or error parsing the field.
+func getNthWeekday(field string) (uint64, error) {
+	parts := strings.Split(field, "#")
+	if len(parts) != 2 || strings.ContainsAny(field, ",-/*?") {
+		return 0, fmt.Errorf("# must be between a single day of week and its occurrence: %s", field)
+	}
+	weekday, err := parseIntOrName(parts[0], dow.names)
+	if err != nil {
+		return 0, err
+	}
+	if weekday == 7 {
+		weekday = 0
+	}
+	if weekday > dow.max {
+		return 0, fmt.Errorf("Day of week (%d) must be from %d to %d: %s", weekday, dow.min, 7, field)
+	}
+	n, err := mustParseInt(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n > 5 {
+		return 0, fmt.Errorf("Occurrence of day of week (%d) must be from 1 to 5: %s", n, field)
+	}
+	return 1 << (nthShift + 7*(n-1) + weekday), nil
+}
+
+// getYears returns the sorted years represented by a year field, or nil if it
+// represents all of them.
+func getYears(field string) ([]int, error) {
+	set := make(map[uint]bool)
+	for _, expr := range strings.Split(field, ",") {
+		start, end, step, _, err := parseRange(expr, years)
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("Beginning of range (%d) beyond end of range (%d): %s", start, end, expr)
+		}
+		for y := start; y <= end; y += step {
+			set[y] = true
+		}
+	}
+	if len(set) == int(years.max-years.min+1) {
+		return nil, nil
+	}
+	var list []int
+	for y := years.min; y <= years.max; y++ {
+		if set[y] {
+			list = append(list, int(y))
+		}
+	}
+	return list, nil
+}
+
+// parseRange returns the start, end and step of a range expression, and
+// whether it was a star.
+func parseRange(expr string, r bounds) (start, end, step uint, star bool, err error) {
 	var (
-		start, end, step uint
-		rangeAndStep     = strings.Split(expr, "/")
-		lowAndHigh       = strings.Split(rangeAndStep[0], "-")
-		singleDigit      = len(lowAndHigh) == 1
-		err              error
+		rangeAndStep = strings.Split(expr, "/")
+		lowAndHigh   = strings.Split(rangeAndStep[0], "-")
+		singleDigit  = len(lowAndHigh) == 1
 	)
 
-	var extra uint64
 	if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
 		start = r.min
 		end = r.max
-		extra = starBit
+		star = true
 	} else {
 		start, err = parseIntOrName(lowAndHigh[0], r.names)
 		if err != nil {
-			return 0, err
+			return 0, 0, 0, false, err
 		}
 		switch len(lowAndHigh) {
 		case 1:
@@ -229,10 +717,10 @@ func getRange(expr string, r bounds) (uint64, error) {
 		case 2:
 			end, err = parseIntOrName(lowAndHigh[1], r.names)
 			if err != nil {
-				return 0, err
+				return 0, 0, 0, false, err
 			}
 		default:
-			return 0, fmt.Errorf("Too many hyphens: %s", expr)
+			return 0, 0, 0, false, fmt.Errorf("Too many hyphens: %s", expr)
 		}
 	}
 
@@ -242,7 +730,7 @@ func getRange(expr string, r bounds) (uint64, error) {
 	case 2:
 		step, err = mustParseInt(rangeAndStep[1])
 		if err != nil {
-			return 0, err
+			return 0, 0, 0, false, err
 		}
 
 		// Special handling: "N/step" means "N-max/step".
@@ -250,23 +738,23 @@ func getRange(expr string, r bounds) (uint64, error) {
 			end = r.max
 		}
 	default:
-		return 0, fmt.Errorf("Too many slashes: %s", expr)
+		return 0, 0, 0, false, fmt.Errorf("Too many slashes: %s", expr)
 	}
 
 	if start < r.min {
-		return 0, fmt.Errorf("Beginning of range (%d) below minimum (%d): %s", start, r.min, expr)
+		return 0, 0, 0, false, fmt.Errorf("Beginning of range (%d) below minimum (%d): %s", start, r.min, expr)
 	}
 	if end > r.max {
-		return 0, fmt.Errorf("End of range (%d) above maximum (%d): %s", end, r.max, expr)
+		return 0, 0, 0, false, fmt.Errorf("End of range (%d) above maximum (%d): %s", end, r.max, expr)
 	}
-	if start > end {
-		return 0, fmt.Errorf("Beginning of range (%d) beyond end of range (%d): %s", start, end, expr)
+	if start > r.max {
+		return 0, 0, 0, false, fmt.Errorf("Beginning of range (%d) beyond end of range (%d): %s", start, r.max, expr)
 	}
 	if step == 0 {
-		return 0, fmt.Errorf("Step of range should be a positive number: %s", expr)
+		return 0, 0, 0, false, fmt.Errorf("Step of range should be a positive number: %s", expr)
 	}
 
-	return getBits(start, end, step) | extra, nil
+	return start, end, step, star, nil
 }
 
 // parseIntOrName returns the (possibly-named) integer contained in expr.
@@ -313,8 +801,43 @@ func all(r bounds) uint64 {
 	return getBits(r.min, r.max, 1) | starBit
 }
 
+// The names of the predefined descriptors, which cannot be registered.
+var predefinedDescriptors = map[string]bool{
+	"yearly": true, "annually": true, "monthly": true, "weekly": true, "daily": true,
+	"midnight": true, "hourly": true, "reboot": true, "at": true, "every": true, "every-aligned": true,
+}
+
+// descriptors are the descriptors registered on a parser and its copies.
+type descriptors struct {
+	mu    sync.RWMutex
+	funcs map[string]func(arg string) (Schedule, error)
+}
+
+func (d *descriptors) register(name string, fn func(arg string) (Schedule, error)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.funcs[name]; ok {
+		return fmt.Errorf("Descriptor @%s already registered", name)
+	}
+	if d.funcs == nil {
+		d.funcs = make(map[string]func(arg string) (Schedule, error))
+	}
+	d.funcs[name] = fn
+	return nil
+}
+
+// lookup returns the func registered for the name, or nil if there is none.
+func (d *descriptors) lookup(name string) func(arg string) (Schedule, error) {
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.funcs[name]
+}
+
 // parseDescriptor returns a predefined schedule for the expression, or error if none matches.
-func parseDescriptor(descriptor string) (Schedule, error) {
+func parseDescriptor(descriptor string, options ParseOption) (Schedule, error) {
 	switch descriptor {
 	case "@yearly", "@annually":
 		return &SpecSchedule{
@@ -365,16 +888,84 @@ func parseDescriptor(descriptor string) (Schedule, error) {
 			Month:  all(months),
 			Dow:    all(dow),
 		}, nil
+
+	case "@reboot":
+		return RebootSchedule{}, nil
+	}
+
+	const at = "@at "
+	if strings.HasPrefix(descriptor, at) {
+		t, err := time.Parse(time.RFC3339, descriptor[len(at):])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse RFC 3339 time %s: %s", descriptor, err)
+		}
+		return At(t), nil
+	}
+
+	const after = "@after "
+	if strings.HasPrefix(descriptor, after) {
+		duration, err := time.ParseDuration(descriptor[len(after):])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse duration %s: %s", descriptor, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("Delay (%s) should be positive: %s", duration, descriptor)
+		}
+		return AfterCompletion(duration), nil
 	}
 
 	const every = "@every "
+	const aligned = "@every-aligned "
+	if strings.HasPrefix(descriptor, aligned) {
+		duration, err := time.ParseDuration(descriptor[len(aligned):])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse duration %s: %s", descriptor, err)
+		}
+		if duration <= 0 || duration > 24*time.Hour {
+			return nil, fmt.Errorf("Aligned delay (%s) should be positive and at most 24h: %s", duration, descriptor)
+		}
+		return EveryAligned(duration), nil
+	}
+
+	if strings.HasPrefix(descriptor, every) && strings.Contains(descriptor, "~") {
+		return parseRandomDelay(descriptor, descriptor[len(every):])
+	}
 	if strings.HasPrefix(descriptor, every) {
 		duration, err := time.ParseDuration(descriptor[len(every):])
 		if err != nil {
 			return nil, fmt.Errorf("Failed to parse duration %s: %s", descriptor, err)
 		}
+		if options&SubsecondPrecision > 0 {
+			return EveryPrecise(duration), nil
+		}
 		return Every(duration), nil
 	}
 
 	return nil, fmt.Errorf("Unrecognized descriptor: %s", descriptor)
 }
+
+// parseRandomDelay returns the schedule of an @every descriptor with a range
+// of delays:
+//   duration "~" duration
+// or error parsing the range.
+func parseRandomDelay(descriptor, expr string) (Schedule, error) {
+	minAndMax := strings.Split(expr, "~")
+	if len(minAndMax) != 2 {
+		return nil, fmt.Errorf("Too many tildes: %s", descriptor)
+	}
+	min, err := time.ParseDuration(minAndMax[0])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse duration %s: %s", descriptor, err)
+	}
+	max, err := time.ParseDuration(minAndMax[1])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse duration %s: %s", descriptor, err)
+	}
+	if min <= 0 {
+		return nil, fmt.Errorf("Minimum delay (%s) should be positive: %s", min, descriptor)
+	}
+	if min > max {
+		return nil, fmt.Errorf("Minimum delay (%s) beyond maximum delay (%s): %s", min, max, descriptor)
+	}
+	return EveryRange(min, max), nil
+}
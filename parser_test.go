@@ -1,8 +1,11 @@
 package cron
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -27,6 +30,9 @@ func TestRange(t *testing.T) {
 		{"5-7/2", 0, 7, 1<<5 | 1<<7, ""},
 		{"5-7/1", 0, 7, 1<<5 | 1<<6 | 1<<7, ""},
 
+		{"5/15", 0, 59, 1<<5 | 1<<20 | 1<<35 | 1<<50, ""},
+		{"10-40/10", 0, 59, 1<<10 | 1<<20 | 1<<30 | 1<<40, ""},
+
 		{"*", 1, 3, 1<<1 | 1<<2 | 1<<3 | starBit, ""},
 		{"*/2", 1, 3, 1<<1 | 1<<3 | starBit, ""},
 
@@ -37,8 +43,12 @@ func TestRange(t *testing.T) {
 		{"*//2", 0, 0, zero, "Too many slashes"},
 		{"1", 3, 5, zero, "below minimum"},
 		{"6", 3, 5, zero, "above maximum"},
-		{"5-3", 3, 5, zero, "beyond end of range"},
+		{"5-3", 3, 5, 1<<5 | 1<<3, ""},
+		{"60-1", 0, 59, zero, "beyond end of range"},
 		{"*/0", 0, 0, zero, "should be a positive number"},
+		{"5/0", 0, 59, zero, "should be a positive number"},
+		{"5/-15", 0, 59, zero, "Negative number"},
+		{"60/15", 0, 59, zero, "beyond end of range"},
 	}
 
 	for _, c := range ranges {
@@ -75,6 +85,68 @@ func TestField(t *testing.T) {
 	}
 }
 
+func TestWrappingRange(t *testing.T) {
+	ranges := []struct {
+		expr     string
+		r        bounds
+		expected uint64
+	}{
+		{"22-2", hours, 1<<22 | 1<<23 | 1<<0 | 1<<1 | 1<<2},
+		{"22-2/2", hours, 1<<22 | 1<<0 | 1<<2},
+		{"50-10/20", minutes, 1<<50 | 1<<10},
+		{"30-2", dom, 1<<30 | 1<<31 | 1<<1 | 1<<2},
+		{"DEC-FEB", months, 1<<12 | 1<<1 | 1<<2},
+		{"nov-jan/2", months, 1<<11 | 1<<1},
+	}
+
+	for _, c := range ranges {
+		actual, err := getRange(c.expr, c.r)
+		if err != nil {
+			t.Errorf("%s => unexpected error %v", c.expr, err)
+		}
+		if actual != c.expected {
+			t.Errorf("%s => expected %d, got %d", c.expr, c.expected, actual)
+		}
+	}
+}
+
+func TestDayOfWeekSunday(t *testing.T) {
+	weekend := uint64(1<<6 | 1<<0)
+	fields := []struct {
+		expr     string
+		expected uint64
+	}{
+		{"7", 1 << 0},
+		{"0,7", 1 << 0},
+		{"7/2", 1 << 0},
+		{"5/2", 1 << 5},
+		{"0-7", all(dow) &^ starBit},
+		{"1-7", all(dow) &^ starBit},
+		{"6-7", weekend},
+		{"7-1", 1<<0 | 1<<1},
+		{"SAT-SUN", weekend},
+		{"sat-7", weekend},
+		{"FRI-MON", 1<<5 | 1<<6 | 1<<0 | 1<<1},
+		{"5-1/2", 1<<5 | 1<<0},
+		{"7#1", 1 << (nthShift + 0)},
+		{"*", all(dow)},
+	}
+
+	for _, c := range fields {
+		actual, err := getDayOfWeek(c.expr)
+		if err != nil {
+			t.Errorf("%s => unexpected error %v", c.expr, err)
+		}
+		if actual != c.expected {
+			t.Errorf("%s => expected %d, got %d", c.expr, c.expected, actual)
+		}
+	}
+
+	if _, err := getDayOfWeek("8"); err == nil || !strings.Contains(err.Error(), "above maximum") {
+		t.Errorf("8 => expected above maximum, got %v", err)
+	}
+}
+
 func TestAll(t *testing.T) {
 	allBits := []struct {
 		r        bounds
@@ -145,6 +217,34 @@ func TestParse(t *testing.T) {
 			expr: "@every Xm",
 			err:  "Failed to parse duration",
 		},
+		{
+			expr: "@every 10m~5m",
+			err:  "beyond maximum delay",
+		},
+		{
+			expr: "@every 0s~5m",
+			err:  "should be positive",
+		},
+		{
+			expr: "@every 1m~x",
+			err:  "Failed to parse duration",
+		},
+		{
+			expr: "@every 1m~2m~3m",
+			err:  "Too many tildes",
+		},
+		{
+			expr:     "@reboot",
+			expected: RebootSchedule{},
+		},
+		{
+			expr:     "@at 2025-07-01T09:00:00Z",
+			expected: At(time.Date(2025, time.July, 1, 9, 0, 0, 0, time.UTC)),
+		},
+		{
+			expr: "@at 2025-07-01 09:00",
+			err:  "Failed to parse RFC 3339 time",
+		},
 		{
 			expr: "@yearly",
 			expected: &SpecSchedule{
@@ -203,7 +303,7 @@ func TestStandardSpecSchedule(t *testing.T) {
 	}{
 		{
 			expr:     "5 * * * *",
-			expected: &SpecSchedule{1 << seconds.min, 1 << 5, all(hours), all(dom), all(months), all(dow)},
+			expected: &SpecSchedule{1 << seconds.min, 1 << 5, all(hours), all(dom), all(months), all(dow), false, 0},
 		},
 		{
 			expr:     "@every 5m",
@@ -232,3 +332,280 @@ func TestStandardSpecSchedule(t *testing.T) {
 		}
 	}
 }
+
+func TestNextNSpec(t *testing.T) {
+	times, err := NextN("0 0 * * * *", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 3 || !times[0].After(time.Now()) ||
+		times[1].Sub(times[0]) != time.Hour || times[2].Sub(times[1]) != time.Hour {
+		t.Errorf("expected 3 hourly times, got %v", times)
+	}
+
+	times, err = NextN("@at 2000-01-01T00:00:00Z", 3)
+	if err != nil || len(times) != 0 {
+		t.Errorf("expected no times, got %v, %v", times, err)
+	}
+
+	if _, err = NextN("0 0 j * * *", 3); err == nil {
+		t.Error("expected an error for an invalid spec")
+	}
+}
+
+func TestParseYear(t *testing.T) {
+	parser := NewParser(Second | Minute | Hour | Dom | Month | DowOptional | Descriptor | Year)
+	entries := []struct {
+		expr  string
+		years []int
+		err   string
+	}{
+		{"0 0 12 1 1 ? 2026", []int{2026}, ""},
+		{"0 0 12 1 1 ? 2026-2028,2040/20", []int{2026, 2027, 2028, 2040, 2060, 2080}, ""},
+		{"0 0 12 1 1 ? *", nil, ""},
+		{"0 0 12 1 1 ?", nil, ""},
+		{"0 0 12 1 1", nil, ""},
+		{"0 0 12 1 1 ? 1969", nil, "below minimum"},
+		{"0 0 12 1 1 ? 2100", nil, "above maximum"},
+		{"0 0 12 1 1 ? 2026 x", nil, "Expected 5 to 7 fields"},
+	}
+
+	for _, c := range entries {
+		actual, err := parser.Parse(c.expr)
+		if len(c.err) != 0 && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s => expected %v, got %v", c.expr, c.err, err)
+		}
+		if len(c.err) != 0 {
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s => unexpected error %v", c.expr, err)
+			continue
+		}
+		years, ok := actual.(*YearSchedule)
+		if c.years == nil && ok || c.years != nil && (!ok || !reflect.DeepEqual(years.Years, c.years)) {
+			t.Errorf("%s => expected years %v, got %v", c.expr, c.years, actual)
+		}
+	}
+}
+
+func TestParseSecondOptional(t *testing.T) {
+	parser := NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow | Descriptor)
+	from := getTime("Mon Jul 9 14:46:10 2012")
+	for _, c := range []struct {
+		expr, next string
+	}{
+		{"*/5 * * * *", "Mon Jul 9 14:50:00 2012"},
+		{"30 */5 * * * *", "Mon Jul 9 14:50:30 2012"},
+		{"*/20 47 * * * ?", "Mon Jul 9 14:47:00 2012"},
+		{"@hourly", "Mon Jul 9 15:00:00 2012"},
+	} {
+		sched, err := parser.Parse(c.expr)
+		if err != nil {
+			t.Errorf("%s => unexpected error %v", c.expr, err)
+			continue
+		}
+		if actual := sched.Next(from); actual != getTime(c.next) {
+			t.Errorf("%s => (expected) %s != %s (actual)", c.expr, c.next, actual)
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "* * * * * * *"} {
+		_, err := parser.Parse(expr)
+		if err == nil || !strings.Contains(err.Error(), "Expected 5 or 6 fields, with an optional leading seconds field") {
+			t.Errorf("%s => expected the accepted field counts, got %v", expr, err)
+		}
+	}
+	_, err := NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow | Year).Parse("* * * *")
+	if err == nil || !strings.Contains(err.Error(), "Expected 5, 6 or 7 fields") {
+		t.Errorf("expected the accepted field counts with a year, got %v", err)
+	}
+
+	sched, err := ParseStandard("30 */5 * * * *", SecondOptional)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := sched.Next(from), getTime("Mon Jul 9 14:50:30 2012"); actual != expected {
+		t.Errorf("(expected) %v != %v (actual)", expected, actual)
+	}
+	if _, err := ParseStandard("30 */5 * * * *"); err == nil {
+		t.Error("expected ParseStandard to reject 6 fields without SecondOptional")
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	parser := NewParser(Minute | Hour | Dom | Month | Dow)
+	minutesOf := func(key, spec string) uint64 {
+		sched, err := parser.WithHashKey(key).Parse(spec)
+		if err != nil {
+			t.Fatal(key, spec, err)
+		}
+		return sched.(*SpecSchedule).Minute
+	}
+
+	if minutesOf("svc", "H 3 * * *") != minutesOf("svc", "H 3 * * *") {
+		t.Error("expected the same key to pick the same minute")
+	}
+	if minutesOf("", "H 3 * * *") != minutesOf("H 3 * * *", "H 3 * * *") {
+		t.Error("expected the spec to be the default key")
+	}
+
+	picked := make(map[uint64]bool)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("svc-%d", i)
+		picked[minutesOf(key, "H 3 * * *")] = true
+
+		if bits := minutesOf(key, "H(0-30) 3 * * *"); bits&^getBits(0, 30, 1) != 0 || bits == 0 {
+			t.Errorf("%s: H(0-30) picked %b", key, bits)
+		}
+		bits := minutesOf(key, "H/15 3 * * *")
+		first := uint(0)
+		for bits&(1<<first) == 0 {
+			first++
+		}
+		if first >= 15 || bits != getBits(first, 59, 15) {
+			t.Errorf("%s: H/15 picked %b", key, bits)
+		}
+		bits = minutesOf(key, "H(10-20)/5,45 3 * * *")
+		if bits&^(getBits(10, 20, 1)|1<<45) != 0 || bits&(1<<45) == 0 {
+			t.Errorf("%s: H(10-20)/5,45 picked %b", key, bits)
+		}
+		sched, _ := parser.WithHashKey(key).Parse("0 0 H * *")
+		if dom := sched.(*SpecSchedule).Dom; dom&^getBits(1, 28, 1) != 0 || dom == 0 {
+			t.Errorf("%s: H day of month picked %b", key, dom)
+		}
+	}
+	if len(picked) < 2 {
+		t.Errorf("expected different keys to pick different minutes, got %v", picked)
+	}
+
+	sched, _ := parser.WithHashKey("svc").Parse("H H * * *")
+	if actual := sched.(*SpecSchedule).String(); strings.Contains(actual, "H") {
+		t.Errorf("expected the picked values in the string, got %s", actual)
+	}
+
+	for _, spec := range []string{"H(0-30 3 * * *", "H(0-70) 3 * * *", "H/0 3 * * *", "Hx 3 * * *", "H(0-9/2) 3 * * *"} {
+		if _, err := parser.Parse(spec); err == nil {
+			t.Errorf("expected an error parsing %s", spec)
+		}
+	}
+}
+
+func TestRegisterDescriptor(t *testing.T) {
+	p := NewParser(Minute | Hour | Dom | Month | Dow | Descriptor)
+	daily := func(arg string) (Schedule, error) {
+		return p.Parse("0 " + arg + " * * *")
+	}
+	if err := p.RegisterDescriptor("daily-at", daily); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"hourly", "every", "at", "reboot", "daily-at", "", "two words", "@x"} {
+		if err := p.RegisterDescriptor(name, daily); err == nil {
+			t.Errorf("%q: expected an error registering", name)
+		}
+	}
+	if err := (Parser{}).RegisterDescriptor("x", daily); err == nil {
+		t.Error("expected an error registering on a zero Parser")
+	}
+
+	sched, err := p.Parse("@daily-at 9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := p.Parse("0 9 * * *"); !reflect.DeepEqual(sched, expected) {
+		t.Errorf("(expected) %v != %v (actual)", expected, sched)
+	}
+	// Copies of the parser share the descriptors, and the predefined ones are
+	// unaffected.
+	if _, err := p.WithHashKey("k").Parse("@daily-at 10"); err != nil {
+		t.Error(err)
+	}
+	if _, err := p.Parse("@daily-at x"); err == nil {
+		t.Error("expected the error of the descriptor")
+	}
+	if _, err := p.Parse("@hourly"); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewParser(Minute | Hour | Dom | Month | Dow | Descriptor).Parse("@daily-at 9"); err == nil {
+		t.Error("expected other parsers not to know the descriptor")
+	}
+
+	// Registering while parsing.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			p.RegisterDescriptor(fmt.Sprintf("d%d", i), daily)
+		}(i)
+		go func() {
+			defer wg.Done()
+			p.Parse("@daily-at 9")
+		}()
+	}
+	wg.Wait()
+	if _, err := p.Parse("@d3 12"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	sixFields := NewParser(Second | Minute | Hour | Dom | Month | Dow | Year)
+	fiveFields := NewParser(Minute | Hour | Dom | Month | Dow)
+	optionalSeconds := NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow)
+	tests := []struct {
+		parser Parser
+		spec   string
+		field  string
+		index  int
+		token  string
+		cause  string
+	}{
+		{sixFields, "60 * * * * *", "second", 0, "60", "above maximum"},
+		{sixFields, "* x * * * *", "minute", 1, "x", "Failed to parse int"},
+		{sixFields, "* * 5-25 * * *", "hour", 2, "5-25", "above maximum"},
+		{sixFields, "* * * 32W * *", "day of month", 3, "32W", "must be from 1 to 31"},
+		{sixFields, "* * * 1,L * *", "day of month", 3, "1,L", "L cannot be combined with other days of month"},
+		{sixFields, "* * * L-31 * *", "day of month", 3, "L-31", "must be from 0 to 30"},
+		{sixFields, "* * * * 13 *", "month", 4, "13", "above maximum"},
+		{sixFields, "* * * * * MON#6", "day of week", 5, "MON#6", "must be from 1 to 5"},
+		{sixFields, "* * * * * * 1969", "year", 6, "1969", "below minimum"},
+		{fiveFields, "0 0 1 1 FUNDAY", "day of week", 4, "FUNDAY", "Failed to parse int"},
+		{sixFields, "0 0 0 13 * &", "day of week", 5, "&", "Missing day of week after &"},
+		{fiveFields, "0 H(0-30 * * *", "hour", 1, "H(0-30", "Invalid range of H"},
+		{optionalSeconds, "0 25 * * *", "hour", 1, "25", "above maximum"},
+		{optionalSeconds, "0 0 25 * * *", "hour", 2, "25", "above maximum"},
+
+		// Unsatisfiable
+		{sixFields, "0 0 0 30 2 *", "day of month", 3, "30", "No such day in February"},
+		{sixFields, "0 0 0 31 APR,JUN ?", "day of month", 3, "31", "No such day in April and June"},
+		{sixFields, "0 0 0 30-31/5 FEB *", "day of month", 3, "30-31/5", "No such day in February"},
+		{sixFields, "0 0 0 L-29 FEB *", "day of month", 3, "L-29", "No such day in February"},
+		{sixFields, "0 0 0 29 2 ? 2025-2027", "year", 6, "2025-2027", "never match in these years"},
+	}
+
+	for _, test := range tests {
+		_, err := test.parser.Parse(test.spec)
+		var fieldErr *ParseError
+		if !errors.As(err, &fieldErr) {
+			t.Errorf("%s: expected a ParseError, got %v", test.spec, err)
+			continue
+		}
+		if fieldErr.Field != test.field || fieldErr.Index != test.index || fieldErr.Token != test.token ||
+			!strings.Contains(fieldErr.Err.Error(), test.cause) {
+			t.Errorf("%s: unexpected error %+v", test.spec, *fieldErr)
+		}
+		if err := Validate(test.spec, Second, Minute, Hour, Dom, Month, Dow, Year); test.parser.options == sixFields.options && err == nil {
+			t.Errorf("%s: expected Validate to fail", test.spec)
+		}
+	}
+
+	// Satisfiable if some month or year has the day, or the day of week is
+	// enough.
+	for _, spec := range []string{"0 0 0 29 2 *", "0 0 0 31 APR,MAY *", "0 0 0 30 2 MON", "0 0 0 29 2 * 2027-2028"} {
+		if err := Validate(spec, Second, Minute, Hour, Dom, Month, Dow, Year); err != nil {
+			t.Errorf("%s: unexpected error %v", spec, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cron
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// PlannedRun is an activation of an entry, as planned by DryRun or Plan.
+type PlannedRun struct {
+	EntryID    EntryID
+	Name       string
+	Spec       string
+	Activation time.Time
+}
+
+// DryRun returns the activations of the entries after from, up to from plus
+// horizon, ordered by time. They are computed as the scheduler would run the
+// entries: in the location of each entry, within its activation window, and
+// with its jitter and remaining runs, an entry with an AfterCompletionSchedule
+// being assumed to run instantly. Paused entries are left out. No job is run,
+// and the scheduler need not be started.
+//
+// The jitter delays are drawn from a source of their own, seeded as set with
+// WithJitterSeed, so that the plan is the same from one call to another. They
+// differ from the delays of the scheduler.
+func (c *Cron) DryRun(from time.Time, horizon time.Duration) []PlannedRun {
+	var entries []*Entry
+	c.read(func() {
+		for _, e := range c.entries {
+			if !e.Paused {
+				copied := *e
+				entries = append(entries, &copied)
+			}
+		}
+	})
+	return plan(entries, from.In(c.Location()), horizon, rand.New(rand.NewSource(c.seed)))
+}
+
+// Plan returns the activations of the given specs after from, up to from plus
+// horizon, ordered by time, in the location of from. The specs are parsed as
+// with Parse, or with a Parser created with the given options if there are
+// any. The runs have the position of their spec in specs, plus one, as
+// EntryID, as if the specs were added in order to a new Cron.
+func Plan(specs []string, from time.Time, horizon time.Duration, opts ...ParseOption) ([]PlannedRun, error) {
+	parser := parserFor(opts)
+	entries := make([]*Entry, len(specs))
+	for i, spec := range specs {
+		schedule, err := parser.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = &Entry{ID: EntryID(i + 1), Spec: spec, Schedule: schedule, Location: from.Location()}
+	}
+	return plan(entries, from, horizon, nil), nil
+}
+
+// plan returns the activations of the entries after from, up to from plus
+// horizon, ordered by time then by entry ID. The jitter delays are drawn from
+// r.
+func plan(entries []*Entry, from time.Time, horizon time.Duration, r *rand.Rand) []PlannedRun {
+	end := from.Add(horizon)
+	var runs []PlannedRun
+	for _, e := range entries {
+		e.rand = r
+		e.Schedule = detached(e.Schedule)
+		for n, t := 0, from; !e.maxRuns || n < e.RemainingRuns; n++ {
+			next := e.next(t)
+			if next.IsZero() || next.After(end) || !next.After(t) {
+				break
+			}
+			runs = append(runs, PlannedRun{EntryID: e.ID, Name: e.Name, Spec: e.Spec, Activation: next})
+			t = next
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].Activation.Equal(runs[j].Activation) {
+			return runs[i].Activation.Before(runs[j].Activation)
+		}
+		return runs[i].EntryID < runs[j].EntryID
+	})
+	return runs
+}
@@ -0,0 +1,85 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cron := NewWithLocation(clockwork.NewFakeClock(), ny)
+	job := FuncJob(func() { t.Error("expected no job to run") })
+	daily, _ := cron.AddJob("0 30 2 * * *", job)
+	every, _ := cron.AddJob("@every 12h", job, WithName("every"))
+	once := cron.Schedule(At(time.Date(2025, 3, 9, 3, 30, 0, 0, ny)), job)
+	utc, _ := cron.AddJob("0 0 12 * * *", job, WithLocationFor(time.UTC))
+	paused, _ := cron.AddJob("@every 1m", job)
+	cron.Pause(paused)
+	windowed, _ := cron.AddJob("0 0 * * * *", job,
+		WithStartAt(time.Date(2025, 3, 9, 22, 0, 0, 0, ny)), WithEndAt(time.Date(2025, 3, 9, 23, 0, 0, 0, ny)))
+
+	// The clocks skip from 2:00 to 3:00 on March 9, so the daily entry does
+	// not run that day.
+	runs := cron.DryRun(time.Date(2025, 3, 8, 0, 0, 0, 0, ny), 48*time.Hour)
+	utcTime := func(day, hour, min int) time.Time { return time.Date(2025, 3, day, hour, min, 0, 0, time.UTC) }
+	expected := []PlannedRun{
+		{EntryID: daily, Spec: "0 30 2 * * *", Activation: utcTime(8, 7, 30)},
+		{EntryID: utc, Spec: "0 0 12 * * *", Activation: utcTime(8, 12, 0)},
+		{EntryID: every, Name: "every", Spec: "@every 12h", Activation: utcTime(8, 17, 0)},
+		{EntryID: every, Name: "every", Spec: "@every 12h", Activation: utcTime(9, 5, 0)},
+		{EntryID: once, Activation: utcTime(9, 7, 30)},
+		{EntryID: utc, Spec: "0 0 12 * * *", Activation: utcTime(9, 12, 0)},
+		{EntryID: every, Name: "every", Spec: "@every 12h", Activation: utcTime(9, 17, 0)},
+		{EntryID: windowed, Spec: "0 0 * * * *", Activation: utcTime(10, 2, 0)},
+		{EntryID: windowed, Spec: "0 0 * * * *", Activation: utcTime(10, 3, 0)},
+		{EntryID: every, Name: "every", Spec: "@every 12h", Activation: utcTime(10, 5, 0)},
+	}
+	assert.Len(t, runs, len(expected))
+	assert.Equal(t, "America/New_York", runs[0].Activation.Location().String())
+	assert.Equal(t, "UTC", runs[1].Activation.Location().String())
+	for i := range runs {
+		runs[i].Activation = runs[i].Activation.UTC()
+	}
+	assert.Equal(t, expected, runs)
+	assert.True(t, cron.Entry(daily).Next.IsZero())
+}
+
+func TestDryRunJitter(t *testing.T) {
+	cron := New(clockwork.NewFakeClock(), WithJitterSeed(42))
+	id, _ := cron.AddFunc("@hourly", func() {}, WithJitter(10*time.Minute), WithMaxRuns(3))
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	runs := cron.DryRun(from, 24*time.Hour)
+	assert.Len(t, runs, 3)
+	for i, run := range runs {
+		hour := from.Add(time.Duration(i+1) * time.Hour)
+		assert.Equal(t, id, run.EntryID)
+		assert.False(t, run.Activation.Before(hour))
+		assert.True(t, run.Activation.Before(hour.Add(10*time.Minute)))
+	}
+	assert.Equal(t, runs, cron.DryRun(from, 24*time.Hour))
+}
+
+func TestPlan(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs, err := Plan([]string{"0 0 6 * * *", "@every 8h"}, from, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []PlannedRun{
+		{EntryID: 1, Spec: "0 0 6 * * *", Activation: from.Add(6 * time.Hour)},
+		{EntryID: 2, Spec: "@every 8h", Activation: from.Add(8 * time.Hour)},
+		{EntryID: 2, Spec: "@every 8h", Activation: from.Add(16 * time.Hour)},
+		{EntryID: 2, Spec: "@every 8h", Activation: from.Add(24 * time.Hour)},
+	}, runs)
+
+	runs, err = Plan([]string{"30 6 * * *"}, from, 24*time.Hour, Minute|Hour|Dom|Month|Dow)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+
+	_, err = Plan([]string{"@every 1h", "bad"}, from, time.Hour)
+	assert.Error(t, err)
+}
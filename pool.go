@@ -0,0 +1,105 @@
+package cron
+
+import "sync"
+
+// OverflowPolicy is what a Cron limited with WithMaxConcurrentJobs does with a
+// run when its queue of runs is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock holds back the runs over the limit, in order, until there
+	// is room in the queue for them. Neither the scheduler nor the callers
+	// starting them, such as RunNow, wait for the room.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDrop skips the run, and logs it.
+	OverflowDrop
+
+	// OverflowRun runs the job right away, regardless of the limit.
+	OverflowRun
+)
+
+// jobPool runs jobs with a limited concurrency, queueing the others in the
+// order they were submitted.
+type jobPool struct {
+	mu       sync.Mutex
+	room     *sync.Cond // Signaled when a job leaves the queue.
+	free     int        // Number of jobs that can be started right away.
+	queue    []func()
+	max      int // Maximum length of the queue, or 0 for no limit.
+	overflow OverflowPolicy
+
+	// The turns of the jobs held back with OverflowBlock: the next one to
+	// hand out, and the one of the job to enter the queue next.
+	nextTurn, turn uint64
+}
+
+func newJobPool(size int) *jobPool {
+	p := &jobPool{free: size}
+	p.room = sync.NewCond(&p.mu)
+	return p
+}
+
+// submit runs the job in its own goroutine once there is room for it, and
+// reports whether it was accepted. It never waits for the room.
+func (p *jobPool) submit(job func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// The jobs held back go first.
+	waiting := p.nextTurn != p.turn
+	if p.free > 0 && !waiting {
+		p.free--
+		go p.run(job)
+		return true
+	}
+	if p.max > 0 && (waiting || len(p.queue) >= p.max) {
+		switch p.overflow {
+		case OverflowDrop:
+			return false
+		case OverflowRun:
+			go job()
+			return true
+		}
+		go p.hold(job, p.nextTurn)
+		p.nextTurn++
+		return true
+	}
+	p.queue = append(p.queue, job)
+	return true
+}
+
+// hold waits for the turn of the job held back with OverflowBlock, and for
+// room for it, then starts or queues it.
+func (p *jobPool) hold(job func(), turn uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.turn != turn || (p.free == 0 && len(p.queue) >= p.max) {
+		p.room.Wait()
+	}
+	p.turn++
+	if p.free > 0 {
+		p.free--
+		go p.run(job)
+	} else {
+		p.queue = append(p.queue, job)
+	}
+	p.room.Broadcast()
+}
+
+// run runs the given job, then the queued ones, until the queue is empty.
+func (p *jobPool) run(job func()) {
+	for job != nil {
+		job()
+		p.mu.Lock()
+		if len(p.queue) > 0 {
+			job = p.queue[0]
+			p.queue[0] = nil
+			p.queue = p.queue[1:]
+		} else {
+			job = nil
+			p.free++
+		}
+		p.room.Broadcast()
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,169 @@
+package cron
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxConcurrentJobs(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	cron := New(clock, WithMaxConcurrentJobs(2))
+	var running, max, completed int32
+	for i := 0; i < 5; i++ {
+		cron.AddFunc("* * * * * ?", func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&completed, 1)
+		}, WithMaxRuns(1))
+	}
+	cron.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	cron.Shutdown(context.Background())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&max))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&completed))
+}
+
+func TestMaxConcurrentJobsNoLimit(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		cron := New(clockwork.NewFakeClock(), WithMaxConcurrentJobs(n), WithJobQueueLimit(1, OverflowBlock))
+		release := make(chan struct{})
+		started := make(chan struct{}, 3)
+		id, _ := cron.AddFunc("@daily", func() {
+			started <- struct{}{}
+			<-release
+		})
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, cron.RunNow(id))
+		}
+		for i := 0; i < 3; i++ {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatalf("%d: expected the runs to start without a limit", n)
+			}
+		}
+		close(release)
+		cron.Shutdown(context.Background())
+		assert.Equal(t, uint64(3), cron.Entry(id).Stats.Runs)
+	}
+}
+
+func TestJobQueueLimit(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	job := func() {
+		started <- struct{}{}
+		<-release
+	}
+	tests := []struct {
+		overflow   OverflowPolicy
+		limitFirst bool
+		runs       int
+		skipped    int
+	}{
+		{OverflowDrop, false, 2, 1},
+		{OverflowDrop, true, 2, 1},
+		{OverflowRun, false, 3, 0},
+	}
+	for _, test := range tests {
+		release = make(chan struct{})
+		var skipped int32
+		opts := []Option{WithMaxConcurrentJobs(1), WithJobQueueLimit(1, test.overflow)}
+		if test.limitFirst {
+			opts[0], opts[1] = opts[1], opts[0]
+		}
+		cron := New(clockwork.NewFakeClock(), opts...)
+		cron.OnJobSkipped(func(EntryID, time.Time) { atomic.AddInt32(&skipped, 1) })
+		id, _ := cron.AddFunc("@daily", job, WithHistory(3))
+		cron.RunNow(id)
+		<-started
+		cron.RunNow(id)
+		cron.RunNow(id)
+		close(release)
+		for i := 1; i < test.runs; i++ {
+			<-started
+		}
+		cron.Shutdown(context.Background())
+		assert.Equal(t, uint64(test.runs), cron.Entry(id).Stats.Runs)
+		assert.Equal(t, int32(test.skipped), atomic.LoadInt32(&skipped))
+		assert.Len(t, cron.History(id), test.runs+test.skipped)
+	}
+}
+
+func TestJobQueueBlock(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan int, 4)
+	cron := New(clockwork.NewFakeClock(), WithMaxConcurrentJobs(1), WithJobQueueLimit(1, OverflowBlock))
+	var ids [4]EntryID
+	for i := range ids {
+		i := i
+		ids[i], _ = cron.AddFunc("@daily", func() {
+			started <- i
+			<-release
+		})
+	}
+	returned := make(chan struct{})
+	go func() {
+		for _, id := range ids {
+			cron.RunNow(id)
+		}
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("expected RunNow not to wait for room in the queue")
+	}
+	// One run in progress, one queued, and the others held back.
+	cron.pool.mu.Lock()
+	assert.Len(t, cron.pool.queue, 1)
+	assert.Equal(t, uint64(2), cron.pool.nextTurn-cron.pool.turn)
+	cron.pool.mu.Unlock()
+	close(release)
+	for i := range ids {
+		assert.Equal(t, i, <-started)
+	}
+	cron.Shutdown(context.Background())
+	for _, id := range ids {
+		assert.Equal(t, uint64(1), cron.Entry(id).Stats.Runs)
+	}
+}
+
+func TestJobQueueBlockRunNowFromJob(t *testing.T) {
+	cron := New(clockwork.NewFakeClock(), WithMaxConcurrentJobs(1), WithJobQueueLimit(1, OverflowBlock))
+	var runs int32
+	var id EntryID
+	id, _ = cron.AddFunc("@daily", func() {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			// The second run fills the queue, the third is over its limit.
+			cron.RunNow(id)
+			cron.RunNow(id)
+		}
+	})
+	cron.RunNow(id)
+	done := make(chan struct{})
+	go func() {
+		cron.Shutdown(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a job to call RunNow while the pool is full")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+}
@@ -0,0 +1,24 @@
+package cron
+
+import "time"
+
+// RebootSchedule is the schedule of the @reboot descriptor: an entry with this
+// schedule runs once each time the Cron starts, or when it is added to a
+// running Cron, as with WithRunOnStart, and never on a schedule.
+type RebootSchedule struct{}
+
+// Next returns the zero time, as the schedule is never activated on its own.
+func (RebootSchedule) Next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+// Equal reports whether other is a RebootSchedule.
+func (RebootSchedule) Equal(other Schedule) bool {
+	_, ok := other.(RebootSchedule)
+	return ok
+}
+
+// String returns the descriptor for the schedule, "@reboot".
+func (RebootSchedule) String() string {
+	return "@reboot"
+}
@@ -1,11 +1,23 @@
 package cron
 
-import "time"
+import (
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // SpecSchedule specifies a duty cycle (to the second granularity), based on a
 // traditional crontab specification. It is computed initially and stored as bit sets.
 type SpecSchedule struct {
 	Second, Minute, Hour, Dom, Month, Dow uint64
+
+	// LastDom is set if the day of month field is "L" or "L-n": the day of
+	// month matches LastDomOffset (n) days before the last day of the month,
+	// and Dom has no bits set.
+	LastDom       bool
+	LastDomOffset uint
 }
 
 // bounds provides a range of acceptable values (plus a map of name to value).
@@ -43,11 +55,29 @@ var (
 		"fri": 5,
 		"sat": 6,
 	}}
+	years = bounds{1970, 2099, nil}
 )
 
 const (
 	// Set the top bit if a star was included in the expression.
 	starBit = 1 << 63
+
+	// Set in the day of week field if both day fields must match.
+	strictBit = 1 << 62
+
+	// The day of month field has the bit weekdayShift+day set for each day
+	// whose nearest weekday matches ("15W").
+	weekdayShift = 31
+
+	// The day of week field has the bit nthShift+7*(n-1)+weekday set for each
+	// nth weekday of the month that matches ("TUE#2").
+	nthShift = 7
+)
+
+// The bits of the day fields set by W and # expressions.
+var (
+	weekdayBits = getBits(weekdayShift+dom.min, weekdayShift+dom.max, 1)
+	nthBits     = getBits(nthShift, nthShift+7*5-1, 1)
 )
 
 // Next returns the next time this schedule is activated, greater than the given
@@ -64,11 +94,22 @@ func (s *SpecSchedule) Next(t time.Time) time.Time {
 	// Start at the earliest possible time (the upcoming second).
 	t = t.Add(1*time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
 
+	// If the upcoming second matches, as with dense schedules, return it
+	// without checking the fields one at a time.
+	year, month, _ := t.Date()
+	hour, minute, second := t.Clock()
+	if 1<<uint(month)&s.Month > 0 && 1<<uint(hour)&s.Hour > 0 && 1<<uint(minute)&s.Minute > 0 &&
+		1<<uint(second)&s.Second > 0 && dayMatches(s, t) {
+		return t
+	}
+
 	// This flag indicates whether a field has been incremented.
 	added := false
 
 	// If no time is found within five years, return zero.
-	yearLimit := t.Year() + 5
+	yearLimit := year + 5
+
+	var wrapped bool
 
 WRAP:
 	if t.Year() > yearLimit {
@@ -93,50 +134,42 @@ WRAP:
 	}
 
 	// Now get a day in that month.
-	for !dayMatches(s, t) {
+	if !dayMatches(s, t) {
 		if !added {
 			added = true
 			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 		}
-		t = t.AddDate(0, 0, 1)
-
-		if t.Day() == 1 {
+		if t, wrapped = advanceDay(s, t); wrapped {
 			goto WRAP
 		}
 	}
 
-	for 1<<uint(t.Hour())&s.Hour == 0 {
+	if 1<<uint(t.Hour())&s.Hour == 0 {
 		if !added {
 			added = true
 			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
 		}
-		t = t.Add(1 * time.Hour)
-
-		if t.Hour() == 0 {
+		if t, wrapped = advance(t, s.Hour, hours.max, time.Hour, time.Time.Hour); wrapped {
 			goto WRAP
 		}
 	}
 
-	for 1<<uint(t.Minute())&s.Minute == 0 {
+	if 1<<uint(t.Minute())&s.Minute == 0 {
 		if !added {
 			added = true
 			t = t.Truncate(time.Minute)
 		}
-		t = t.Add(1 * time.Minute)
-
-		if t.Minute() == 0 {
+		if t, wrapped = advance(t, s.Minute, minutes.max, time.Minute, time.Time.Minute); wrapped {
 			goto WRAP
 		}
 	}
 
-	for 1<<uint(t.Second())&s.Second == 0 {
+	if 1<<uint(t.Second())&s.Second == 0 {
 		if !added {
 			added = true
 			t = t.Truncate(time.Second)
 		}
-		t = t.Add(1 * time.Second)
-
-		if t.Second() == 0 {
+		if t, wrapped = advance(t, s.Second, seconds.max, time.Second, time.Time.Second); wrapped {
 			goto WRAP
 		}
 	}
@@ -144,15 +177,318 @@ WRAP:
 	return t
 }
 
+// advanceDay adds days to t until the schedule matches its day, or it wraps
+// around to the first day of the next month, in which case it returns true.
+// Like advance, it adds them all at once without a change of time zone offset
+// on the way, provided the time of day is kept too, which it is not when the
+// date falls in a gap of the time zone.
+func advanceDay(s *SpecSchedule, t time.Time) (time.Time, bool) {
+	day, weekday, last := t.Day(), t.Weekday(), lastDay(t)
+	next := day + 1
+	for next <= last && !dayMatchesIn(s, next, (weekday+time.Weekday(next-day))%7, last) {
+		next++
+	}
+	target := t.AddDate(0, 0, next-day)
+	if _, end := t.ZoneBounds(); (end.IsZero() || target.Before(end)) && target.Sub(t) == time.Duration(next-day)*24*time.Hour {
+		return target, next > last
+	}
+	for {
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			return t, true
+		}
+		if dayMatches(s, t) {
+			return t, false
+		}
+	}
+}
+
+// advance adds unit to t until the field of t, from 0 to max, is in set, or
+// wraps around to 0, in which case it returns true. Without a change of time
+// zone offset on the way, the field grows by one with each unit, so it adds
+// them all at once, up to the next value in set.
+func advance(t time.Time, set uint64, max uint, unit time.Duration, field func(time.Time) int) (time.Time, bool) {
+	value := uint(field(t))
+	next := max + 1
+	if rest := set & (1<<(max+1) - 1) >> (value + 1) << (value + 1); rest > 0 {
+		next = uint(bits.TrailingZeros64(rest))
+	}
+	target := t.Add(time.Duration(next-value) * unit)
+	if _, end := t.ZoneBounds(); end.IsZero() || target.Before(end) {
+		return target, next > max
+	}
+	for {
+		t = t.Add(unit)
+		if field(t) == 0 {
+			return t, true
+		}
+		if 1<<uint(field(t))&set > 0 {
+			return t, false
+		}
+	}
+}
+
+// YearSchedule is a SpecSchedule restricted to some years, as parsed from a
+// spec with a year field.
+type YearSchedule struct {
+	Schedule *SpecSchedule
+
+	// The years the schedule is activated in, sorted.
+	Years []int
+}
+
+// Next returns the next time this schedule is activated, greater than the given
+// time.  If no time can be found to satisfy the schedule, return the zero time.
+func (s *YearSchedule) Next(t time.Time) time.Time {
+	for {
+		i := sort.SearchInts(s.Years, t.Year())
+		if i == len(s.Years) {
+			return time.Time{}
+		}
+		if year := s.Years[i]; year > t.Year() {
+			// Start from the beginning of the next year of the schedule.
+			t = time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location()).Add(-time.Second)
+		}
+		next := s.Schedule.Next(t)
+		if next.IsZero() {
+			return next
+		}
+		i = sort.SearchInts(s.Years, next.Year())
+		if i < len(s.Years) && s.Years[i] == next.Year() {
+			return next
+		}
+		t = time.Date(next.Year()+1, time.January, 1, 0, 0, 0, 0, t.Location()).Add(-time.Second)
+	}
+}
+
+// Equal reports whether other is a YearSchedule with an equal schedule in the
+// same years.
+func (s *YearSchedule) Equal(other Schedule) bool {
+	o, ok := other.(*YearSchedule)
+	if !ok || !s.Schedule.Equal(o.Schedule) || len(s.Years) != len(o.Years) {
+		return false
+	}
+	for i := range s.Years {
+		if s.Years[i] != o.Years[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the spec of the schedule, with its years as a seventh field.
+func (s *YearSchedule) String() string {
+	var parts []string
+	for i := 0; i < len(s.Years); {
+		j := i
+		for j+1 < len(s.Years) && s.Years[j+1] == s.Years[j]+1 {
+			j++
+		}
+		if j == i {
+			parts = append(parts, strconv.Itoa(s.Years[i]))
+		} else {
+			parts = append(parts, strconv.Itoa(s.Years[i])+"-"+strconv.Itoa(s.Years[j]))
+		}
+		i = j + 1
+	}
+	return s.Schedule.String() + " " + strings.Join(parts, ",")
+}
+
 // dayMatches returns true if the schedule's day-of-week and day-of-month
 // restrictions are satisfied by the given time.
 func dayMatches(s *SpecSchedule, t time.Time) bool {
+	last := 0
+	if s.LastDom || s.Dom&weekdayBits > 0 {
+		last = lastDay(t)
+	}
+	return dayMatchesIn(s, t.Day(), t.Weekday(), last)
+}
+
+// dayMatchesIn is like dayMatches, for the given day of month falling on the
+// given weekday, in a month whose last day is last. The last day is only used
+// by L and W days.
+func dayMatchesIn(s *SpecSchedule, day int, weekday time.Weekday, last int) bool {
 	var (
-		domMatch bool = 1<<uint(t.Day())&s.Dom > 0
-		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0
+		nth      = uint(day-1) / 7
+		domMatch = 1<<uint(day)&s.Dom > 0 || s.Dom&weekdayBits > 0 && nearestWeekdayMatches(s.Dom, day, weekday, last) ||
+			s.LastDom && day+int(s.LastDomOffset) == last
+		dowMatch = 1<<uint(weekday)&s.Dow > 0 || 1<<(nthShift+7*nth+uint(weekday))&s.Dow > 0
 	)
-	if s.Dom&starBit > 0 || s.Dow&starBit > 0 {
+	return daysMatch(s.Dom, s.Dow, domMatch, dowMatch)
+}
+
+// dayBitsMatch returns true if the given day of month and day of week satisfy
+// the day-of-month and day-of-week fields, not counting their W and # items.
+func dayBitsMatch(domBits, dowBits uint64, day, weekday uint) bool {
+	return daysMatch(domBits, dowBits, 1<<day&domBits > 0, 1<<weekday&dowBits > 0)
+}
+
+// daysMatch combines whether the day-of-month and day-of-week fields match.
+//
+// When both fields are restricted, either one matching is enough (as in Vixie
+// cron), unless the schedule was parsed with StrictDow.
+func daysMatch(domBits, dowBits uint64, domMatch, dowMatch bool) bool {
+	if domBits&starBit > 0 || dowBits&(starBit|strictBit) > 0 {
 		return domMatch && dowMatch
 	}
 	return domMatch || dowMatch
 }
+
+// lastDay returns the last day of the month of t.
+func lastDay(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// nearestWeekdayMatches returns true if the given day, falling on the given
+// weekday in a month whose last day is last, is the weekday nearest to one of
+// the W days of the day of month bits. A W day falling on a Saturday moves
+// to the Friday before, and one falling on a Sunday to the Monday after,
+// unless that crosses into another month: 1W moves from a Saturday to Monday
+// the 3rd, and the last day from a Sunday to the Friday before. A W day the
+// month does not have is not activated.
+func nearestWeekdayMatches(domBits uint64, day int, weekday time.Weekday, last int) bool {
+	for w := dom.min; w <= uint(last); w++ {
+		if domBits&(1<<(weekdayShift+w)) == 0 {
+			continue
+		}
+		nearest := int(w)
+		switch time.Weekday((int(weekday) + nearest - day + 35) % 7) {
+		case time.Saturday:
+			if nearest == 1 {
+				nearest = 3
+			} else {
+				nearest--
+			}
+		case time.Sunday:
+			if nearest == last {
+				nearest -= 2
+			} else {
+				nearest++
+			}
+		}
+		if nearest == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal reports whether other is a SpecSchedule activating at exactly the same
+// times, however either was written. For example "0 */20 * * * *" is equal to
+// "0 0,20,40 * * * *".
+func (s *SpecSchedule) Equal(other Schedule) bool {
+	o, ok := other.(*SpecSchedule)
+	if !ok {
+		return false
+	}
+	const valueBits = ^uint64(starBit | strictBit)
+	if s.Second&valueBits != o.Second&valueBits ||
+		s.Minute&valueBits != o.Minute&valueBits ||
+		s.Hour&valueBits != o.Hour&valueBits ||
+		s.Month&valueBits != o.Month&valueBits ||
+		s.Dom&weekdayBits != o.Dom&weekdayBits ||
+		s.Dow&nthBits != o.Dow&nthBits ||
+		s.LastDom != o.LastDom || s.LastDomOffset != o.LastDomOffset {
+		return false
+	}
+	for day := dom.min; day <= dom.max; day++ {
+		for weekday := dow.min; weekday <= dow.max; weekday++ {
+			if dayBitsMatch(s.Dom, s.Dow, day, weekday) != dayBitsMatch(o.Dom, o.Dow, day, weekday) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String returns a normalized six-field spec for the schedule, e.g.
+// "0 0-10,30 9-17 * * 1-5", which parses back to the same schedule. The spec
+// is built by these rules, so that it is stable:
+//   - Values are numeric, in ascending order, with consecutive values
+//     collapsed into ranges.
+//   - A field covering a step from its minimum starts with "*/step", using the
+//     smallest such step, followed by the other values. A step of 1 is "*".
+//   - The day fields only use "*" if they were written with "*" or "?", since
+//     it changes how they combine.
+//   - W and # items come after the other values of their field.
+//   - A day of month field counting back from the last day is "L" or "L-n".
+//   - A day of week field that must match along with a restricted day of
+//     month field, as parsed with StrictDow, starts with "&".
+func (s *SpecSchedule) String() string {
+	domString := dayFieldString(s.Dom, dom, weekdayBits, func(bit uint) string {
+		return strconv.Itoa(int(bit-weekdayShift)) + "W"
+	})
+	if s.LastDom {
+		domString = "L"
+		if s.LastDomOffset > 0 {
+			domString += "-" + strconv.Itoa(int(s.LastDomOffset))
+		}
+	}
+	dowString := dayFieldString(s.Dow, dow, nthBits, func(bit uint) string {
+		n := bit - nthShift
+		return strconv.Itoa(int(n%7)) + "#" + strconv.Itoa(int(n/7+1))
+	})
+	if s.Dow&strictBit > 0 && s.Dow&starBit == 0 && s.Dom&starBit == 0 {
+		dowString = "&" + dowString
+	}
+	return strings.Join([]string{
+		fieldString(s.Second, seconds, false),
+		fieldString(s.Minute, minutes, false),
+		fieldString(s.Hour, hours, false),
+		domString,
+		fieldString(s.Month, months, false),
+		dowString,
+	}, " ")
+}
+
+// fieldString returns the normalized expression of a field's bits. Day fields
+// only use "*" if the star bit is set, since it changes how they combine.
+func fieldString(bits uint64, r bounds, dayField bool) string {
+	var items []string
+	full := getBits(r.min, r.max, 1)
+	if bits&starBit > 0 || !dayField && bits&full == full {
+		// Use the smallest "*/step" covered by the field, and list the
+		// remaining values after it.
+		for step := uint(1); step <= r.max-r.min+1; step++ {
+			if star := getBits(r.min, r.max, step); bits&star == star {
+				if step == 1 {
+					return "*"
+				}
+				items = append(items, "*/"+strconv.Itoa(int(step)))
+				bits &^= star
+				break
+			}
+		}
+	}
+	for v := r.min; v <= r.max; v++ {
+		if bits&(1<<v) == 0 {
+			continue
+		}
+		end := v
+		for end < r.max && bits&(1<<(end+1)) > 0 {
+			end++
+		}
+		if end == v {
+			items = append(items, strconv.Itoa(int(v)))
+		} else {
+			items = append(items, strconv.Itoa(int(v))+"-"+strconv.Itoa(int(end)))
+		}
+		v = end
+	}
+	return strings.Join(items, ",")
+}
+
+// dayFieldString returns the normalized expression of a day field, with the
+// items for its W or # bits, formatted by item, after its plain values.
+func dayFieldString(bits uint64, r bounds, special uint64, item func(bit uint) string) string {
+	var items []string
+	if plain := fieldString(bits&^special, r, true); plain != "" {
+		items = append(items, plain)
+	}
+	for bit := uint(0); bit < 63; bit++ {
+		if bits&special&(1<<bit) > 0 {
+			items = append(items, item(bit))
+		}
+	}
+	return strings.Join(items, ",")
+}
@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -70,6 +71,66 @@ func TestActivation(t *testing.T) {
 	}
 }
 
+func TestDayFieldsSemantics(t *testing.T) {
+	strictParser := NewParser(Minute | Hour | Dom | Month | Dow | StrictDow)
+	tests := []struct {
+		time          string
+		union, strict bool
+	}{
+		{"Fri Jul 6 00:00 2012", true, false},
+		{"Fri Jul 13 00:00 2012", true, true},
+		{"Mon Aug 13 00:00 2012", true, false},
+		{"Mon Aug 6 00:00 2012", false, false},
+	}
+
+	union, _ := ParseStandard("0 0 13 * 5")
+	strict, _ := strictParser.Parse("0 0 13 * 5")
+	for _, test := range tests {
+		expected := getTime(test.time)
+		before := expected.Add(-1 * time.Second)
+		if actual := union.Next(before); test.union != (actual == expected) {
+			t.Errorf("union on %s: next %s", test.time, actual)
+		}
+		if actual := strict.Next(before); test.strict != (actual == expected) {
+			t.Errorf("strict on %s: next %s", test.time, actual)
+		}
+	}
+
+	// A star in either field behaves the same in both modes.
+	strict, _ = strictParser.Parse("0 0 * * 5")
+	if actual := strict.Next(getTime("Mon Jul 9 00:00 2012")); actual != getTime("Fri Jul 13 00:00 2012") {
+		t.Errorf("strict with star: next %s", actual)
+	}
+	if actual := strict.(*SpecSchedule).String(); actual != "0 0 0 * * 5" {
+		t.Errorf("strict with star: string %q", actual)
+	}
+
+	// A day of week starting with & is strict with any parser, and the
+	// String of a strict schedule keeps it so.
+	strict, _ = strictParser.Parse("0 0 13 * 5")
+	if actual := strict.(*SpecSchedule).String(); actual != "0 0 0 13 * &5" {
+		t.Errorf("strict: string %q", actual)
+	}
+	for _, spec := range []string{"0 0 13 * &5", "0 0 13 * &FRI"} {
+		amp, err := ParseStandard(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !amp.(*SpecSchedule).Equal(strict) {
+			t.Errorf("%q: not equal to the strict schedule", spec)
+		}
+		if actual := amp.Next(getTime("Fri Jul 6 00:00 2012")); actual != getTime("Fri Jul 13 00:00 2012") {
+			t.Errorf("%q: next %s", spec, actual)
+		}
+	}
+
+	// Descriptors are not affected.
+	daily, _ := NewParser(Minute | Hour | Dom | Month | Dow | Descriptor | StrictDow).Parse("@daily")
+	if actual := daily.(*SpecSchedule).String(); actual != "0 0 0 * * *" {
+		t.Errorf("strict descriptor: string %q", actual)
+	}
+}
+
 func TestNext(t *testing.T) {
 	runs := []struct {
 		time, spec string
@@ -94,6 +155,16 @@ func TestNext(t *testing.T) {
 		{"Mon Jul 9 23:35:51 2012", "15/35 20-35/15 * 9-20 * *", "Wed Jul 10 00:20:15 2012"},
 		{"Mon Jul 9 23:35:51 2012", "15/35 20-35/15 * 9-20 Jul *", "Wed Jul 10 00:20:15 2012"},
 
+		// Ranges wrapping around the end of the field
+		{"Fri Jul 13 12:00 2012", "0 0 9 * * SAT-SUN", "Sat Jul 14 09:00 2012"},
+		{"Sat Jul 14 12:00 2012", "0 0 9 * * 6-7", "Sun Jul 15 09:00 2012"},
+		{"Sun Jul 15 12:00 2012", "0 0 9 * * FRI-MON", "Mon Jul 16 09:00 2012"},
+		{"Mon Jul 16 12:00 2012", "0 0 9 * * FRI-MON", "Fri Jul 20 09:00 2012"},
+		{"Mon Jul 9 23:00 2012", "0 0 22-2/2 * * *", "Tue Jul 10 00:00 2012"},
+		{"Sat Nov 10 00:00 2012", "0 0 0 1 DEC-FEB *", "Sat Dec 1 00:00 2012"},
+		{"Sat Dec 1 12:00 2012", "0 0 0 1 DEC-FEB *", "Tue Jan 1 00:00 2013"},
+		{"Fri Feb 1 12:00 2013", "0 0 0 1 DEC-FEB *", "Sun Dec 1 00:00 2013"},
+
 		// Wrap around months
 		{"Mon Jul 9 23:35 2012", "0 0 0 9 Apr-Oct ?", "Thu Aug 9 00:00 2012"},
 		{"Mon Jul 9 23:35 2012", "0 0 0 */5 Apr,Aug,Oct Mon", "Mon Aug 6 00:00 2012"},
@@ -146,10 +217,6 @@ func TestNext(t *testing.T) {
 		// 3am nightly job
 		{"2012-11-04T00:00:00-0400", "0 0 3 * * ?", "2012-11-04T03:00:00-0500"},
 		{"2012-11-04T03:00:00-0500", "0 0 3 * * ?", "2012-11-05T03:00:00-0500"},
-
-		// Unsatisfiable
-		{"Mon Jul 9 23:35 2012", "0 0 0 30 Feb ?", ""},
-		{"Mon Jul 9 23:35 2012", "0 0 0 31 Apr ?", ""},
 	}
 
 	for _, c := range runs {
@@ -164,6 +231,281 @@ func TestNext(t *testing.T) {
 			t.Errorf("%s, \"%s\": (expected) %v != %v (actual)", c.time, c.spec, expected, actual)
 		}
 	}
+
+	// Unsatisfiable, as the parser rejects "0 0 0 30 Feb ?" and "0 0 0 31 Apr ?".
+	for _, sched := range []*SpecSchedule{
+		{Second: 1, Minute: 1, Hour: 1, Dom: 1 << 30, Month: 1 << 2, Dow: all(dow)},
+		{Second: 1, Minute: 1, Hour: 1, Dom: 1 << 31, Month: 1 << 4, Dow: all(dow)},
+	} {
+		if actual := sched.Next(getTime("Mon Jul 9 23:35 2012")); !actual.IsZero() {
+			t.Errorf("%v: (expected) zero time != %v (actual)", sched, actual)
+		}
+	}
+}
+
+func TestNextMatchesReference(t *testing.T) {
+	var locations []*time.Location
+	for _, name := range []string{"UTC", "America/New_York", "Europe/London", "Australia/Lord_Howe", "America/Sao_Paulo", "Asia/Kolkata", "Africa/Monrovia", "Asia/Kathmandu"} {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		locations = append(locations, loc)
+	}
+
+	// Changes of offset at midnight, found in larger corpora.
+	for _, test := range []struct{ spec, start, location string }{
+		{"6,11,14,17-18,41,43,48 */33,15,18,20,29,39 20,22 30 1 5", "1971-09-05T18:07:35-00:44", "Africa/Monrovia"},
+		{"* 1,16,23,33-34,39,48,55,57 15 L-6 1,3,7-10 */7,1-2", "1964-11-26T06:30:00-03:00", "America/Sao_Paulo"},
+	} {
+		sched, _ := Parse(test.spec)
+		loc, _ := time.LoadLocation(test.location)
+		start, _ := time.Parse(time.RFC3339, test.start)
+		if expected, actual := nextReference(sched.(*SpecSchedule), start.In(loc)), sched.Next(start.In(loc)); actual != expected {
+			t.Errorf("%s from %s: (expected) %s != %s (actual)", test.spec, test.start, expected, actual)
+		}
+	}
+
+	// Random schedules, from dense to sparse, with the day fields a parsed
+	// spec may have.
+	rnd := rand.New(rand.NewSource(1))
+	field := func(r bounds) uint64 {
+		var bits uint64
+		n := []int{1, 2, 10, int(r.max - r.min + 1)}[rnd.Intn(4)]
+		for bits == 0 {
+			for v := r.min; v <= r.max; v++ {
+				if rnd.Intn(n) == 0 {
+					bits |= 1 << v
+				}
+			}
+		}
+		if rnd.Intn(4) == 0 {
+			bits |= starBit
+		}
+		return bits
+	}
+	for i := 0; i < 5000; i++ {
+		sched := &SpecSchedule{
+			Second: field(seconds),
+			Minute: field(minutes),
+			Hour:   field(hours),
+			Dom:    field(dom),
+			Month:  field(months),
+			Dow:    field(dow),
+		}
+		switch rnd.Intn(8) {
+		case 0:
+			sched.Dom = 1 << (weekdayShift + uint(rnd.Intn(31)+1))
+		case 1:
+			sched.Dow = 1 << (nthShift + uint(rnd.Intn(35)))
+		case 2:
+			sched.Dom, sched.LastDom, sched.LastDomOffset = 0, true, uint(rnd.Intn(28))
+		case 3:
+			sched.Dow |= strictBit
+		}
+		for j := 0; j < 5; j++ {
+			loc := locations[rnd.Intn(len(locations))]
+			start := time.Unix(rnd.Int63n(200*365*24*3600)-70*365*24*3600, rnd.Int63n(2e9)-1e9).In(loc)
+			if _, end := start.ZoneBounds(); !end.IsZero() && rnd.Intn(2) == 0 {
+				// Up to 79 days before a change of offset.
+				start = end.Add(time.Duration(rnd.Int63n(int64(80*24*time.Hour))) - 79*24*time.Hour)
+			}
+			expected, actual := nextReference(sched, start), sched.Next(start)
+			if actual != expected {
+				t.Fatalf("%s from %s: (expected) %s != %s (actual)", sched, start, expected, actual)
+			}
+		}
+	}
+}
+
+func TestNextSequence(t *testing.T) {
+	runs := []struct {
+		time, spec string
+		expected   []string
+	}{
+		{"Mon Jul 9 14:59 2012", "0 5/15 * * * *", []string{
+			"Mon Jul 9 15:05 2012", "Mon Jul 9 15:20 2012", "Mon Jul 9 15:35 2012",
+			"Mon Jul 9 15:50 2012", "Mon Jul 9 16:05 2012",
+		}},
+		{"Mon Jul 9 14:45 2012", "0 10-40/10 * * * *", []string{
+			"Mon Jul 9 15:10 2012", "Mon Jul 9 15:20 2012", "Mon Jul 9 15:30 2012",
+			"Mon Jul 9 15:40 2012", "Mon Jul 9 16:10 2012",
+		}},
+	}
+
+	for _, c := range runs {
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		next := getTime(c.time)
+		for _, e := range c.expected {
+			next = sched.Next(next)
+			if expected := getTime(e); !next.Equal(expected) {
+				t.Errorf("%s, \"%s\": (expected) %v != %v (actual)", c.time, c.spec, expected, next)
+			}
+		}
+	}
+}
+
+func TestSpecScheduleEqual(t *testing.T) {
+	tests := []struct {
+		spec1, spec2 string
+		expected     bool
+	}{
+		{"0 */20 * * * *", "0 0,20,40 * * * *", true},
+		{"0 0 9-17 * * MON-FRI", "0 0 9,10,11,12,13,14,15,16,17 ? * 1-5", true},
+		{"0-59 * * * * *", "* * * * * *", true},
+		{"0 0 0 1 * *", "@monthly", true},
+		{"* * * 1-31 * 1", "* * * * * 0-6", true},
+		{"0 0 0 * * 1", "0 0 0 1-31 * 1", false},
+		{"0 0 0 13 * 5", "0 0 0 13 * *", false},
+		{"0 30 * * * *", "0 31 * * * *", false},
+		{"0 30 * * * *", "@every 30m", false},
+		{"0 0 0 15W * ?", "0 0 0 15w * *", true},
+		{"0 0 0 15W * ?", "0 0 0 15 * ?", false},
+		{"0 0 0 ? * TUE#2", "0 0 0 * * 2#2", true},
+		{"0 0 0 ? * TUE#2", "0 0 0 ? * TUE#3", false},
+		{"0 0 23 L * ?", "0 0 23 l * *", true},
+		{"0 0 23 L * ?", "0 0 23 L-0 * ?", true},
+		{"0 0 23 L * ?", "0 0 23 L-1 * ?", false},
+		{"0 0 23 L * ?", "0 0 23 31 * ?", false},
+	}
+
+	for _, test := range tests {
+		sched1, err := Parse(test.spec1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sched2, err := Parse(test.spec2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := sched1.(*SpecSchedule).Equal(sched2); actual != test.expected {
+			t.Errorf("%q equal to %q: (expected) %v != %v (actual)", test.spec1, test.spec2, test.expected, actual)
+		}
+	}
+}
+
+func TestSpecScheduleString(t *testing.T) {
+	tests := []struct {
+		spec, expected string
+	}{
+		{"0 30 * * * *", "0 30 * * * *"},
+		{"0 */20 * * * *", "0 */20 * * * *"},
+		{"0 0,10,20 * * * *", "0 0,10,20 * * * *"},
+		{"0-59 30,0-10 9-17 * * MON-FRI", "* 0-10,30 9-17 * * 1-5"},
+		{"0 0 0 */10,5 * ?", "0 0 0 */10,5 * *"},
+		{"0 0 0 1-31 * 1", "0 0 0 1-31 * 1"},
+		{"@weekly", "0 0 0 * * 0"},
+		{"0 0 9 15W * ?", "0 0 9 15W * *"},
+		{"0 0 9 ? * fri#5", "0 0 9 * * 5#5"},
+		{"0 0 23 l * ?", "0 0 23 L * *"},
+		{"0 0 23 L-0 * ?", "0 0 23 L * *"},
+		{"0 0 23 L-2 * 1-5", "0 0 23 L-2 * 1-5"},
+	}
+
+	for _, test := range tests {
+		sched, err := Parse(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := sched.(*SpecSchedule).String()
+		if actual != test.expected {
+			t.Errorf("%q: (expected) %q != %q (actual)", test.spec, test.expected, actual)
+		}
+		reparsed, err := Parse(actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sched.(*SpecSchedule).Equal(reparsed) {
+			t.Errorf("%q: %q is not equal once parsed", test.spec, actual)
+		}
+	}
+}
+
+func TestSpecScheduleStringRoundTrip(t *testing.T) {
+	const valueBits = ^uint64(starBit | strictBit)
+	check := func(sched *SpecSchedule) {
+		spec := sched.String()
+		reparsed, err := Parse(spec)
+		if err != nil {
+			t.Errorf("%q: %s", spec, err)
+			return
+		}
+		actual := reparsed.(*SpecSchedule)
+		// Only the day fields keep their star bit.
+		if actual.Second&valueBits != sched.Second&valueBits ||
+			actual.Minute&valueBits != sched.Minute&valueBits ||
+			actual.Hour&valueBits != sched.Hour&valueBits ||
+			actual.Dom != sched.Dom ||
+			actual.Month&valueBits != sched.Month&valueBits ||
+			actual.Dow != sched.Dow ||
+			actual.LastDom != sched.LastDom ||
+			actual.LastDomOffset != sched.LastDomOffset {
+			t.Errorf("%q: (expected) %+v != %+v (actual)", spec, *sched, *actual)
+		}
+		if again := actual.String(); again != spec {
+			t.Errorf("%q: string not stable, got %q", spec, again)
+		}
+	}
+
+	for _, spec := range []string{
+		"* * * * * *", "0 0 0 1 1 *", "0 */5 * * * *", "0 5/15 * * * *", "0 0 9-17/2 * * MON-FRI",
+		"0 0 0 ? * SUN,SAT", "0 0 0 */3,2 * ?", "0 0 0 15W * ?", "0 0 0 ? * 5#3",
+		"1,3,5,7,9 0 0 1-31 JAN-DEC 0-6", "@daily", "@weekly", "0 0 0 13 * 5",
+		"0 0 23 L * ?", "0 0 23 L-2 * MON", "0 0 0 13 * &5",
+	} {
+		sched, err := Parse(spec)
+		if err != nil {
+			continue
+		}
+		check(sched.(*SpecSchedule))
+	}
+
+	// Random schedules, with the fields a parsed spec may have.
+	rnd := rand.New(rand.NewSource(1))
+	field := func(r bounds) uint64 {
+		var bits uint64
+		for bits == 0 {
+			for v := r.min; v <= r.max; v++ {
+				if rnd.Intn(3) == 0 {
+					bits |= 1 << v
+				}
+			}
+		}
+		return bits
+	}
+	dayField := func(r bounds) uint64 {
+		if rnd.Intn(3) == 0 {
+			return getBits(r.min, r.max, uint(rnd.Intn(int(r.max-r.min))+1)) | field(r)&^(1<<r.min) | starBit
+		}
+		return field(r)
+	}
+	for i := 0; i < 1000; i++ {
+		sched := &SpecSchedule{
+			Second: field(seconds),
+			Minute: field(minutes),
+			Hour:   field(hours),
+			Dom:    dayField(dom),
+			Month:  field(months),
+			Dow:    dayField(dow),
+		}
+		switch rnd.Intn(8) {
+		case 0:
+			sched.Dom = 1 << (weekdayShift + uint(rnd.Intn(31)+1))
+		case 1:
+			sched.Dow = 1 << (nthShift + uint(rnd.Intn(35)))
+		case 2:
+			sched.Dom, sched.LastDom, sched.LastDomOffset = 0, true, uint(rnd.Intn(28))
+		case 3:
+			if sched.Dom&starBit == 0 && sched.Dow&starBit == 0 {
+				sched.Dow |= strictBit
+			}
+		}
+		check(sched)
+	}
 }
 
 func TestErrors(t *testing.T) {
@@ -172,6 +514,22 @@ func TestErrors(t *testing.T) {
 		"60 0 * * *",
 		"0 60 * * *",
 		"0 0 * * XYZ",
+		"0 0 0 1,15W * ?",
+		"0 0 0 1-15W * ?",
+		"0 0 0 32W * ?",
+		"0 0 0 W * ?",
+		"0 0 0 ? * 2#6",
+		"0 0 0 ? * 2#0",
+		"0 0 0 ? * 1,2#2",
+		"0 0 0 ? * 8#1",
+		"0 0 0 ? * 2#2#2",
+		"0 0 23 1,L * ?",
+		"0 0 23 L,15 * ?",
+		"0 0 23 L-31 * ?",
+		"0 0 23 L-x * ?",
+		"0 0 23 LW * ?",
+		"0 0 23 L/2 * ?",
+		"0 0 23 L-30 FEB ?",
 	}
 	for _, spec := range invalidSpecs {
 		_, err := Parse(spec)
@@ -247,3 +605,330 @@ func getTimeTZ(value string) time.Time {
 
 	return t
 }
+
+func TestYearScheduleNext(t *testing.T) {
+	parser := NewParser(Second | Minute | Hour | Dom | Month | DowOptional | Descriptor | Year)
+	runs := []struct {
+		time, spec string
+		expected   string
+	}{
+		// Bounded to next year: skip the rest of this one.
+		{"Fri Jun 15 12:00 2012", "0 0 12 * * ? 2013", "Tue Jan 1 12:00 2013"},
+		{"Fri Jun 15 12:00 2012", "0 0 12 1 1 ? 2013", "Tue Jan 1 12:00 2013"},
+		{"Fri Jun 15 12:00 2012", "0 0 12 * * ? 2012", "Sat Jun 16 12:00 2012"},
+		{"Mon Dec 31 12:00 2012", "0 0 12 * * ? 2012,2014", "Wed Jan 1 12:00 2014"},
+
+		// Beyond the usual five year search.
+		{"Fri Jun 15 12:00 2012", "0 0 12 1 1 ? 2030-2040/5", "Tue Jan 1 12:00 2030"},
+
+		// Only past years.
+		{"Fri Jun 15 12:00 2012", "0 0 12 * * ? 2010", ""},
+	}
+
+	for _, c := range runs {
+		sched, err := parser.Parse(c.spec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		actual := sched.Next(getTime(c.time))
+		expected := getTime(c.expected)
+		if !actual.Equal(expected) {
+			t.Errorf("%s, \"%s\": (expected) %v != %v (actual)", c.time, c.spec, expected, actual)
+		}
+	}
+
+	sched, _ := parser.Parse("0 0 12 1 1 ? 2026-2028,2030")
+	if actual := sched.(*YearSchedule).String(); actual != "0 0 12 1 1 * 2026-2028,2030" {
+		t.Errorf("(expected) 0 0 12 1 1 * 2026-2028,2030 != %s (actual)", actual)
+	}
+	other, _ := parser.Parse("0 0 12 1 1 * 2026,2027,2028,2030")
+	if !sched.(*YearSchedule).Equal(other) {
+		t.Errorf("expected %v to equal %v", sched, other)
+	}
+}
+
+func TestNearestAndNthWeekday(t *testing.T) {
+	runs := []struct {
+		time, spec string
+		expected   string
+	}{
+		// The 15th on each day of the week.
+		{"Mon Jan 1 00:00 2024", "0 0 9 15W * ?", "Mon Jan 15 09:00 2024"},
+		{"Tue Oct 1 00:00 2024", "0 0 9 15W * ?", "Tue Oct 15 09:00 2024"},
+		{"Wed May 1 00:00 2024", "0 0 9 15W * ?", "Wed May 15 09:00 2024"},
+		{"Thu Feb 1 00:00 2024", "0 0 9 15W * ?", "Thu Feb 15 09:00 2024"},
+		{"Fri Mar 1 00:00 2024", "0 0 9 15W * ?", "Fri Mar 15 09:00 2024"},
+		{"Sat Jun 1 00:00 2024", "0 0 9 15W * ?", "Fri Jun 14 09:00 2024"},
+		{"Sun Sep 1 00:00 2024", "0 0 9 15W * ?", "Mon Sep 16 09:00 2024"},
+		{"Fri Jun 14 10:00 2024", "0 0 9 15W * ?", "Mon Jul 15 09:00 2024"},
+
+		// Without crossing a month boundary.
+		{"Fri May 31 10:00 2024", "0 0 9 1W * ?", "Mon Jun 3 09:00 2024"},
+		{"Fri Mar 1 00:00 2024", "0 0 9 31W * ?", "Fri Mar 29 09:00 2024"},
+		{"Mon Apr 1 00:00 2024", "0 0 9 31W * ?", "Fri May 31 09:00 2024"},
+
+		// Nth weekday of the month.
+		{"Mon Jan 1 00:00 2024", "0 0 9 ? * TUE#2", "Tue Jan 9 09:00 2024"},
+		{"Sat Jun 1 00:00 2024", "0 0 9 ? * MON#1", "Mon Jun 3 09:00 2024"},
+
+		// Months without a fifth occurrence.
+		{"Mon Apr 1 00:00 2024", "0 0 9 ? * FRI#5", "Fri May 31 09:00 2024"},
+		{"Sat Jun 1 00:00 2024", "0 0 9 ? * FRI#5", "Fri Aug 30 09:00 2024"},
+		{"Thu Feb 1 00:00 2024", "0 0 9 ? * SUN#5", "Sun Mar 31 09:00 2024"},
+	}
+
+	for _, c := range runs {
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		actual := sched.Next(getTime(c.time))
+		expected := getTime(c.expected)
+		if !actual.Equal(expected) {
+			t.Errorf("%s, \"%s\": (expected) %v != %v (actual)", c.time, c.spec, expected, actual)
+		}
+	}
+}
+
+func TestLastDayOfMonth(t *testing.T) {
+	runs := []struct {
+		time, spec string
+		expected   string
+	}{
+		// The last day of each month, in a leap year.
+		{"Mon Jan 1 00:00 2024", "0 0 23 L * ?", "Wed Jan 31 23:00 2024"},
+		{"Thu Feb 1 00:00 2024", "0 0 23 L * ?", "Thu Feb 29 23:00 2024"},
+		{"Fri Mar 1 00:00 2024", "0 0 23 L * ?", "Sun Mar 31 23:00 2024"},
+		{"Mon Apr 1 00:00 2024", "0 0 23 L * ?", "Tue Apr 30 23:00 2024"},
+		{"Wed May 1 00:00 2024", "0 0 23 L * ?", "Fri May 31 23:00 2024"},
+		{"Sat Jun 1 00:00 2024", "0 0 23 L * ?", "Sun Jun 30 23:00 2024"},
+		{"Mon Jul 1 00:00 2024", "0 0 23 L * ?", "Wed Jul 31 23:00 2024"},
+		{"Thu Aug 1 00:00 2024", "0 0 23 L * ?", "Sat Aug 31 23:00 2024"},
+		{"Sun Sep 1 00:00 2024", "0 0 23 L * ?", "Mon Sep 30 23:00 2024"},
+		{"Tue Oct 1 00:00 2024", "0 0 23 L * ?", "Thu Oct 31 23:00 2024"},
+		{"Fri Nov 1 00:00 2024", "0 0 23 L * ?", "Sat Nov 30 23:00 2024"},
+		{"Sun Dec 1 00:00 2024", "0 0 23 L * ?", "Tue Dec 31 23:00 2024"},
+
+		// February in other years.
+		{"Sat Feb 1 00:00 2025", "0 0 23 L * ?", "Fri Feb 28 23:00 2025"},
+		{"Fri Feb 1 00:00 2100", "0 0 23 L * ?", "Sun Feb 28 23:00 2100"},
+		{"Tue Feb 1 00:00 2000", "0 0 23 L * ?", "Tue Feb 29 23:00 2000"},
+
+		// Two days before the last day of each month, in a leap year.
+		{"Mon Jan 1 00:00 2024", "0 0 23 L-2 * ?", "Mon Jan 29 23:00 2024"},
+		{"Thu Feb 1 00:00 2024", "0 0 23 L-2 * ?", "Tue Feb 27 23:00 2024"},
+		{"Fri Mar 1 00:00 2024", "0 0 23 L-2 * ?", "Fri Mar 29 23:00 2024"},
+		{"Mon Apr 1 00:00 2024", "0 0 23 L-2 * ?", "Sun Apr 28 23:00 2024"},
+		{"Wed May 1 00:00 2024", "0 0 23 L-2 * ?", "Wed May 29 23:00 2024"},
+		{"Sat Jun 1 00:00 2024", "0 0 23 L-2 * ?", "Fri Jun 28 23:00 2024"},
+		{"Mon Jul 1 00:00 2024", "0 0 23 L-2 * ?", "Mon Jul 29 23:00 2024"},
+		{"Thu Aug 1 00:00 2024", "0 0 23 L-2 * ?", "Thu Aug 29 23:00 2024"},
+		{"Sun Sep 1 00:00 2024", "0 0 23 L-2 * ?", "Sat Sep 28 23:00 2024"},
+		{"Tue Oct 1 00:00 2024", "0 0 23 L-2 * ?", "Tue Oct 29 23:00 2024"},
+		{"Fri Nov 1 00:00 2024", "0 0 23 L-2 * ?", "Thu Nov 28 23:00 2024"},
+		{"Sun Dec 1 00:00 2024", "0 0 23 L-2 * ?", "Sun Dec 29 23:00 2024"},
+		{"Sat Feb 1 00:00 2025", "0 0 23 L-2 * ?", "Wed Feb 26 23:00 2025"},
+
+		// From the day itself, and an offset only some months have.
+		{"Wed Jan 31 23:00 2024", "0 0 23 L * ?", "Thu Feb 29 23:00 2024"},
+		{"Thu Feb 1 00:00 2024", "0 0 0 L-30 * ?", "Fri Mar 1 00:00 2024"},
+		{"Mon Apr 1 00:00 2024", "0 0 0 L-30 * ?", "Wed May 1 00:00 2024"},
+
+		// A restricted day of week also matches, or must match with StrictDow.
+		{"Mon Apr 1 00:00 2024", "0 0 23 L * MON", "Mon Apr 1 23:00 2024"},
+		{"Tue Apr 2 00:00 2024", "0 0 23 L * MON", "Mon Apr 8 23:00 2024"},
+		{"Sat Apr 27 00:00 2024", "0 0 23 L * MON", "Mon Apr 29 23:00 2024"},
+		{"Tue Apr 30 00:00 2024", "0 0 23 L * MON", "Tue Apr 30 23:00 2024"},
+	}
+
+	for _, c := range runs {
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		actual := sched.Next(getTime(c.time))
+		expected := getTime(c.expected)
+		if !actual.Equal(expected) {
+			t.Errorf("%s, \"%s\": (expected) %v != %v (actual)", c.time, c.spec, expected, actual)
+		}
+	}
+
+	strict, err := NewParser(Second | Minute | Hour | Dom | Month | Dow | StrictDow).Parse("0 0 23 L * MON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := strict.Next(getTime("Mon Jan 1 00:00 2024")), getTime("Mon Sep 30 23:00 2024"); !actual.Equal(expected) {
+		t.Errorf("strict: (expected) %v != %v (actual)", expected, actual)
+	}
+}
+
+func BenchmarkNext(b *testing.B) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	for _, bench := range []struct {
+		spec string
+		loc  *time.Location
+	}{
+		{"* * * * * *", time.UTC},
+		{"* * * * * *", newYork},
+		{"0 */5 * * * *", time.UTC},
+		{"30 0 9 * * MON-FRI", time.UTC},
+		{"30 0 9 * * MON-FRI", newYork},
+		{"0 0 0 L * ?", time.UTC},
+	} {
+		sched, err := Parse(bench.spec)
+		if err != nil {
+			b.Fatal(err)
+		}
+		start := time.Date(2024, time.March, 1, 0, 0, 0, 0, bench.loc)
+		b.Run(bench.spec+" "+bench.loc.String(), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sched.Next(start.Add(time.Duration(i%100000) * 7 * time.Second))
+			}
+		})
+	}
+}
+
+// nextReference is the implementation of SpecSchedule.Next that scans
+// forward one unit at a time, which the optimized one must agree with.
+func nextReference(s *SpecSchedule, t time.Time) time.Time {
+	// General approach:
+	// For Month, Day, Hour, Minute, Second:
+	// Check if the time value matches.  If yes, continue to the next field.
+	// If the field doesn't match the schedule, then increment the field until it matches.
+	// While incrementing the field, a wrap-around brings it back to the beginning
+	// of the field list (since it is necessary to re-verify previous field
+	// values)
+
+	// Start at the earliest possible time (the upcoming second).
+	t = t.Add(1*time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+
+	// This flag indicates whether a field has been incremented.
+	added := false
+
+	// If no time is found within five years, return zero.
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	// Find the first applicable month.
+	// If it's this month, then do nothing.
+	for 1<<uint(t.Month())&s.Month == 0 {
+		// If we have to add a month, reset the other parts to 0.
+		if !added {
+			added = true
+			// Otherwise, set the date at the beginning (since the current time is irrelevant).
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		}
+		t = t.AddDate(0, 1, 0)
+
+		// Wrapped around.
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	// Now get a day in that month.
+	for !dayMatchesReference(s, t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		}
+		t = t.AddDate(0, 0, 1)
+
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.Hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		}
+		t = t.Add(1 * time.Hour)
+
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.Minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(1 * time.Minute)
+
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.Second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(1 * time.Second)
+
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// dayMatchesReference is dayMatches as used by nextReference.
+func dayMatchesReference(s *SpecSchedule, t time.Time) bool {
+	var (
+		day      = uint(t.Day())
+		weekday  = uint(t.Weekday())
+		nth      = uint(t.Day()-1) / 7
+		domMatch = 1<<day&s.Dom > 0 || s.Dom&weekdayBits > 0 && nearestWeekdayMatchesReference(s.Dom, t) ||
+			s.LastDom && int(day+s.LastDomOffset) == lastDayReference(t)
+		dowMatch = 1<<weekday&s.Dow > 0 || 1<<(nthShift+7*nth+weekday)&s.Dow > 0
+	)
+	return daysMatch(s.Dom, s.Dow, domMatch, dowMatch)
+}
+
+// lastDayReference is lastDay as used by nextReference.
+func lastDayReference(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// nearestWeekdayMatchesReference is nearestWeekdayMatches as used by
+// nextReference.
+func nearestWeekdayMatchesReference(domBits uint64, t time.Time) bool {
+	last := lastDayReference(t)
+	for day := dom.min; day <= uint(last); day++ {
+		if domBits&(1<<(weekdayShift+day)) == 0 {
+			continue
+		}
+		nearest := int(day)
+		switch time.Date(t.Year(), t.Month(), nearest, 12, 0, 0, 0, t.Location()).Weekday() {
+		case time.Saturday:
+			if nearest == 1 {
+				nearest = 3
+			} else {
+				nearest--
+			}
+		case time.Sunday:
+			if nearest == last {
+				nearest -= 2
+			} else {
+				nearest++
+			}
+		}
+		if nearest == t.Day() {
+			return true
+		}
+	}
+	return false
+}
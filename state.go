@@ -0,0 +1,193 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateStore persists the last run times of the named entries of a Cron, set
+// with WithStateStore, so that it knows after a restart which activations it
+// missed while it was down.
+type StateStore interface {
+	// Load returns the last run times of the entries, by name.
+	Load() (map[string]time.Time, error)
+
+	// Save records the last run time of the entry with the given name.
+	Save(name string, lastRun time.Time) error
+}
+
+// loadState loads the last run times of the entries from the store, if any.
+// It is called with the entries unlocked, the store possibly being slow.
+func (c *Cron) loadState() (map[string]time.Time, error) {
+	if c.store == nil {
+		return nil, nil
+	}
+	lastRuns, err := c.store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("cron: failed to load state: %v", err)
+	}
+	return lastRuns, nil
+}
+
+// missedRuns returns the activations of the entries missed since their last
+// runs, by entry ID, for runMissed. They are computed on copies of the entries,
+// with the entries unlocked but while they are read.
+func (c *Cron) missedRuns(lastRuns map[string]time.Time, now time.Time) map[EntryID][]time.Time {
+	if len(lastRuns) == 0 || c.missed == SkipMissed {
+		return nil
+	}
+	var entries []*Entry
+	c.read(func() {
+		for _, e := range c.entries {
+			if _, ok := lastRuns[e.Name]; ok && e.Name != "" {
+				copied := *e
+				copied.Schedule = detached(e.Schedule)
+				copied.rand = rand.New(rand.NewSource(c.seed + int64(e.ID)))
+				entries = append(entries, &copied)
+			}
+		}
+	})
+	missed := make(map[EntryID][]time.Time, len(entries))
+	for _, e := range entries {
+		missed[e.ID] = c.missedTimes(e, lastRuns[e.Name], now)
+	}
+	return missed
+}
+
+// missedTimes returns the activations of the entry to run for those missed
+// since last, according to the missed run policy: now with RunOnce, or each of
+// them, up to the limit, with RunAll.
+func (c *Cron) missedTimes(e *Entry, last, now time.Time) []time.Time {
+	var times []time.Time
+	for t := e.next(last); !t.IsZero() && !t.After(now); t = e.next(t) {
+		if c.missed == RunOnce {
+			return []time.Time{now}
+		}
+		times = append(times, t)
+		if c.missedMax > 0 && len(times) >= c.missedMax {
+			break
+		}
+	}
+	return times
+}
+
+// runMissed runs the activations of the entry missed since its last run
+// recorded in the store, according to the missed run policy. They are the ones
+// in missed, if computed ahead by missedRuns.
+func (c *Cron) runMissed(e *Entry, now time.Time, missed map[EntryID][]time.Time) {
+	last, ok := c.lastRuns[e.Name]
+	if !ok || e.Name == "" || e.Paused || c.missed == SkipMissed {
+		return
+	}
+	delete(c.lastRuns, e.Name)
+	times, ok := missed[e.ID]
+	if !ok {
+		times = c.missedTimes(e, last, now)
+	}
+	for _, t := range times {
+		c.startJob(*e, t)
+		e.Prev = t
+	}
+}
+
+// saveState records the run of the entry for the activation at fireTime in the
+// store, if any and if the entry is named, unless a run for a later activation
+// was already recorded. The failure to save it is passed to the error handler.
+func (c *Cron) saveState(e Entry, fireTime time.Time) {
+	if c.store == nil || e.Name == "" {
+		return
+	}
+	s := e.state
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if !fireTime.After(s.saved) {
+		return
+	}
+	if err := c.store.Save(e.Name, fireTime); err != nil {
+		c.handleError(e, fmt.Errorf("cron: failed to save state: %v", err))
+		return
+	}
+	s.saved = fireTime
+}
+
+// FileStateStore is a StateStore keeping the last run times in a JSON file,
+// which is written again on each Save.
+type FileStateStore struct {
+	mu       sync.Mutex
+	path     string
+	lastRuns map[string]time.Time
+}
+
+// NewFileStateStore returns a FileStateStore keeping the last run times in
+// the file at path, which is created on the first Save.
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+// Load reads the last run times from the file, or returns no times if it does
+// not exist.
+func (s *FileStateStore) Load() (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	lastRuns := make(map[string]time.Time, len(s.lastRuns))
+	for name, t := range s.lastRuns {
+		lastRuns[name] = t
+	}
+	return lastRuns, nil
+}
+
+// load reads the file, unless it was already read. s.mu must be held.
+func (s *FileStateStore) load() error {
+	if s.lastRuns != nil {
+		return nil
+	}
+	lastRuns := make(map[string]time.Time)
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &lastRuns); err != nil {
+			return err
+		}
+	}
+	s.lastRuns = lastRuns
+	return nil
+}
+
+// Save records the last run time of the entry, and writes the file again. The
+// file is replaced at once, so that it is never left half written.
+func (s *FileStateStore) Save(name string, lastRun time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.lastRuns[name] = lastRun
+	data, err := json.Marshal(s.lastRuns)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+// restart runs a Cron with a daily 02:00 entry from the start time of the
+// clock until it is advanced by d, and returns the activation times of the
+// runs.
+func restart(t *testing.T, clock clockwork.FakeClock, store StateStore, d time.Duration, opts ...Option) []time.Time {
+	var mu sync.Mutex
+	var runs []time.Time
+	cron := New(clock, append([]Option{WithStateStore(store)}, opts...)...)
+	cron.AddFuncCtx("0 0 2 * * *", func(ctx context.Context) {
+		activation, _ := ActivationFromContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		runs = append(runs, activation)
+	}, WithName("daily"))
+	cron.Start()
+	clock.BlockUntil(1)
+	if d > 0 {
+		clock.Advance(d)
+		clock.BlockUntil(1)
+	}
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Before(runs[j]) })
+	return runs
+}
+
+func TestStateStoreRestart(t *testing.T) {
+	day := func(d, h, m int) time.Time { return time.Date(2012, time.June, d, h, m, 0, 0, time.UTC) }
+	for _, c := range []struct {
+		policy   MissedRunPolicy
+		expected []time.Time
+	}{
+		{SkipMissed, nil},
+		{RunOnce, []time.Time{day(3, 2, 1)}},
+		{RunAll, []time.Time{day(2, 2, 0), day(3, 2, 0)}},
+	} {
+		store := NewFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+
+		// The first process runs the 02:00 activation of the 1st, then stops
+		// at 01:59 on the 2nd.
+		clock := clockwork.NewFakeClockAt(day(1, 1, 0))
+		assert.Equal(t, []time.Time{day(1, 2, 0)}, restart(t, clock, store, 25*time.Hour-time.Minute, WithMissedRunPolicy(c.policy)))
+		lastRuns, err := store.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, day(1, 2, 0), lastRuns["daily"].UTC())
+
+		// The second one starts at 02:01 on the 3rd.
+		clock = clockwork.NewFakeClockAt(day(3, 2, 1))
+		store = NewFileStateStore(store.path)
+		assert.Equal(t, c.expected, restart(t, clock, store, 0, WithMissedRunPolicy(c.policy)))
+		if c.expected != nil {
+			lastRuns, _ = store.Load()
+			assert.Equal(t, c.expected[len(c.expected)-1], lastRuns["daily"].UTC())
+		}
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Load() (map[string]time.Time, error) { return nil, errors.New("load") }
+
+func (failingStore) Save(string, time.Time) error { return errors.New("save") }
+
+func TestStateStoreErrors(t *testing.T) {
+	var mu sync.Mutex
+	var errs []string
+	cron := New(clockwork.NewFakeClock(), WithStateStore(failingStore{}), WithErrorHandler(func(_ EntryID, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err.Error())
+	}))
+	id, _ := cron.AddFunc("@every 1h", func() {}, WithName("named"))
+	unnamed, _ := cron.AddFunc("@every 1h", func() {})
+	cron.Start()
+	assert.NoError(t, cron.RunNow(id))
+	assert.NoError(t, cron.RunNow(unnamed))
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, []string{"cron: failed to load state: load", "cron: failed to save state: save"}, errs)
+
+	// A corrupt file fails to load.
+	path := filepath.Join(t.TempDir(), "state.json")
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err := NewFileStateStore(path).Load()
+	assert.Error(t, err)
+}
+
+// callbackStore is a StateStore whose Load calls load first.
+type callbackStore struct {
+	load     func()
+	lastRuns map[string]time.Time
+	err      error
+}
+
+func (s *callbackStore) Load() (map[string]time.Time, error) {
+	s.load()
+	return s.lastRuns, s.err
+}
+
+func (s *callbackStore) Save(string, time.Time) error { return nil }
+
+func TestStateStoreUnlocked(t *testing.T) {
+	var cron *Cron
+	store := &callbackStore{err: errors.New("load")}
+	store.load = func() { cron.Entries() }
+	handled := make(chan int, 1)
+	cron = New(clockwork.NewFakeClock(), WithStateStore(store), WithErrorHandler(func(EntryID, error) {
+		handled <- len(cron.Entries())
+	}))
+	cron.AddFunc("@every 1h", func() {}, WithName("named"))
+	started := make(chan struct{})
+	go func() {
+		cron.Start()
+		close(started)
+	}()
+	select {
+	case n := <-handled:
+		assert.Equal(t, 1, n)
+	case <-time.After(OneSecond):
+		t.Fatal("expected the store and the error handler to call the Cron")
+	}
+	<-started
+	cron.Stop()
+}
+
+func TestMissedRunDefaultLimit(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	store := &callbackStore{load: func() {}, lastRuns: map[string]time.Time{"secondly": clock.Now().Add(-24 * time.Hour)}}
+	cron := New(clock, WithStateStore(store), WithMissedRunPolicy(RunAll))
+	var runs int32
+	cron.AddFunc("@every 1s", func() { atomic.AddInt32(&runs, 1) }, WithName("secondly"))
+	cron.Start()
+	assert.NoError(t, cron.Shutdown(context.Background()))
+	assert.Equal(t, int32(defaultMissedRunLimit), atomic.LoadInt32(&runs))
+}
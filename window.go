@@ -0,0 +1,123 @@
+package cron
+
+import "time"
+
+// Window is a time of day range, on some days of the week, to which the
+// activations of a schedule can be restricted with Between.
+type Window struct {
+	// Start and End are the wall clock times of day the window starts and
+	// ends at, from midnight, with End excluded. A window crosses midnight if
+	// End is before Start, and spans whole days if they are equal.
+	Start, End time.Duration
+
+	// Days are the days of the week the window starts on. The window is open
+	// every day if there are none.
+	Days []time.Weekday
+
+	// Location is the time zone of the wall clock times. It defaults to the
+	// location of the times the schedule is given.
+	Location *time.Location
+}
+
+// WindowSchedule is a schedule restricted to the activations falling in a
+// window.
+type WindowSchedule struct {
+	Schedule Schedule
+	Window   Window
+}
+
+// Between returns a Schedule activated at the activations of inner that fall in
+// the window.
+func Between(inner Schedule, window Window) WindowSchedule {
+	return WindowSchedule{Schedule: inner, Window: window}
+}
+
+// Next returns the next activation of the inner schedule after t falling in the
+// window. An activation out of the window skips the inner schedule to the next
+// start of the window: wall clock schedules may be activated right at the
+// start, while delays, such as @every, start from it. If no time is found
+// within five years, Next returns the zero time.
+func (s WindowSchedule) Next(t time.Time) time.Time {
+	limit := t.AddDate(5, 0, 0)
+	for {
+		next := s.Schedule.Next(t)
+		if next.IsZero() || next.After(limit) {
+			return time.Time{}
+		}
+		if s.Window.contains(next) {
+			return next
+		}
+		start := s.Window.nextStart(next)
+		if start.IsZero() {
+			return time.Time{}
+		}
+		t = skipTo(s.Schedule, start)
+	}
+}
+
+// skipTo returns the time to compute the next activation of a schedule from,
+// for it to be at or after start: wall clock schedules may be activated right
+// at start, while delays start from it.
+func skipTo(s Schedule, start time.Time) time.Time {
+	switch s.(type) {
+	case ConstantDelaySchedule, PreciseDelaySchedule:
+		return start
+	}
+	return start.Add(-time.Nanosecond)
+}
+
+// contains returns true if t is in the window.
+func (w Window) contains(t time.Time) bool {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	var (
+		hour, min, sec = t.Clock()
+		clock          = time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute +
+			time.Duration(sec)*time.Second + time.Duration(t.Nanosecond())
+		weekday = t.Weekday()
+	)
+	switch {
+	case w.Start == w.End:
+		return w.on(weekday)
+	case w.Start < w.End:
+		return w.Start <= clock && clock < w.End && w.on(weekday)
+	case clock >= w.Start:
+		return w.on(weekday)
+	default:
+		// In the part of the window after midnight.
+		return clock < w.End && w.on((weekday+6)%7)
+	}
+}
+
+// nextStart returns the first start of the window after t, or the zero time if
+// the window is never open.
+func (w Window) nextStart(t time.Time) time.Time {
+	loc := t.Location()
+	if w.Location != nil {
+		loc = w.Location
+	}
+	local := t.In(loc)
+	for day := 0; day <= 7; day++ {
+		// time.Date moves a start skipped by a daylight saving time
+		// transition to the wall clock time after it.
+		start := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, int(w.Start), loc)
+		if start.After(t) && w.on(start.Weekday()) {
+			return start.In(t.Location())
+		}
+	}
+	return time.Time{}
+}
+
+// on returns true if the window starts on the given day of the week.
+func (w Window) on(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	var (
+		hourly, _   = Parse("0 0 * * * *")
+		every5m     = Every(5 * time.Minute)
+		every30m    = Every(30 * time.Minute)
+		business    = Window{Start: 9 * time.Hour, End: 17 * time.Hour, Days: []time.Weekday{1, 2, 3, 4, 5}}
+		night       = Window{Start: 22 * time.Hour, End: 6 * time.Hour}
+		fridayNight = Window{Start: 22 * time.Hour, End: 6 * time.Hour, Days: []time.Weekday{time.Friday}}
+		nyEarly     = Window{Start: time.Hour, End: 3 * time.Hour, Location: ny}
+		nyOne       = Window{Start: time.Hour, End: 2 * time.Hour, Location: ny}
+	)
+	utc := func(value string) time.Time { return getTime(value).UTC() }
+	tests := []struct {
+		sched    Schedule
+		window   Window
+		time     time.Time
+		expected time.Time
+	}{
+		// An interval clipped to business hours.
+		{every5m, business, utc("Mon Jul 9 10:00 2012"), utc("Mon Jul 9 10:05 2012")},
+		{every5m, business, utc("Mon Jul 9 16:54 2012"), utc("Mon Jul 9 16:59 2012")},
+		{every5m, business, utc("Mon Jul 9 16:58 2012"), utc("Tue Jul 10 09:05 2012")},
+		{every5m, business, utc("Fri Jul 13 16:58 2012"), utc("Mon Jul 16 09:05 2012")},
+		{every5m, business, utc("Sat Jul 14 12:00 2012"), utc("Mon Jul 16 09:05 2012")},
+		{hourly, business, utc("Mon Jul 9 16:30 2012"), utc("Tue Jul 10 09:00 2012")},
+
+		// Windows crossing midnight.
+		{hourly, night, utc("Mon Jul 9 06:30 2012"), utc("Mon Jul 9 22:00 2012")},
+		{hourly, night, utc("Mon Jul 9 23:30 2012"), utc("Tue Jul 10 00:00 2012")},
+		{hourly, night, utc("Tue Jul 10 05:30 2012"), utc("Tue Jul 10 22:00 2012")},
+		{hourly, fridayNight, utc("Sat Jul 14 01:30 2012"), utc("Sat Jul 14 02:00 2012")},
+		{hourly, fridayNight, utc("Sat Jul 14 05:30 2012"), utc("Fri Jul 20 22:00 2012")},
+		{hourly, fridayNight, utc("Thu Jul 12 23:30 2012"), utc("Fri Jul 13 22:00 2012")},
+
+		// The window is shorter on the day the clocks are set forward, and
+		// longer on the day they are set back.
+		{hourly, Window{Start: time.Hour, End: 4 * time.Hour, Location: ny}, getTime("2012-03-11T01:30:00-0500"), getTime("2012-03-11T03:00:00-0400")},
+		{every30m, nyEarly, getTime("2012-03-11T01:50:00-0500"), getTime("2012-03-12T01:30:00-0400")},
+		{every30m, nyOne, getTime("2012-11-04T01:40:00-0400"), getTime("2012-11-04T01:10:00-0500")},
+		{every30m, nyOne, getTime("2012-11-04T01:40:00-0500"), getTime("2012-11-05T01:30:00-0500")},
+
+		// Whole days.
+		{hourly, Window{}, utc("Mon Jul 9 10:00 2012"), utc("Mon Jul 9 11:00 2012")},
+		{hourly, Window{Days: []time.Weekday{time.Sunday}}, utc("Mon Jul 9 10:00 2012"), utc("Sun Jul 15 00:00 2012")},
+
+		// No activation in the window.
+		{hourly, Window{Start: 9*time.Hour + time.Minute, End: 9*time.Hour + 2*time.Minute}, utc("Mon Jul 9 10:00 2012"), time.Time{}},
+	}
+
+	for _, c := range tests {
+		actual := Between(c.sched, c.window).Next(c.time)
+		if !actual.Equal(c.expected) {
+			t.Errorf("%v, %v: (expected) %v != %v (actual)", c.time, c.window, c.expected, actual)
+		}
+	}
+}